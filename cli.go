@@ -0,0 +1,272 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"blockyserver/internal/render"
+	"blockyserver/internal/service"
+)
+
+// renderFormats are the formats of the render command
+var renderFormats = []string{"glb", "png", "gif", "mp4", "obj", "stl", "vox"}
+
+// renderCommand renders a character file like the matching /render endpoint
+func renderCommand(args []string) {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	format := fs.String("format", "png", "Output format: "+strings.Join(renderFormats, ", "))
+	in := fs.String("in", "", "Character JSON file, or - for stdin")
+	out := fs.String("out", "", "Output file, or - for stdout, default character.<format>")
+	pack := fs.String("pack", "", "Asset pack to render with, default the default pack")
+	charFormat := fs.String("character-format", "auto", "Character format: auto, blocky or hytale (the game's saved player skin, experimental)")
+	strict := fs.Bool("strict", false, "Fail instead of rendering with fallbacks or missing parts")
+	rotation := fs.Float64("rotation", 0, "Rotation in degrees (png)")
+	background := fs.String("background", "", "\"transparent\" or hex \"#RRGGBB\", default transparent for png and #FFFFFF for gif and mp4")
+	width := fs.Int("width", 512, "Image width in pixels (png, gif, mp4)")
+	height := fs.Int("height", 512, "Image height in pixels (png, gif, mp4)")
+	frames := fs.Int("frames", 36, "Number of frames (gif, mp4)")
+	delay := fs.Int("delay", 5, "Centiseconds between frames (gif)")
+	fps := fs.Int("fps", 12, "Frames per second (mp4)")
+	dithering := fs.Bool("dithering", true, "Floyd-Steinberg dithering (gif)")
+	autoZoom := fs.Bool("auto-zoom", true, "Zoom to fit the character (gif, mp4)")
+	stlHeight := fs.Float64("stl-height", 100, "Model height in millimetres (stl)")
+	basePlate := fs.Bool("base-plate", false, "Add a base plate (stl)")
+	plateThickness := fs.Float64("base-plate-thickness", 2, "Base plate thickness in millimetres (stl)")
+	plateMargin := fs.Float64("base-plate-margin", 3, "Base plate margin around the model in millimetres (stl)")
+	resolution := fs.Int("resolution", 64, fmt.Sprintf("Voxels along the longest axis, at most %d (vox)", render.MaxVoxelResolution))
+	pathsCfg := pathFlags(fs)
+	fs.Parse(args)
+
+	if !containsFormat(*format) {
+		log.Fatalf("Unknown format %q, expected one of %s", *format, strings.Join(renderFormats, ", "))
+	}
+	if *in == "" {
+		log.Fatalf("-in is required")
+	}
+	if *out == "" {
+		*out = "character." + *format
+	}
+	if *background == "" {
+		*background = "transparent"
+		if *format == "gif" || *format == "mp4" {
+			*background = "#FFFFFF"
+		}
+	}
+
+	charJSON, err := readInput(*in)
+	if err != nil {
+		log.Fatalf("Reading %s: %v", *in, err)
+	}
+
+	svc := packService(loadPacks(pathsCfg, false), *pack)
+	result, err := svc.MergeFromJSON(charJSON, service.MergeOptions{Strict: *strict, Format: *charFormat})
+	if err != nil {
+		log.Fatalf("Merge failed: %v", err)
+	}
+	logWarnings(result.Warnings)
+
+	var data []byte
+	switch *format {
+	case "glb":
+		data = result.GLBBytes
+	case "png":
+		data, err = render.RenderPNG(result.GLBBytes, result.Atlas, *rotation, *background, *width, *height, true)
+	case "gif":
+		data, err = render.RenderGIF(result.GLBBytes, result.Atlas, *background, *frames, *width, *height, *delay, *dithering, *autoZoom, nil)
+	case "mp4":
+		data, err = render.RenderMP4(result.GLBBytes, result.Atlas, *background, *frames, *width, *height, *fps, *autoZoom, nil)
+	case "obj":
+		data, err = render.RenderOBJ(result.GLBBytes, result.Atlas)
+	case "stl":
+		var skipped []string
+		data, skipped, err = render.RenderSTL(result.GLBBytes, *stlHeight, *basePlate, *plateThickness, *plateMargin)
+		for _, name := range skipped {
+			log.Printf("Warning: shape %s is not a closed solid and was left out of the print", name)
+		}
+	case "vox":
+		if *resolution < 1 || *resolution > render.MaxVoxelResolution {
+			log.Fatalf("-resolution must be between 1 and %d", render.MaxVoxelResolution)
+		}
+		data, err = render.RenderVOX(result.GLBBytes, result.Atlas, *resolution)
+	}
+	if err != nil {
+		log.Fatalf("Render failed: %v", err)
+	}
+
+	if err := writeOutput(*out, data); err != nil {
+		log.Fatalf("Writing %s: %v", *out, err)
+	}
+	if *out != "-" {
+		log.Printf("Wrote %s (%d bytes)", *out, len(data))
+	}
+}
+
+// icon is one cosmetic and color rendered by the icons command
+type icon struct {
+	value string // "Id" or "Id.Color"
+	file  string
+}
+
+// iconsCommand renders a PNG of every cosmetic and color of a category, as
+// /render/item would, into a directory
+func iconsCommand(args []string) {
+	fs := flag.NewFlagSet("icons", flag.ExitOnError)
+	category := fs.String("category", "", "Character field to render, e.g. haircut")
+	out := fs.String("out", "", "Output directory, created if missing")
+	pack := fs.String("pack", "", "Asset pack to render with, default the default pack")
+	workers := fs.Int("workers", runtime.NumCPU(), "Icons rendered in parallel")
+	mannequin := fs.Bool("mannequin", false, "Show each item on a faded player body")
+	skinTone := fs.String("skin-tone", "", "Skin tone for skin-colored items without a color")
+	rotation := fs.Float64("rotation", 0, "Rotation in degrees")
+	background := fs.String("background", "transparent", "\"transparent\" or hex \"#RRGGBB\"")
+	width := fs.Int("width", 256, "Image width in pixels")
+	height := fs.Int("height", 256, "Image height in pixels")
+	pathsCfg := pathFlags(fs)
+	fs.Parse(args)
+
+	if *category == "" || *out == "" {
+		log.Fatalf("-category and -out are required")
+	}
+	if *workers < 1 {
+		*workers = 1
+	}
+	if _, err := render.ParseHexColor(*background); err != nil {
+		log.Fatalf("Invalid -background: %v", err)
+	}
+
+	svc := packService(loadPacks(pathsCfg, false), *pack)
+	items, ok := svc.Catalog().Items(*category)
+	if !ok {
+		log.Fatalf("Unknown category %q, available: %s", *category, strings.Join(svc.Catalog().Categories(), ", "))
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatalf("Creating %s: %v", *out, err)
+	}
+
+	var icons []icon
+	for _, item := range items {
+		icons = append(icons, itemIcons(item)...)
+	}
+	log.Printf("Rendering %d icons of %d %s items with %d workers", len(icons), len(items), *category, *workers)
+
+	queue := make(chan icon)
+	var mu sync.Mutex
+	failed := 0
+	var wg sync.WaitGroup
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ic := range queue {
+				err := renderIcon(svc, *category, ic, filepath.Join(*out, ic.file), service.ItemOptions{
+					Mannequin: *mannequin,
+					SkinTone:  *skinTone,
+				}, *rotation, *background, *width, *height)
+				if err != nil {
+					log.Printf("%s: %v", ic.value, err)
+					mu.Lock()
+					failed++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, ic := range icons {
+		queue <- ic
+	}
+	close(queue)
+	wg.Wait()
+
+	log.Printf("Wrote %d icons to %s", len(icons)-failed, *out)
+	if failed > 0 {
+		log.Fatalf("%d icons failed", failed)
+	}
+}
+
+// itemIcons lists the icons of an item: one per color, and per variant for
+// items with variants, named "Id.Color.Variant.png". Items without colors are
+// rendered with their default texture, as "Id.png" or "Id..Variant.png".
+func itemIcons(item *service.CatalogItem) []icon {
+	colors := item.Colors
+	if len(colors) == 0 {
+		colors = []string{""}
+	}
+	var icons []icon
+	for _, color := range colors {
+		value := item.ID
+		if color != "" || len(item.Variants) > 0 {
+			value += "." + color
+		}
+		if len(item.Variants) == 0 {
+			icons = append(icons, icon{value: value, file: value + ".png"})
+			continue
+		}
+		for _, variant := range item.Variants {
+			icons = append(icons, icon{value: value + "." + variant.Name, file: value + "." + variant.Name + ".png"})
+		}
+	}
+	return icons
+}
+
+// renderIcon renders a single icon to a file
+func renderIcon(svc *service.MergeService, category string, ic icon, path string, opts service.ItemOptions, rotation float64, background string, width, height int) error {
+	result, err := svc.MergeItem(category, ic.value, opts)
+	if err != nil {
+		return err
+	}
+	data, err := render.RenderPNG(result.GLBBytes, result.Atlas, rotation, background, width, height, true)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// packService returns the MergeService of a pack, the default if name is empty
+func packService(packs *service.Packs, name string) *service.MergeService {
+	if name == "" {
+		name = packs.Default()
+	}
+	reloader, ok := packs.Get(name)
+	if !ok {
+		log.Fatalf("Unknown asset pack %q, available: %s", name, strings.Join(packs.Names(), ", "))
+	}
+	return reloader.Current()
+}
+
+// logWarnings reports the changes applied while rendering
+func logWarnings(warnings []service.FieldIssue) {
+	for _, w := range warnings {
+		log.Printf("Warning: %s: %s", w.Field, w.Message)
+	}
+}
+
+func containsFormat(format string) bool {
+	for _, f := range renderFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+func writeOutput(path string, data []byte) error {
+	if path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,440 @@
+package api
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"time"
+
+	"blockyserver/internal/cache"
+	"blockyserver/internal/render"
+	"blockyserver/internal/service"
+)
+
+// batchManifestFile is the name of the manifest entry of a batch response
+const batchManifestFile = "manifest.json"
+
+// batchContentTypes are the formats a batch job can render, by content type
+var batchContentTypes = map[string]string{
+	"glb": "model/gltf-binary",
+	"png": "image/png",
+	"gif": "image/gif",
+	"mp4": "video/mp4",
+}
+
+// batchIDPattern limits job IDs to names that are safe as archive entries
+var batchIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// batchJob is a job of a batch with the shared defaults applied
+type batchJob struct {
+	result    *BatchJobResult // manifest entry
+	format    string
+	character json.RawMessage
+	strict    bool
+	frames    int         // frames rendered, reported as job progress
+	options   interface{} // render options, cache keys match the single render endpoints
+
+	// render encodes the merged character, reporting frames to progress if not nil
+	render func(result *service.MergeResult, progress render.Progress) ([]byte, error)
+}
+
+// HandleBatch handles POST /render/batch. Jobs are grouped by character so
+// each distinct character is merged once, and every output is streamed as
+// soon as it is rendered. The manifest comes last and records the outcome
+// of every job; failed jobs do not fail the batch.
+func (h *Handlers) HandleBatch(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	var req BatchRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	if len(req.Jobs) == 0 {
+		writeError(w, http.StatusBadRequest, "jobs is required")
+		return
+	}
+	if len(req.Jobs) > h.batch.MaxJobs {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("at most %d jobs are allowed per batch", h.batch.MaxJobs))
+		return
+	}
+	if req.Output == "" {
+		req.Output = "zip"
+	}
+	if req.Output != "zip" && req.Output != "multipart" {
+		writeError(w, http.StatusBadRequest, "output must be \"zip\" or \"multipart\"")
+		return
+	}
+
+	pack, ok := h.pack(w, r, req.PackSelector)
+	if !ok {
+		return
+	}
+	defer pack.release()
+	charFormat := characterFormat(r)
+
+	manifest := BatchManifest{Jobs: make([]BatchJobResult, len(req.Jobs))}
+	jobs := make([]*batchJob, len(req.Jobs))
+	ids := make(map[string]bool)
+	for i, fields := range req.Jobs {
+		job, err := newBatchJob(req.Defaults, fields, charFormat)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("job %d: %v", i, err))
+			return
+		}
+		if !h.formatEnabled(job.format) {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("job %d: /render/%s is disabled", i, job.format))
+			return
+		}
+
+		manifest.Jobs[i] = BatchJobResult{Index: i, ID: strconv.Itoa(i), Format: job.format}
+		job.result = &manifest.Jobs[i]
+		if id, ok := fields["id"]; ok {
+			if err := json.Unmarshal(id, &job.result.ID); err != nil || !batchIDPattern.MatchString(job.result.ID) {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("job %d: id must be 1-64 letters, digits, \"_\", \"-\" or \".\"", i))
+				return
+			}
+		}
+		if ids[job.result.ID] {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("job %d: id %q is used twice", i, job.result.ID))
+			return
+		}
+		ids[job.result.ID] = true
+		jobs[i] = job
+	}
+
+	// Group the jobs by character, keeping the order of first appearance
+	var groups [][]*batchJob
+	groupOf := make(map[string]int)
+	for i, job := range jobs {
+		job.character, err = decodeShareCode(pack, job.character)
+		if err != nil {
+			job.fail(err)
+			continue
+		}
+		key, ok := renderKey(pack, "merge", job.character, charFormat, job.strict)
+		if !ok {
+			// Malformed characters fail on their own when merged
+			key = "job/" + strconv.Itoa(i)
+		}
+		if g, ok := groupOf[key]; ok {
+			groups[g] = append(groups[g], job)
+			continue
+		}
+		groupOf[key] = len(groups)
+		groups = append(groups, []*batchJob{job})
+	}
+
+	out := newBatchWriter(w, req.Output)
+	ctx, cancel := context.WithTimeout(r.Context(), h.batch.Timeout)
+	defer cancel()
+
+	// Stopping the batch also stops the animation being rendered
+	stop := func(render.ProgressEvent) error { return ctx.Err() }
+
+	for _, group := range groups {
+		var result *service.MergeResult
+		var mergeErr error
+		merged := false
+		for _, job := range group {
+			if err := ctx.Err(); err != nil {
+				job.fail(fmt.Errorf("batch stopped: %w", err))
+				continue
+			}
+
+			key, cacheable := renderKey(pack, job.format, job.character, charFormat, job.options)
+			entry, hit := (*cache.RenderEntry)(nil), false
+			if cacheable {
+				entry, hit = h.renders.Get(job.format, key)
+			}
+
+			if !hit {
+				if !merged {
+					result, mergeErr = pack.svc.MergeFromJSON(job.character, service.MergeOptions{Strict: job.strict, Format: charFormat})
+					merged = true
+					if mergeErr == nil {
+						manifest.Merges++
+					}
+				}
+				if mergeErr != nil {
+					job.fail(mergeErr)
+					continue
+				}
+
+				data, err := job.render(result, stop)
+				if err != nil {
+					if ctx.Err() != nil {
+						job.fail(fmt.Errorf("batch stopped: %w", ctx.Err()))
+					} else {
+						job.fail(fmt.Errorf("render failed: %w", err))
+					}
+					continue
+				}
+				entry = &cache.RenderEntry{
+					ContentType: batchContentTypes[job.format],
+					Warnings:    encodeWarnings(result.Warnings),
+					Data:        data,
+				}
+				if cacheable {
+					h.renders.Add(job.format, key, entry)
+				}
+			}
+
+			job.result.Status = "ok"
+			job.result.File = job.result.ID + "." + job.format
+			job.result.Bytes = len(entry.Data)
+			job.result.Cache = "MISS"
+			if hit {
+				job.result.Cache = "HIT"
+			}
+			if err := json.Unmarshal([]byte(entry.Warnings), &job.result.Warnings); err != nil {
+				job.fail(fmt.Errorf("reading warnings of the cached render: %w", err))
+				continue
+			}
+
+			if err := out.write(job.result.File, entry.ContentType, job.format == "glb", entry.Data); err != nil {
+				// The client is gone
+				return
+			}
+		}
+	}
+
+	out.close(&manifest)
+}
+
+// newBatchJob applies the defaults to a job and reads it into the request of
+// its format, so jobs take the same options as the single render endpoints
+func newBatchJob(defaults, fields map[string]json.RawMessage, charFormat string) (*batchJob, error) {
+	merged := make(map[string]json.RawMessage)
+	for k, v := range defaults {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	delete(merged, "id")
+
+	var format string
+	if raw, ok := merged["format"]; ok {
+		json.Unmarshal(raw, &format)
+	}
+	delete(merged, "format")
+	if _, ok := batchContentTypes[format]; !ok {
+		return nil, errors.New("format must be glb, png, gif or mp4")
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &batchJob{format: format, frames: 1}
+	switch format {
+	case "glb":
+		var req struct {
+			Character json.RawMessage `json:"character"`
+			Strict    bool            `json:"strict"`
+		}
+		if err := json.Unmarshal(data, &req); err != nil {
+			return nil, err
+		}
+		job.character, job.strict = req.Character, req.Strict
+		job.options = service.MergeOptions{Strict: req.Strict, Format: charFormat}
+		job.render = func(result *service.MergeResult, progress render.Progress) ([]byte, error) {
+			return result.GLBBytes, nil
+		}
+
+	case "png":
+		var req PNGRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return nil, err
+		}
+		req.ApplyDefaults()
+		if err := req.Validate(); err != nil {
+			return nil, err
+		}
+		job.character, job.strict = req.Character, req.Strict
+		options := req
+		options.Character = nil
+		options.PackSelector = PackSelector{}
+		job.options = options
+		job.render = func(result *service.MergeResult, progress render.Progress) ([]byte, error) {
+			return render.RenderPNG(result.GLBBytes, result.Atlas, req.Rotation, req.Background, req.Width, req.Height, true)
+		}
+
+	case "gif":
+		var req GIFRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return nil, err
+		}
+		req.ApplyDefaults()
+		if err := req.Validate(); err != nil {
+			return nil, err
+		}
+		job.character, job.strict, job.frames = req.Character, req.Strict, req.Frames
+		options := req
+		options.Character = nil
+		options.PackSelector = PackSelector{}
+		job.options = options
+		job.render = func(result *service.MergeResult, progress render.Progress) ([]byte, error) {
+			return render.RenderGIF(result.GLBBytes, result.Atlas, req.Background, req.Frames, req.Width, req.Height, req.Delay, *req.Dithering, *req.AutoZoom, progress)
+		}
+
+	case "mp4":
+		var req MP4Request
+		if err := json.Unmarshal(data, &req); err != nil {
+			return nil, err
+		}
+		req.ApplyDefaults()
+		if err := req.Validate(); err != nil {
+			return nil, err
+		}
+		job.character, job.strict, job.frames = req.Character, req.Strict, req.Frames
+		options := req
+		options.Character = nil
+		options.PackSelector = PackSelector{}
+		job.options = options
+		job.render = func(result *service.MergeResult, progress render.Progress) ([]byte, error) {
+			return render.RenderMP4(result.GLBBytes, result.Atlas, req.Background, req.Frames, req.Width, req.Height, req.FPS, *req.AutoZoom, progress)
+		}
+	}
+
+	if job.character == nil {
+		return nil, errors.New("character field is required")
+	}
+	return job, nil
+}
+
+// fail records the error of a job in the manifest
+func (j *batchJob) fail(err error) {
+	j.result.Status = "error"
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		j.result.Error = "character has invalid fields"
+		j.result.Fields = validationErr.Issues
+		return
+	}
+	j.result.Error = err.Error()
+}
+
+// formatEnabled reports whether the render endpoint of a format is enabled
+func (h *Handlers) formatEnabled(format string) bool {
+	switch format {
+	case "glb":
+		return h.endpoints.GLBEnabled
+	case "png":
+		return h.endpoints.PNGEnabled
+	case "gif":
+		return h.endpoints.GIFEnabled
+	case "mp4":
+		return h.endpoints.MP4Enabled
+	}
+	return false
+}
+
+// batchWriter streams the outputs of a batch followed by its manifest
+type batchWriter interface {
+	write(name, contentType string, compress bool, data []byte) error
+	close(manifest *BatchManifest) error
+}
+
+// newBatchWriter starts a ZIP or multipart/mixed response
+func newBatchWriter(w http.ResponseWriter, output string) batchWriter {
+	if output == "multipart" {
+		mw := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+		return &multipartBatchWriter{w: w, mw: mw}
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename=batch.zip")
+	return &zipBatchWriter{w: w, zw: zip.NewWriter(w)}
+}
+
+// zipBatchWriter writes a batch as ZIP entries. Images and videos are
+// stored as they are, they do not compress any further.
+type zipBatchWriter struct {
+	w  http.ResponseWriter
+	zw *zip.Writer
+}
+
+func (b *zipBatchWriter) write(name, contentType string, compress bool, data []byte) error {
+	method := zip.Store
+	if compress {
+		method = zip.Deflate
+	}
+	f, err := b.zw.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if err := b.zw.Flush(); err != nil {
+		return err
+	}
+	flush(b.w)
+	return nil
+}
+
+func (b *zipBatchWriter) close(manifest *BatchManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := b.write(batchManifestFile, "application/json", true, data); err != nil {
+		return err
+	}
+	return b.zw.Close()
+}
+
+// multipartBatchWriter writes a batch as multipart/mixed parts
+type multipartBatchWriter struct {
+	w  http.ResponseWriter
+	mw *multipart.Writer
+}
+
+func (b *multipartBatchWriter) write(name, contentType string, compress bool, data []byte) error {
+	part, err := b.mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":        {contentType},
+		"Content-Disposition": {fmt.Sprintf("attachment; filename=%q", name)},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+	flush(b.w)
+	return nil
+}
+
+func (b *multipartBatchWriter) close(manifest *BatchManifest) error {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if err := b.write(batchManifestFile, "application/json", false, data); err != nil {
+		return err
+	}
+	return b.mw.Close()
+}
+
+// flush sends what has been written so far to the client
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"blockyserver/internal/cache"
+	"blockyserver/internal/config"
+	"blockyserver/internal/service"
+)
+
+// newTestHandlers serves the small asset pack in the service testdata with
+// every render endpoint enabled, shared by the tests of this package
+func newTestHandlers(t *testing.T) *Handlers {
+	t.Helper()
+	packs, err := service.NewPacks([]service.PackSpec{{
+		Name:  service.DefaultPackName,
+		Paths: service.Paths{AssetsDir: "../service/testdata/assets", DataDir: "../service/testdata/data"},
+	}}, "", service.Options{})
+	if err != nil {
+		t.Fatalf("loading testdata: %v", err)
+	}
+	renders, err := cache.NewRenderCache(cache.RenderCacheOptions{MaxBytes: map[string]int64{
+		"glb": 16 << 20, "png": 16 << 20, "gif": 16 << 20, "mp4": 16 << 20,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints := &config.EndpointConfig{GLBEnabled: true, PNGEnabled: true, GIFEnabled: true, MP4Enabled: true, BatchEnabled: true}
+	batch := &config.BatchConfig{MaxJobs: 10, Timeout: time.Minute}
+	return NewHandlers(packs, renders, 0, endpoints, batch, nil)
+}
+
+// batchEntry is a file of a batch response
+type batchEntry struct {
+	name string
+	data []byte
+}
+
+// postBatch sends a batch request and returns the files of the response,
+// the manifest last
+func postBatch(t *testing.T, h *Handlers, body string) []batchEntry {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.HandleBatch(rec, httptest.NewRequest(http.MethodPost, "/render/batch", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body)
+	}
+
+	var entries []batchEntry
+	mediaType, params, _ := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	switch mediaType {
+	case "application/zip":
+		zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			entries = append(entries, batchEntry{f.Name, data})
+		}
+	case "multipart/mixed":
+		mr := multipart.NewReader(rec.Body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := io.ReadAll(part)
+			entries = append(entries, batchEntry{part.FileName(), data})
+		}
+	default:
+		t.Fatalf("got content type %s", mediaType)
+	}
+	return entries
+}
+
+// batchManifest decodes the manifest, which must be the last file
+func batchManifest(t *testing.T, entries []batchEntry) BatchManifest {
+	t.Helper()
+	if len(entries) == 0 || entries[len(entries)-1].name != batchManifestFile {
+		t.Fatalf("manifest is not the last file of %d", len(entries))
+	}
+	var manifest BatchManifest
+	if err := json.Unmarshal(entries[len(entries)-1].data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	return manifest
+}
+
+func TestHandleBatch(t *testing.T) {
+	const character = `{"bodyCharacteristic":"Default.01","haircut":"Scavenger_Hair.Brown"}`
+	body := `{"output":"%s","defaults":{"character":` + character + `,"width":32,"height":32},"jobs":[
+		{"id":"model","format":"glb"},
+		{"format":"png","rotation":90},
+		{"id":"typo","format":"png","character":{"haircut":"Scavenger_Hiar.Brown"}},
+		{"id":"reordered","format":"png","character":{"haircut":"Scavenger_Hair.Brown","bodyCharacteristic":"Default.01"}}
+	]}`
+
+	for _, output := range []string{"zip", "multipart"} {
+		t.Run(output, func(t *testing.T) {
+			h := newTestHandlers(t)
+			entries := postBatch(t, h, strings.Replace(body, "%s", output, 1))
+			manifest := batchManifest(t, entries)
+
+			// Both characters are the same once their fields are sorted
+			if manifest.Merges != 1 {
+				t.Errorf("got %d merges, want 1", manifest.Merges)
+			}
+			var files, statuses []string
+			for _, e := range entries[:len(entries)-1] {
+				files = append(files, e.name)
+			}
+			for _, job := range manifest.Jobs {
+				statuses = append(statuses, job.ID+":"+job.Status)
+			}
+			if want := []string{"model.glb", "1.png", "reordered.png"}; !reflect.DeepEqual(files, want) {
+				t.Errorf("got files %v, want %v", files, want)
+			}
+			if want := []string{"model:ok", "1:ok", "typo:error", "reordered:ok"}; !reflect.DeepEqual(statuses, want) {
+				t.Errorf("got jobs %v, want %v", statuses, want)
+			}
+
+			typo := manifest.Jobs[2]
+			if len(typo.Fields) != 1 || typo.Fields[0].Field != "haircut" || typo.Fields[0].Reason != service.ReasonUnknownID {
+				t.Errorf("got fields %+v, want the unknown haircut", typo.Fields)
+			}
+			for i, job := range manifest.Jobs {
+				if job.Index != i || (job.Status == "ok" && (job.Bytes != len(entries[fileIndex(entries, job.File)].data) || job.Cache != "MISS")) {
+					t.Errorf("got job %+v", job)
+				}
+			}
+			if !bytes.HasPrefix(entries[0].data, []byte("glTF")) || !bytes.HasPrefix(entries[1].data, []byte("\x89PNG")) {
+				t.Error("files do not hold a GLB and a PNG")
+			}
+
+			// The same renders are served from the cache without merging
+			manifest = batchManifest(t, postBatch(t, h, strings.Replace(body, "%s", output, 1)))
+			if manifest.Merges != 0 {
+				t.Errorf("got %d merges on the second batch, want 0", manifest.Merges)
+			}
+			for _, job := range manifest.Jobs {
+				if job.Status == "ok" && job.Cache != "HIT" {
+					t.Errorf("job %s cache %s, want HIT", job.ID, job.Cache)
+				}
+			}
+		})
+	}
+}
+
+// fileIndex returns the index of a file in a batch response
+func fileIndex(entries []batchEntry, name string) int {
+	for i, e := range entries {
+		if e.name == name {
+			return i
+		}
+	}
+	return len(entries) - 1
+}
+
+func TestHandleBatchStopped(t *testing.T) {
+	h := newTestHandlers(t)
+	h.batch.Timeout = 0
+
+	manifest := batchManifest(t, postBatch(t, h, `{"jobs":[
+		{"format":"glb","character":{"haircut":"Scavenger_Hair.Brown"}},
+		{"format":"gif","frames":4,"width":16,"height":16,"character":{"haircut":"Scavenger_Hair.Brown"}}
+	]}`))
+	if manifest.Merges != 0 {
+		t.Errorf("got %d merges, want 0", manifest.Merges)
+	}
+	for _, job := range manifest.Jobs {
+		if job.Status != "error" || !strings.HasPrefix(job.Error, "batch stopped") {
+			t.Errorf("got job %+v, want it stopped", job)
+		}
+	}
+}
+
+func TestHandleBatchErrors(t *testing.T) {
+	h := newTestHandlers(t)
+	character := `"character":{"haircut":"Scavenger_Hair.Brown"}`
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"no jobs", `{"jobs":[]}`, http.StatusBadRequest},
+		{"too many jobs", `{"defaults":{"format":"glb",` + character + `},"jobs":[{},{},{},{},{},{},{},{},{},{},{}]}`, http.StatusBadRequest},
+		{"unknown output", `{"output":"tar","jobs":[{"format":"glb",` + character + `}]}`, http.StatusBadRequest},
+		{"unknown format", `{"jobs":[{"format":"stl",` + character + `}]}`, http.StatusBadRequest},
+		{"missing character", `{"jobs":[{"format":"glb"}]}`, http.StatusBadRequest},
+		{"duplicate id", `{"jobs":[{"id":"a","format":"glb",` + character + `},{"id":"a","format":"png",` + character + `}]}`, http.StatusBadRequest},
+		{"duplicate default id", `{"jobs":[{"format":"glb",` + character + `},{"id":"0","format":"png",` + character + `}]}`, http.StatusBadRequest},
+		{"id with a path", `{"jobs":[{"id":"../a","format":"glb",` + character + `}]}`, http.StatusBadRequest},
+		{"id not a string", `{"jobs":[{"id":1,"format":"glb",` + character + `}]}`, http.StatusBadRequest},
+		{"unknown pack", `{"assetPack":"old","jobs":[{"format":"glb",` + character + `}]}`, http.StatusBadRequest},
+		{"negative frames", `{"jobs":[{"format":"gif","frames":-1,` + character + `}]}`, http.StatusBadRequest},
+		{"too many frames", `{"jobs":[{"format":"mp4","frames":100000,` + character + `}]}`, http.StatusBadRequest},
+		{"negative width", `{"jobs":[{"format":"png","width":-1,` + character + `}]}`, http.StatusBadRequest},
+		{"too high", `{"defaults":{"height":100000},"jobs":[{"format":"png",` + character + `}]}`, http.StatusBadRequest},
+		{"negative delay", `{"jobs":[{"format":"gif","delay":-1,` + character + `}]}`, http.StatusBadRequest},
+		{"negative fps", `{"jobs":[{"format":"mp4","fps":-1,` + character + `}]}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.HandleBatch(rec, httptest.NewRequest(http.MethodPost, "/render/batch", strings.NewReader(tt.body)))
+			if rec.Code != tt.status {
+				t.Errorf("got status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+
+	h.endpoints.GIFEnabled = false
+	rec := httptest.NewRecorder()
+	h.HandleBatch(rec, httptest.NewRequest(http.MethodPost, "/render/batch", strings.NewReader(`{"jobs":[{"format":"gif",`+character+`}]}`)))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("got status %d for a disabled format, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestHandleBatchCachedWarnings(t *testing.T) {
+	h := newTestHandlers(t)
+	body := `{"jobs":[{"format":"glb","character":{"haircut":"Scavenger_Hair.Brown"}}]}`
+
+	// Plant a cached render whose warnings cannot be read
+	r := httptest.NewRequest(http.MethodPost, "/render/batch", strings.NewReader(body))
+	pack, ok := h.pack(httptest.NewRecorder(), r, PackSelector{})
+	if !ok {
+		t.Fatal("no default pack")
+	}
+	defer pack.release()
+	var fields map[string]json.RawMessage
+	json.Unmarshal([]byte(`{"format":"glb","character":{"haircut":"Scavenger_Hair.Brown"}}`), &fields)
+	job, err := newBatchJob(nil, fields, characterFormat(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := renderKey(pack, job.format, job.character, characterFormat(r), job.options)
+	h.renders.Add(job.format, key, &cache.RenderEntry{ContentType: "model/gltf-binary", Warnings: "[{", Data: []byte("glTF")})
+
+	manifest := batchManifest(t, postBatch(t, h, body))
+	if got := manifest.Jobs[0]; got.Status != "error" || !strings.Contains(got.Error, "warnings") {
+		t.Errorf("got job %+v, want it failed on the warnings", got)
+	}
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"blockyserver/internal/service"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultCatalogLimit = 100
+	maxCatalogLimit     = 1000
+)
+
+// HandleCatalog handles GET /catalog
+func (h *Handlers) HandleCatalog(w http.ResponseWriter, r *http.Request) {
+	pack, ok := h.pack(w, r, PackSelector{})
+	if !ok {
+		return
+	}
+	defer pack.release()
+	catalog := pack.svc.Catalog()
+
+	filter := catalogFilter(r)
+	if c := r.URL.Query().Get("category"); c != "" {
+		filter.Categories = strings.Split(c, ",")
+		for _, category := range filter.Categories {
+			if _, ok := catalog.Items(category); !ok {
+				writeError(w, http.StatusNotFound, "unknown category: "+category)
+				return
+			}
+		}
+	}
+
+	resp, ok := paginateCatalog(w, r, catalog.Filter(filter))
+	if !ok {
+		return
+	}
+
+	for _, category := range catalog.Categories() {
+		items, _ := catalog.Items(category)
+		resp.Categories = append(resp.Categories, CatalogCategory{Name: category, Count: len(items)})
+	}
+
+	writeCachedJSON(w, r, resp)
+}
+
+// HandleCatalogCategory handles GET /catalog/{category}
+func (h *Handlers) HandleCatalogCategory(w http.ResponseWriter, r *http.Request) {
+	pack, ok := h.pack(w, r, PackSelector{})
+	if !ok {
+		return
+	}
+	defer pack.release()
+	catalog := pack.svc.Catalog()
+
+	category := chi.URLParam(r, "category")
+	if _, ok := catalog.Items(category); !ok {
+		writeError(w, http.StatusNotFound, "unknown category: "+category)
+		return
+	}
+
+	filter := catalogFilter(r)
+	filter.Categories = []string{category}
+
+	resp, ok := paginateCatalog(w, r, catalog.Filter(filter))
+	if !ok {
+		return
+	}
+
+	writeCachedJSON(w, r, resp)
+}
+
+// catalogFilter reads the common catalog filters from the query string
+func catalogFilter(r *http.Request) service.CatalogFilter {
+	q := r.URL.Query()
+	return service.CatalogFilter{
+		Query:       q.Get("q"),
+		GradientSet: q.Get("gradientSet"),
+		Color:       q.Get("color"),
+		Type:        q.Get("type"),
+	}
+}
+
+func paginateCatalog(w http.ResponseWriter, r *http.Request, items []*service.CatalogItem) (*CatalogResponse, bool) {
+	limit, ok := queryInt(w, r, "limit", defaultCatalogLimit)
+	if !ok {
+		return nil, false
+	}
+	offset, ok := queryInt(w, r, "offset", 0)
+	if !ok {
+		return nil, false
+	}
+	if limit < 1 || limit > maxCatalogLimit {
+		writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxCatalogLimit))
+		return nil, false
+	}
+	if offset < 0 {
+		writeError(w, http.StatusBadRequest, "offset must not be negative")
+		return nil, false
+	}
+
+	resp := &CatalogResponse{
+		Total:  len(items),
+		Offset: offset,
+		Limit:  limit,
+		Items:  []*service.CatalogItem{},
+	}
+	if offset < len(items) {
+		end := offset + limit
+		if end > len(items) {
+			end = len(items)
+		}
+		resp.Items = items[offset:end]
+	}
+	return resp, true
+}
+
+// queryInt parses an optional integer query parameter, writing a 400 on failure
+func queryInt(w http.ResponseWriter, r *http.Request, name string, def int) (int, bool) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, true
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, name+" must be an integer")
+		return 0, false
+	}
+	return v, true
+}
+
+// writeCachedJSON writes v as JSON with an ETag derived from the body,
+// answering 304 Not Modified when the client already has it
+func writeCachedJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "encoding response failed: "+err.Error())
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// etagMatches reports whether an If-None-Match header matches the given ETag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"blockyserver/internal/service"
+)
+
+// HandleEncodeCode handles POST /codes/encode. The body is the character;
+// the response carries its share code and the character decoded back from it.
+func (h *Handlers) HandleEncodeCode(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	sel, body := splitPack(body)
+	pack, ok := h.pack(w, r, sel)
+	if !ok {
+		return
+	}
+	defer pack.release()
+
+	code, err := pack.svc.EncodeCode(body)
+	if err != nil {
+		writeMergeError(w, err)
+		return
+	}
+	character, err := pack.svc.DecodeCode(code)
+	if err != nil {
+		writeMergeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, CodeResponse{
+		Code:      code,
+		Version:   service.CodeVersion,
+		Character: character,
+	})
+}
+
+// HandleDecodeCode handles POST /codes/decode
+func (h *Handlers) HandleDecodeCode(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	var req CodeDecodeRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+
+	if req.Code == "" {
+		writeError(w, http.StatusBadRequest, "code is required")
+		return
+	}
+
+	pack, ok := h.pack(w, r, req.PackSelector)
+	if !ok {
+		return
+	}
+	defer pack.release()
+
+	character, err := pack.svc.DecodeCode(req.Code)
+	if err != nil {
+		writeMergeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, CodeResponse{
+		Code:      req.Code,
+		Version:   service.CodeVersion,
+		Character: character,
+	})
+}
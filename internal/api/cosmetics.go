@@ -0,0 +1,90 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"blockyserver/internal/service"
+)
+
+// multipartOverhead allows for form fields and part headers on top of the
+// upload limit
+const multipartOverhead = 64 << 10
+
+// HandleUploadCosmetic handles POST /cosmetics. The multipart form carries
+// name, category, an optional displayName, the model file, and either a
+// texture file or a greyscale file with a gradientSet. The upload is checked
+// against the selected asset pack and registered as "custom:<name>".
+func (h *Handlers) HandleUploadCosmetic(w http.ResponseWriter, r *http.Request) {
+	pack, ok := h.pack(w, r, PackSelector{})
+	if !ok {
+		return
+	}
+	defer pack.release()
+	custom := pack.svc.Custom()
+	if custom == nil {
+		writeError(w, http.StatusForbidden, "custom cosmetics are disabled")
+		return
+	}
+
+	limit := custom.Limits().MaxUploadBytes
+	r.Body = http.MaxBytesReader(w, r.Body, limit+multipartOverhead)
+	if err := r.ParseMultipartForm(limit); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "upload exceeds the size limit")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid multipart form: "+err.Error())
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	upload := service.CustomUpload{
+		Name:        r.FormValue("name"),
+		Category:    r.FormValue("category"),
+		DisplayName: r.FormValue("displayName"),
+		GradientSet: r.FormValue("gradientSet"),
+	}
+	for field, dst := range map[string]*[]byte{"model": &upload.Model, "texture": &upload.Texture, "greyscale": &upload.Greyscale} {
+		data, err := formFile(r, field)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "reading "+field+": "+err.Error())
+			return
+		}
+		*dst = data
+	}
+
+	item, err := pack.svc.AddCustom(upload)
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		writeJSON(w, http.StatusUnprocessableEntity, ErrorResponse{
+			Error:  "cosmetic upload is invalid",
+			Fields: validationErr.Issues,
+		})
+	case errors.Is(err, service.ErrCustomExists):
+		writeError(w, http.StatusConflict, service.CustomPrefix+upload.Name+" already exists in "+upload.Category)
+	case errors.Is(err, service.ErrCustomFull):
+		writeError(w, http.StatusInsufficientStorage, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "storing cosmetic failed: "+err.Error())
+	default:
+		writeJSON(w, http.StatusCreated, item)
+	}
+}
+
+// formFile reads an optional file from a parsed multipart form. A missing
+// file returns nil.
+func formFile(r *http.Request, field string) ([]byte, error) {
+	f, _, err := r.FormFile(field)
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
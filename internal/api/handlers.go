@@ -1,22 +1,38 @@
 package api
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 
+	"blockyserver/internal/cache"
+	"blockyserver/internal/config"
+	"blockyserver/internal/jobs"
 	"blockyserver/internal/render"
 	"blockyserver/internal/service"
 )
 
+// warningsHeader carries the warnings of a render as a JSON array of field issues
+const warningsHeader = "X-Blocky-Warnings"
+
 // Handlers contains HTTP handlers for the API
 type Handlers struct {
-	svc *service.MergeService
+	packs       *service.Packs
+	renders     *cache.RenderCache
+	cacheMaxAge int                    // Cache-Control max-age for renders, in seconds
+	endpoints   *config.EndpointConfig // enabled endpoints and URL limit
+	batch       *config.BatchConfig
+	jobs        *jobs.Queue // nil if jobs are disabled
+	progress    *progressHub
 }
 
 // NewHandlers creates a new Handlers instance
-func NewHandlers(svc *service.MergeService) *Handlers {
-	return &Handlers{svc: svc}
+func NewHandlers(packs *service.Packs, renders *cache.RenderCache, cacheMaxAge int, endpoints *config.EndpointConfig, batch *config.BatchConfig, queue *jobs.Queue) *Handlers {
+	return &Handlers{packs: packs, renders: renders, cacheMaxAge: cacheMaxAge, endpoints: endpoints, batch: batch, jobs: queue, progress: newProgressHub()}
 }
 
 // HandleGLB handles POST /render/glb
@@ -28,19 +44,182 @@ func (h *Handlers) HandleGLB(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	result, err := h.svc.MergeFromJSON(body)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+	sel, body := splitPack(body)
+	pack, ok := h.pack(w, r, sel)
+	if !ok {
 		return
 	}
+	defer pack.release()
 
-	w.Header().Set("Content-Type", "model/gltf-binary")
-	w.Header().Set("Content-Disposition", "attachment; filename=character.glb")
-	w.Write(result.GLBBytes)
+	opts := service.MergeOptions{Strict: strictQuery(r), Format: characterFormat(r)}
+	h.serveRender(w, r, pack, "glb", body, opts, func() *cache.RenderEntry {
+		result, err := pack.svc.MergeFromJSON(body, opts)
+		if err != nil {
+			writeMergeError(w, err)
+			return nil
+		}
+
+		return &cache.RenderEntry{
+			ContentType: "model/gltf-binary",
+			Filename:    "character.glb",
+			Warnings:    encodeWarnings(result.Warnings),
+			Data:        result.GLBBytes,
+		}
+	})
 }
 
-// HandlePNG handles POST /render/png
+// HandlePNG handles POST and GET /render/png
 func (h *Handlers) HandlePNG(w http.ResponseWriter, r *http.Request) {
+	var req PNGRequest
+	if !h.readRenderRequest(w, r, &req) {
+		return
+	}
+	req.ApplyDefaults()
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Character == nil {
+		writeError(w, http.StatusBadRequest, "character field is required")
+		return
+	}
+
+	pack, ok := h.pack(w, r, req.PackSelector)
+	if !ok {
+		return
+	}
+	defer pack.release()
+	if req.Character, ok = expandCode(w, pack, req.Character); !ok {
+		return
+	}
+
+	options := req
+	options.Character = nil
+	options.PackSelector = PackSelector{}
+	h.serveRender(w, r, pack, "png", req.Character, options, func() *cache.RenderEntry {
+		result, err := pack.svc.MergeFromJSON(req.Character, service.MergeOptions{Strict: req.Strict, Format: characterFormat(r)})
+		if err != nil {
+			writeMergeError(w, err)
+			return nil
+		}
+
+		pngBytes, err := render.RenderPNG(result.GLBBytes, result.Atlas, req.Rotation, req.Background, req.Width, req.Height, true)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "render failed: "+err.Error())
+			return nil
+		}
+
+		return &cache.RenderEntry{
+			ContentType: "image/png",
+			Warnings:    encodeWarnings(result.Warnings),
+			Data:        pngBytes,
+		}
+	})
+}
+
+// HandleGIF handles POST and GET /render/gif
+func (h *Handlers) HandleGIF(w http.ResponseWriter, r *http.Request) {
+	var req GIFRequest
+	if !h.readRenderRequest(w, r, &req) {
+		return
+	}
+	req.ApplyDefaults()
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Character == nil {
+		writeError(w, http.StatusBadRequest, "character field is required")
+		return
+	}
+
+	pack, ok := h.pack(w, r, req.PackSelector)
+	if !ok {
+		return
+	}
+	defer pack.release()
+	if req.Character, ok = expandCode(w, pack, req.Character); !ok {
+		return
+	}
+
+	options := req
+	options.Character = nil
+	options.PackSelector = PackSelector{}
+	h.serveRender(w, r, pack, "gif", req.Character, options, func() *cache.RenderEntry {
+		result, err := pack.svc.MergeFromJSON(req.Character, service.MergeOptions{Strict: req.Strict, Format: characterFormat(r)})
+		if err != nil {
+			writeMergeError(w, err)
+			return nil
+		}
+
+		gifBytes, err := render.RenderGIF(result.GLBBytes, result.Atlas, req.Background, req.Frames, req.Width, req.Height, req.Delay, *req.Dithering, *req.AutoZoom, h.progress.progress(progressID(r)))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "render failed: "+err.Error())
+			return nil
+		}
+
+		return &cache.RenderEntry{
+			ContentType: "image/gif",
+			Warnings:    encodeWarnings(result.Warnings),
+			Data:        gifBytes,
+		}
+	})
+}
+
+// HandleMP4 handles POST and GET /render/mp4
+func (h *Handlers) HandleMP4(w http.ResponseWriter, r *http.Request) {
+	var req MP4Request
+	if !h.readRenderRequest(w, r, &req) {
+		return
+	}
+	req.ApplyDefaults()
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Character == nil {
+		writeError(w, http.StatusBadRequest, "character field is required")
+		return
+	}
+
+	pack, ok := h.pack(w, r, req.PackSelector)
+	if !ok {
+		return
+	}
+	defer pack.release()
+	if req.Character, ok = expandCode(w, pack, req.Character); !ok {
+		return
+	}
+
+	options := req
+	options.Character = nil
+	options.PackSelector = PackSelector{}
+	h.serveRender(w, r, pack, "mp4", req.Character, options, func() *cache.RenderEntry {
+		result, err := pack.svc.MergeFromJSON(req.Character, service.MergeOptions{Strict: req.Strict, Format: characterFormat(r)})
+		if err != nil {
+			writeMergeError(w, err)
+			return nil
+		}
+
+		mp4Bytes, err := render.RenderMP4(result.GLBBytes, result.Atlas, req.Background, req.Frames, req.Width, req.Height, req.FPS, *req.AutoZoom, h.progress.progress(progressID(r)))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "render failed: "+err.Error())
+			return nil
+		}
+
+		return &cache.RenderEntry{
+			ContentType: "video/mp4",
+			Warnings:    encodeWarnings(result.Warnings),
+			Data:        mp4Bytes,
+		}
+	})
+}
+
+// HandleOBJ handles POST /render/obj
+func (h *Handlers) HandleOBJ(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, "failed to read request body")
@@ -48,7 +227,46 @@ func (h *Handlers) HandlePNG(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	var req PNGRequest
+	sel, body := splitPack(body)
+	pack, ok := h.pack(w, r, sel)
+	if !ok {
+		return
+	}
+	defer pack.release()
+
+	opts := service.MergeOptions{Strict: strictQuery(r), Format: characterFormat(r)}
+	h.serveRender(w, r, pack, "obj", body, opts, func() *cache.RenderEntry {
+		result, err := pack.svc.MergeFromJSON(body, opts)
+		if err != nil {
+			writeMergeError(w, err)
+			return nil
+		}
+
+		zipBytes, err := render.RenderOBJ(result.GLBBytes, result.Atlas)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "export failed: "+err.Error())
+			return nil
+		}
+
+		return &cache.RenderEntry{
+			ContentType: "application/zip",
+			Filename:    "character.zip",
+			Warnings:    encodeWarnings(result.Warnings),
+			Data:        zipBytes,
+		}
+	})
+}
+
+// HandleSTL handles POST /render/stl
+func (h *Handlers) HandleSTL(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	var req STLRequest
 	if err := json.Unmarshal(body, &req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
 		return
@@ -59,25 +277,44 @@ func (h *Handlers) HandlePNG(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "character field is required")
 		return
 	}
-
-	result, err := h.svc.MergeFromJSON(req.Character)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "merge failed: "+err.Error())
+	if req.Height < 0 || req.BasePlateThickness < 0 || req.BasePlateMargin < 0 {
+		writeError(w, http.StatusBadRequest, "height and base plate dimensions must be positive")
 		return
 	}
 
-	pngBytes, err := render.RenderPNG(result.GLBBytes, result.Atlas, req.Rotation, req.Background, req.Width, req.Height, true)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "render failed: "+err.Error())
+	pack, ok := h.pack(w, r, req.PackSelector)
+	if !ok {
 		return
 	}
+	defer pack.release()
+
+	options := req
+	options.Character = nil
+	options.PackSelector = PackSelector{}
+	h.serveRender(w, r, pack, "stl", req.Character, options, func() *cache.RenderEntry {
+		result, err := pack.svc.MergeFromJSON(req.Character, service.MergeOptions{Strict: req.Strict, Format: characterFormat(r)})
+		if err != nil {
+			writeMergeError(w, err)
+			return nil
+		}
 
-	w.Header().Set("Content-Type", "image/png")
-	w.Write(pngBytes)
+		stlBytes, skipped, err := render.RenderSTL(result.GLBBytes, req.Height, req.BasePlate, req.BasePlateThickness, req.BasePlateMargin)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "export failed: "+err.Error())
+			return nil
+		}
+
+		return &cache.RenderEntry{
+			ContentType: "model/stl",
+			Filename:    "character.stl",
+			Warnings:    encodeWarnings(append(result.Warnings, skippedShapes(skipped)...)),
+			Data:        stlBytes,
+		}
+	})
 }
 
-// HandleGIF handles POST /render/gif
-func (h *Handlers) HandleGIF(w http.ResponseWriter, r *http.Request) {
+// HandleVOX handles POST /render/vox
+func (h *Handlers) HandleVOX(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, "failed to read request body")
@@ -85,7 +322,7 @@ func (h *Handlers) HandleGIF(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	var req GIFRequest
+	var req VOXRequest
 	if err := json.Unmarshal(body, &req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
 		return
@@ -96,25 +333,44 @@ func (h *Handlers) HandleGIF(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "character field is required")
 		return
 	}
-
-	result, err := h.svc.MergeFromJSON(req.Character)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "merge failed: "+err.Error())
+	if req.Resolution < 1 || req.Resolution > render.MaxVoxelResolution {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("resolution must be between 1 and %d", render.MaxVoxelResolution))
 		return
 	}
 
-	gifBytes, err := render.RenderGIF(result.GLBBytes, result.Atlas, req.Background, req.Frames, req.Width, req.Height, req.Delay, *req.Dithering, *req.AutoZoom)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "render failed: "+err.Error())
+	pack, ok := h.pack(w, r, req.PackSelector)
+	if !ok {
 		return
 	}
+	defer pack.release()
+
+	options := req
+	options.Character = nil
+	options.PackSelector = PackSelector{}
+	h.serveRender(w, r, pack, "vox", req.Character, options, func() *cache.RenderEntry {
+		result, err := pack.svc.MergeFromJSON(req.Character, service.MergeOptions{Strict: req.Strict, Format: characterFormat(r)})
+		if err != nil {
+			writeMergeError(w, err)
+			return nil
+		}
 
-	w.Header().Set("Content-Type", "image/gif")
-	w.Write(gifBytes)
+		voxBytes, err := render.RenderVOX(result.GLBBytes, result.Atlas, req.Resolution)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "export failed: "+err.Error())
+			return nil
+		}
+
+		return &cache.RenderEntry{
+			ContentType: "application/octet-stream",
+			Filename:    "character.vox",
+			Warnings:    encodeWarnings(result.Warnings),
+			Data:        voxBytes,
+		}
+	})
 }
 
-// HandleMP4 handles POST /render/mp4
-func (h *Handlers) HandleMP4(w http.ResponseWriter, r *http.Request) {
+// HandleAtlas handles POST /render/atlas
+func (h *Handlers) HandleAtlas(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, "failed to read request body")
@@ -122,7 +378,7 @@ func (h *Handlers) HandleMP4(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	var req MP4Request
+	var req AtlasRequest
 	if err := json.Unmarshal(body, &req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
 		return
@@ -133,27 +389,444 @@ func (h *Handlers) HandleMP4(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "character field is required")
 		return
 	}
+	if req.Format != "zip" && req.Format != "png" && req.Format != "json" {
+		writeError(w, http.StatusBadRequest, "format must be one of zip, png, json")
+		return
+	}
+
+	pack, ok := h.pack(w, r, req.PackSelector)
+	if !ok {
+		return
+	}
+	defer pack.release()
+
+	options := req
+	options.Character = nil
+	options.PackSelector = PackSelector{}
+	h.serveRender(w, r, pack, "atlas", req.Character, options, func() *cache.RenderEntry {
+		result, err := pack.svc.MergeFromJSON(req.Character, service.MergeOptions{Strict: req.Strict, Format: characterFormat(r)})
+		if err != nil {
+			writeMergeError(w, err)
+			return nil
+		}
+		if result.Atlas == nil {
+			writeError(w, http.StatusUnprocessableEntity, "character has no textures")
+			return nil
+		}
+		warnings := encodeWarnings(result.Warnings)
+
+		manifest := buildAtlasManifest(result)
+		if req.Format == "json" {
+			manifestBytes, err := json.Marshal(manifest)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "encoding manifest failed: "+err.Error())
+				return nil
+			}
+			return &cache.RenderEntry{
+				ContentType: "application/json",
+				Warnings:    warnings,
+				Data:        append(manifestBytes, '\n'),
+			}
+		}
+
+		pngBytes, err := render.RenderAtlas(result.GLBBytes, result.Atlas, req.Wireframe, req.WireframeColor)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "render failed: "+err.Error())
+			return nil
+		}
+
+		if req.Format == "png" {
+			return &cache.RenderEntry{
+				ContentType: "image/png",
+				Warnings:    warnings,
+				Data:        pngBytes,
+			}
+		}
+
+		manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "encoding manifest failed: "+err.Error())
+			return nil
+		}
+
+		zipBytes, err := render.BuildZip([]render.ArchiveFile{
+			{Name: "atlas.png", Data: pngBytes},
+			{Name: "manifest.json", Data: manifestBytes},
+		})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "export failed: "+err.Error())
+			return nil
+		}
+
+		return &cache.RenderEntry{
+			ContentType: "application/zip",
+			Filename:    "atlas.zip",
+			Warnings:    warnings,
+			Data:        zipBytes,
+		}
+	})
+}
 
-	result, err := h.svc.MergeFromJSON(req.Character)
+// HandleBlockyModel handles POST /render/blockymodel
+func (h *Handlers) HandleBlockyModel(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "merge failed: "+err.Error())
+		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	sel, body := splitPack(body)
+	pack, ok := h.pack(w, r, sel)
+	if !ok {
 		return
 	}
+	defer pack.release()
+
+	opts := service.MergeOptions{Strict: strictQuery(r), Format: characterFormat(r)}
+	h.serveRender(w, r, pack, "blockymodel", body, opts, func() *cache.RenderEntry {
+		result, err := pack.svc.MergeFromJSON(body, opts)
+		if err != nil {
+			writeMergeError(w, err)
+			return nil
+		}
 
-	mp4Bytes, err := render.RenderMP4(result.GLBBytes, result.Atlas, req.Background, req.Frames, req.Width, req.Height, req.FPS, *req.AutoZoom)
+		zipBytes, err := render.RenderBlockyModel(result.Model, result.Atlas)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "export failed: "+err.Error())
+			return nil
+		}
+
+		return &cache.RenderEntry{
+			ContentType: "application/zip",
+			Filename:    "character.zip",
+			Warnings:    encodeWarnings(result.Warnings),
+			Data:        zipBytes,
+		}
+	})
+}
+
+// HandleItem handles POST and GET /render/item, rendering a single cosmetic
+// without the player body as PNG, framed to the item
+func (h *Handlers) HandleItem(w http.ResponseWriter, r *http.Request) {
+	var req ItemRequest
+	if !h.readRenderRequest(w, r, &req) {
+		return
+	}
+	req.ApplyDefaults()
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Item == "" {
+		writeError(w, http.StatusBadRequest, "item field is required")
+		return
+	}
+
+	pack, ok := h.pack(w, r, req.PackSelector)
+	if !ok {
+		return
+	}
+	defer pack.release()
+	if req.Category == "" {
+		category, err := pack.svc.ItemCategory(req.Item)
+		if err != nil {
+			writeMergeError(w, err)
+			return
+		}
+		req.Category = category
+	}
+
+	// The item is cached like a character wearing only that item
+	character, err := json.Marshal(map[string]string{req.Category: req.Item})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	options := req
+	options.Category, options.Item = "", ""
+	options.PackSelector = PackSelector{}
+	h.serveRender(w, r, pack, "item", character, options, func() *cache.RenderEntry {
+		result, err := pack.svc.MergeItem(req.Category, req.Item, service.ItemOptions{
+			Strict:    req.Strict,
+			Mannequin: req.Mannequin,
+			SkinTone:  req.SkinTone,
+		})
+		if err != nil {
+			writeMergeError(w, err)
+			return nil
+		}
+
+		pngBytes, err := render.RenderPNG(result.GLBBytes, result.Atlas, req.Rotation, req.Background, req.Width, req.Height, true)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "render failed: "+err.Error())
+			return nil
+		}
+
+		return &cache.RenderEntry{
+			ContentType: "image/png",
+			Warnings:    encodeWarnings(result.Warnings),
+			Data:        pngBytes,
+		}
+	})
+}
+
+// HandleValidate handles POST /validate
+func (h *Handlers) HandleValidate(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	sel, body := splitPack(body)
+	pack, ok := h.pack(w, r, sel)
+	if !ok {
+		return
+	}
+	defer pack.release()
+
+	body, imported, err := pack.svc.ImportCharacter(body, characterFormat(r))
+	if err != nil {
+		writeMergeError(w, err)
+		return
+	}
+
+	result, err := pack.svc.Validate(body)
+	if err != nil {
+		writeMergeError(w, err)
+		return
+	}
+	if len(imported) > 0 {
+		result.Warnings = append(imported, result.Warnings...)
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// HandleResolve handles POST /resolve
+func (h *Handlers) HandleResolve(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	sel, body := splitPack(body)
+	pack, ok := h.pack(w, r, sel)
+	if !ok {
+		return
+	}
+	defer pack.release()
+
+	body, imported, err := pack.svc.ImportCharacter(body, characterFormat(r))
+	if err != nil {
+		writeMergeError(w, err)
+		return
+	}
+
+	res, err := pack.svc.Resolve(body)
+	if err != nil {
+		writeMergeError(w, err)
+		return
+	}
+	if len(imported) > 0 {
+		res.Warnings = append(imported, res.Warnings...)
+	}
+	if strictQuery(r) && len(res.Warnings) > 0 {
+		writeMergeError(w, &service.ValidationError{Issues: res.Warnings})
+		return
+	}
+
+	resp := ResolveResponse{
+		Character:   res.Character,
+		Accessories: res.Accessories,
+		Textures:    make([]ResolvedTexture, len(res.Textures)),
+		Warnings:    res.Warnings,
+	}
+	if resp.Accessories == nil {
+		resp.Accessories = []service.ResolvedAccessory{}
+	}
+	for i, t := range res.Textures {
+		resp.Textures[i] = ResolvedTexture{
+			Name:        t.Name,
+			Category:    t.Category,
+			Texture:     t.SourcePath,
+			GradientSet: t.GradientSet,
+			Color:       t.Color,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleRules handles GET /rules
+func (h *Handlers) HandleRules(w http.ResponseWriter, r *http.Request) {
+	pack, ok := h.pack(w, r, PackSelector{})
+	if !ok {
+		return
+	}
+	defer pack.release()
+
+	resp := RulesResponse{Rules: pack.svc.Rules()}
+	if resp.Rules == nil {
+		resp.Rules = []service.Rule{}
+	}
+	writeCachedJSON(w, r, resp)
+}
+
+// HandleRandom handles POST /random
+func (h *Handlers) HandleRandom(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	var req RandomRequest
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+			return
+		}
+	}
+	req.ApplyDefaults()
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Render != "" && req.Render != "png" && req.Render != "glb" {
+		writeError(w, http.StatusBadRequest, "render must be \"png\" or \"glb\"")
+		return
+	}
+	for field, p := range req.Probabilities {
+		if p < 0 || p > 1 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("probability for %s must be between 0 and 1", field))
+			return
+		}
+	}
+
+	pack, ok := h.pack(w, r, req.PackSelector)
+	if !ok {
+		return
+	}
+	defer pack.release()
+
+	character, err := pack.svc.Random(service.RandomOptions{
+		Seed:          *req.Seed,
+		Categories:    req.Categories,
+		Probabilities: req.Probabilities,
+		Palettes:      req.Palettes,
+		Locked:        req.Locked,
+	})
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "render failed: "+err.Error())
+		writeMergeError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "video/mp4")
-	w.Write(mp4Bytes)
+	resp := RandomResponse{
+		Seed:      *req.Seed,
+		Character: character,
+	}
+
+	if req.Render != "" {
+		charJSON, err := json.Marshal(character)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		result, err := pack.svc.MergeFromJSON(charJSON, service.MergeOptions{})
+		if err != nil {
+			writeMergeError(w, err)
+			return
+		}
+		resp.Warnings = result.Warnings
+
+		data := result.GLBBytes
+		resp.RenderType = "model/gltf-binary"
+		if req.Render == "png" {
+			data, err = render.RenderPNG(result.GLBBytes, result.Atlas, req.Rotation, req.Background, req.Width, req.Height, true)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "render failed: "+err.Error())
+				return
+			}
+			resp.RenderType = "image/png"
+		}
+		resp.Render = base64.StdEncoding.EncodeToString(data)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // HandleHealth handles GET /health
 func (h *Handlers) HandleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"status":"ok"}`))
+	defaultPack, _ := h.packs.Get("")
+	svc, release := defaultPack.Acquire()
+	defer release()
+	packs := h.packStatus()
+
+	// A failed reload leaves the previous assets serving
+	status := "ok"
+	for _, reload := range packs {
+		if reload.LastError != "" {
+			status = "degraded"
+		}
+	}
+
+	resp := HealthResponse{
+		Status:       status,
+		AssetVersion: svc.AssetVersion(),
+		Reload:       defaultPack.Status(),
+		Packs:        packs,
+		Cache:        svc.CacheStats(),
+		RenderCache:  h.renders.Stats(),
+	}
+	if h.jobs != nil {
+		resp.Jobs = h.jobs.Count()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleReady handles GET /ready. The server is ready as long as a version
+// of the assets is loaded, even if the last reload failed.
+func (h *Handlers) HandleReady(w http.ResponseWriter, r *http.Request) {
+	defaultPack, _ := h.packs.Get("")
+	if defaultPack.Current() == nil {
+		writeError(w, http.StatusServiceUnavailable, "assets not loaded")
+		return
+	}
+	writeJSON(w, http.StatusOK, defaultPack.Status())
+}
+
+// HandleReload handles POST /admin/reload. It reloads the pack named by
+// ?assetPack=, or every pack.
+func (h *Handlers) HandleReload(w http.ResponseWriter, r *http.Request) {
+	reload := h.packs.Reload
+	if name := r.URL.Query().Get("assetPack"); name != "" {
+		pack, ok := h.packs.Get(name)
+		if !ok {
+			writeError(w, http.StatusNotFound, "unknown asset pack: "+name)
+			return
+		}
+		reload = pack.Reload
+	}
+
+	defaultPack, _ := h.packs.Get("")
+	resp := ReloadResponse{}
+	status := http.StatusOK
+	if err := reload(); err != nil {
+		resp.Error = "reload failed: " + err.Error()
+		status = http.StatusInternalServerError
+	}
+	resp.Reload = defaultPack.Status()
+	resp.Packs = h.packStatus()
+	writeJSON(w, status, resp)
 }
 
 // HandleOpenAPISpec handles GET /openapi.json
@@ -168,6 +841,72 @@ func (h *Handlers) HandleSwaggerUI(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(SwaggerUIHTML))
 }
 
+// buildAtlasManifest lists every atlas entry together with the texture it was built from
+func buildAtlasManifest(result *service.MergeResult) AtlasManifest {
+	manifest := AtlasManifest{
+		Width:    result.Atlas.Width,
+		Height:   result.Atlas.Height,
+		Entries:  []AtlasManifestEntry{},
+		Warnings: result.Warnings,
+	}
+
+	for _, tex := range result.Textures {
+		x, y, width, height, ok := result.Atlas.GetPixelCoords(tex.Name)
+		if !ok {
+			continue
+		}
+		manifest.Entries = append(manifest.Entries, AtlasManifestEntry{
+			Name:        tex.Name,
+			Category:    tex.Category,
+			X:           x,
+			Y:           y,
+			Width:       width,
+			Height:      height,
+			Texture:     tex.SourcePath,
+			GradientSet: tex.GradientSet,
+			Color:       tex.Color,
+		})
+	}
+
+	return manifest
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeMergeError maps merge failures to status codes: malformed JSON, codes
+// and unknown formats are a 400, invalid fields are a 422 listing each field,
+// anything else is a 500
+func writeMergeError(w http.ResponseWriter, err error) {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.Is(err, service.ErrInvalidJSON), errors.Is(err, service.ErrInvalidCode), errors.Is(err, service.ErrUnknownFormat):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.As(err, &validationErr):
+		writeJSON(w, http.StatusUnprocessableEntity, ErrorResponse{
+			Error:  "character has invalid fields",
+			Fields: validationErr.Issues,
+		})
+	default:
+		writeError(w, http.StatusInternalServerError, "merge failed: "+err.Error())
+	}
+}
+
+// characterFormat returns the ?format= character format, see service.ImportCharacter
+func characterFormat(r *http.Request) string {
+	return r.URL.Query().Get("format")
+}
+
+// strictQuery reports whether ?strict=true was passed, for endpoints whose
+// body is the character itself
+func strictQuery(r *http.Request) bool {
+	strict, _ := strconv.ParseBool(r.URL.Query().Get("strict"))
+	return strict
+}
+
 func writeError(w http.ResponseWriter, status int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
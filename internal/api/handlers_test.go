@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"blockyserver/internal/service"
+)
+
+func TestRenderLimits(t *testing.T) {
+	h := newTestHandlers(t)
+	character := `"character":{"haircut":"Scavenger_Hair.Brown"}`
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+	}{
+		{"png width", h.HandlePNG, `{"width":-1,` + character + `}`},
+		{"png height", h.HandlePNG, `{"height":4097,` + character + `}`},
+		{"gif frames", h.HandleGIF, `{"frames":-1,` + character + `}`},
+		{"gif delay", h.HandleGIF, `{"delay":-1,` + character + `}`},
+		{"mp4 frames", h.HandleMP4, `{"frames":721,` + character + `}`},
+		{"mp4 fps", h.HandleMP4, `{"fps":-1,` + character + `}`},
+		{"item size", h.HandleItem, `{"category":"haircut","item":"Scavenger_Hair","width":-1}`},
+		{"random size", h.HandleRandom, `{"width":-1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("got status %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body)
+			}
+		})
+	}
+}
+
+func TestHandleItemCategory(t *testing.T) {
+	h := newTestHandlers(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		reason string
+	}{
+		{"category looked up", `{"item":"Scavenger_Hair.Brown","width":32,"height":32}`, http.StatusOK, ""},
+		{"category given", `{"category":"pants","item":"Pants_A.Red.Long","width":32,"height":32}`, http.StatusOK, ""},
+		{"ID in several categories", `{"item":"Pants_A.Red.Long"}`, http.StatusUnprocessableEntity, service.ReasonAmbiguousID},
+		{"unknown ID", `{"item":"Scavenger_Hiar.Brown"}`, http.StatusUnprocessableEntity, service.ReasonUnknownID},
+		{"missing item", `{"category":"haircut"}`, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.HandleItem(rec, httptest.NewRequest(http.MethodPost, "/render/item", strings.NewReader(tt.body)))
+			if rec.Code != tt.status {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.reason != "" {
+				var resp ErrorResponse
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				if len(resp.Fields) != 1 || resp.Fields[0].Reason != tt.reason {
+					t.Errorf("got fields %+v, want %s", resp.Fields, tt.reason)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"blockyserver/internal/cache"
+	"blockyserver/internal/jobs"
+	"blockyserver/internal/render"
+	"blockyserver/internal/service"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// HandleSubmitJob handles POST /jobs. The body is a batch job: a render
+// request of any of the glb, png, gif or mp4 endpoints with its format, and
+// optionally a webhook to notify when the job finishes. The job is checked
+// and queued, and rendered in the background.
+func (h *Handlers) HandleSubmitJob(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	var req JobRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	delete(fields, "webhook")
+
+	if req.Webhook != "" {
+		if !h.jobs.WebhooksEnabled() {
+			writeError(w, http.StatusBadRequest, "webhooks are disabled, set BLOCKY_WEBHOOK_SECRET to enable them")
+			return
+		}
+		if err := h.jobs.ValidWebhook(r.Context(), req.Webhook); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	pack, ok := h.pack(w, r, req.PackSelector)
+	if !ok {
+		return
+	}
+	defer pack.release()
+	charFormat := characterFormat(r)
+
+	job, err := newBatchJob(nil, fields, charFormat)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.formatEnabled(job.format) {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("/render/%s is disabled", job.format))
+		return
+	}
+
+	// Share codes are decoded now so a bad code fails the request, not the job
+	if fields["character"], ok = expandCode(w, pack, job.character); !ok {
+		return
+	}
+	request, err := json.Marshal(fields)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	queued := &jobs.Job{
+		Format:          job.format,
+		AssetPack:       pack.name,
+		Progress:        jobs.Progress{Total: job.frames},
+		Webhook:         req.Webhook,
+		Request:         request,
+		CharacterFormat: charFormat,
+	}
+	if err := h.jobs.Submit(queued); err != nil {
+		if errors.Is(err, jobs.ErrQueueFull) {
+			w.Header().Set("Retry-After", "60")
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "queueing job: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Location", "/jobs/"+queued.ID)
+	writeJSON(w, http.StatusAccepted, queued)
+}
+
+// HandleJob handles GET /jobs/{id}
+func (h *Handlers) HandleJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobs.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// HandleJobResult handles GET /jobs/{id}/result. Jobs that have not
+// finished, or failed, answer 409 with their status.
+func (h *Handlers) HandleJobResult(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobs.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if job.Status != jobs.StatusDone {
+		writeJSON(w, http.StatusConflict, job)
+		return
+	}
+
+	f, err := h.jobs.OpenResult(job.ID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "job result is gone: "+err.Error())
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", job.ContentType)
+	if job.Filename != "" {
+		w.Header().Set("Content-Disposition", "attachment; filename="+job.Filename)
+	}
+	if len(job.Warnings) > 0 {
+		w.Header().Set(warningsHeader, encodeWarnings(job.Warnings))
+	}
+	w.Header().Set(packHeader, job.AssetPack)
+	http.ServeContent(w, r, "", *job.Finished, f)
+}
+
+// runJob renders a queued job like the matching render endpoint would,
+// sharing its render cache
+func (h *Handlers) runJob(queued *jobs.Job, progress func(jobs.Progress)) (*cache.RenderEntry, error) {
+	reloader, ok := h.packs.Get(queued.AssetPack)
+	if !ok {
+		return nil, fmt.Errorf("asset pack %q is no longer loaded", queued.AssetPack)
+	}
+	svc, release := reloader.Acquire()
+	defer release()
+	pack := &servedPack{name: queued.AssetPack, svc: svc, release: release}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(queued.Request, &fields); err != nil {
+		return nil, err
+	}
+	job, err := newBatchJob(nil, fields, queued.CharacterFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	key, cacheable := renderKey(pack, job.format, job.character, queued.CharacterFormat, job.options)
+	if cacheable {
+		if entry, hit := h.renders.Get(job.format, key); hit {
+			return entry, nil
+		}
+	}
+
+	result, err := pack.svc.MergeFromJSON(job.character, service.MergeOptions{Strict: job.strict, Format: queued.CharacterFormat})
+	if err != nil {
+		return nil, err
+	}
+	data, err := job.render(result, func(e render.ProgressEvent) error {
+		progress(jobs.Progress{Stage: e.Stage, Done: e.Done, Total: e.Total, Percent: e.Percent})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("render failed: %w", err)
+	}
+
+	entry := &cache.RenderEntry{
+		ContentType: batchContentTypes[job.format],
+		Warnings:    encodeWarnings(result.Warnings),
+		Data:        data,
+	}
+	if job.format == "glb" {
+		// As served by /render/glb
+		entry.Filename = "character.glb"
+	}
+	if cacheable {
+		h.renders.Add(job.format, key, entry)
+	}
+	return entry, nil
+}
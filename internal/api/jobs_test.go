@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"blockyserver/internal/jobs"
+)
+
+func TestHandleSubmitJob(t *testing.T) {
+	h := newTestHandlers(t)
+	queue, err := jobs.Open(jobs.Options{Dir: t.TempDir(), MaxQueued: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.jobs = queue
+	character := `"character":{"haircut":"Scavenger_Hair.Brown"}`
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"gif", `{"format":"gif","frames":4,` + character + `}`, http.StatusAccepted},
+		{"negative frames", `{"format":"gif","frames":-1,` + character + `}`, http.StatusBadRequest},
+		{"too wide", `{"format":"mp4","width":100000,` + character + `}`, http.StatusBadRequest},
+		{"zero fps", `{"format":"mp4","fps":-5,` + character + `}`, http.StatusBadRequest},
+		{"negative delay", `{"format":"gif","delay":-1,` + character + `}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.HandleSubmitJob(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(tt.body)))
+			if rec.Code != tt.status {
+				t.Errorf("got status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+	if got := queue.Count()[jobs.StatusQueued]; got != 1 {
+		t.Errorf("got %d queued jobs, want 1", got)
+	}
+}
@@ -1,8 +1,10 @@
 package api
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"blockyserver/internal/config"
 )
@@ -24,12 +26,42 @@ func EndpointGuard(enabled bool, endpointName string) func(http.Handler) http.Ha
 	}
 }
 
+// AdminAuth creates middleware that requires "Authorization: Bearer <token>".
+// Admin endpoints are disabled with 403 when no token is configured.
+func AdminAuth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				writeError(w, http.StatusForbidden, "admin endpoints are disabled")
+				return
+			}
+			given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, "invalid admin token")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // NewEndpointGuards creates guards for all render endpoints based on config
 func NewEndpointGuards(cfg *config.EndpointConfig) map[string]func(http.Handler) http.Handler {
 	return map[string]func(http.Handler) http.Handler{
-		"glb": EndpointGuard(cfg.GLBEnabled, "/render/glb"),
-		"png": EndpointGuard(cfg.PNGEnabled, "/render/png"),
-		"gif": EndpointGuard(cfg.GIFEnabled, "/render/gif"),
-		"mp4": EndpointGuard(cfg.MP4Enabled, "/render/mp4"),
+		"glb":         EndpointGuard(cfg.GLBEnabled, "/render/glb"),
+		"png":         EndpointGuard(cfg.PNGEnabled, "/render/png"),
+		"gif":         EndpointGuard(cfg.GIFEnabled, "/render/gif"),
+		"mp4":         EndpointGuard(cfg.MP4Enabled, "/render/mp4"),
+		"obj":         EndpointGuard(cfg.OBJEnabled, "/render/obj"),
+		"stl":         EndpointGuard(cfg.STLEnabled, "/render/stl"),
+		"vox":         EndpointGuard(cfg.VOXEnabled, "/render/vox"),
+		"atlas":       EndpointGuard(cfg.AtlasEnabled, "/render/atlas"),
+		"blockymodel": EndpointGuard(cfg.BlockyModelEnabled, "/render/blockymodel"),
+		"item":        EndpointGuard(cfg.ItemEnabled, "/render/item"),
+		"random":      EndpointGuard(cfg.RandomEnabled, "/random"),
+		"cosmetics":   EndpointGuard(cfg.CosmeticsEnabled, "/cosmetics"),
+		"batch":       EndpointGuard(cfg.BatchEnabled, "/render/batch"),
+		"jobs":        EndpointGuard(cfg.JobsEnabled, "/jobs"),
 	}
 }
@@ -30,7 +30,33 @@ const OpenAPISpec = `{
                   "properties": {
                     "status": {
                       "type": "string",
-                      "example": "ok"
+                      "enum": ["ok", "degraded"],
+                      "description": "degraded when the last reload failed; the previous assets keep serving"
+                    },
+                    "assetVersion": {
+                      "type": "string",
+                      "description": "Fingerprint of the loaded assets, part of every render cache key"
+                    },
+                    "cache": {
+                      "type": "object",
+                      "description": "Asset cache statistics for models, images and tinted textures",
+                      "additionalProperties": {"$ref": "#/components/schemas/CacheStats"}
+                    },
+                    "renderCache": {
+                      "type": "object",
+                      "description": "Render cache statistics per format; disk tiers are reported as \"<format>:disk\"",
+                      "additionalProperties": {"$ref": "#/components/schemas/CacheStats"}
+                    },
+                    "reload": {"$ref": "#/components/schemas/ReloadStatus"},
+                    "packs": {
+                      "type": "object",
+                      "description": "Reload status per asset pack; \"reload\" and \"assetVersion\" describe the default pack",
+                      "additionalProperties": {"$ref": "#/components/schemas/ReloadStatus"}
+                    },
+                    "jobs": {
+                      "type": "object",
+                      "description": "Render jobs by status, omitted when jobs are disabled",
+                      "additionalProperties": {"type": "integer"}
                     }
                   }
                 }
@@ -46,6 +72,12 @@ const OpenAPISpec = `{
         "description": "Renders a character and returns GLB binary file.",
         "operationId": "renderGLB",
         "tags": ["Render"],
+        "parameters": [
+          {"$ref": "#/components/parameters/Strict"},
+          {"$ref": "#/components/parameters/AssetPack"},
+          {"$ref": "#/components/parameters/CharacterFormat"},
+          {"$ref": "#/components/parameters/ProgressID"}
+        ],
         "requestBody": {
           "required": true,
           "content": {
@@ -57,8 +89,17 @@ const OpenAPISpec = `{
           }
         },
         "responses": {
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
           "200": {
             "description": "GLB binary file",
+            "headers": {
+              "X-Blocky-Warnings": {"$ref": "#/components/headers/Warnings"},
+              "X-Blocky-Cache": {"$ref": "#/components/headers/RenderCache"},
+              "X-Blocky-Asset-Pack": {"$ref": "#/components/headers/AssetPack"},
+              "ETag": {"$ref": "#/components/headers/RenderETag"}
+            },
             "content": {
               "model/gltf-binary": {
                 "schema": {
@@ -71,6 +112,9 @@ const OpenAPISpec = `{
           "400": {
             "$ref": "#/components/responses/BadRequest"
           },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          },
           "500": {
             "$ref": "#/components/responses/InternalError"
           }
@@ -83,6 +127,10 @@ const OpenAPISpec = `{
         "description": "Renders a character as a PNG image with configurable rotation and background.",
         "operationId": "renderPNG",
         "tags": ["Render"],
+        "parameters": [
+          {"$ref": "#/components/parameters/CharacterFormat"},
+          {"$ref": "#/components/parameters/ProgressID"}
+        ],
         "requestBody": {
           "required": true,
           "content": {
@@ -94,8 +142,66 @@ const OpenAPISpec = `{
           }
         },
         "responses": {
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
+          "200": {
+            "description": "PNG image",
+            "headers": {
+              "X-Blocky-Warnings": {"$ref": "#/components/headers/Warnings"},
+              "X-Blocky-Cache": {"$ref": "#/components/headers/RenderCache"},
+              "X-Blocky-Asset-Pack": {"$ref": "#/components/headers/AssetPack"},
+              "ETag": {"$ref": "#/components/headers/RenderETag"}
+            },
+            "content": {
+              "image/png": {
+                "schema": {
+                  "type": "string",
+                  "format": "binary"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      },
+      "get": {
+        "summary": "Render character as PNG from the query string",
+        "description": "Same as POST with the request fields as query parameters, so the render can be used directly as an image or video URL. URLs longer than BLOCKY_MAX_URL_LENGTH are rejected with 414.",
+        "operationId": "renderPNGGet",
+        "tags": ["Render"],
+        "parameters": [
+          {"$ref": "#/components/parameters/Character"},
+          {"name": "rotation", "in": "query", "schema": {"type": "number"}, "description": "Rotation in degrees"},
+          {"name": "background", "in": "query", "schema": {"type": "string", "default": "transparent"}, "description": "\"transparent\" or hex \"#RRGGBB\""},
+          {"name": "width", "in": "query", "schema": {"type": "integer", "default": 512, "minimum": 1, "maximum": 4096}},
+          {"name": "height", "in": "query", "schema": {"type": "integer", "default": 512, "minimum": 1, "maximum": 4096}},
+          {"$ref": "#/components/parameters/Strict"},
+          {"$ref": "#/components/parameters/AssetPack"},
+          {"$ref": "#/components/parameters/CharacterFormat"},
+          {"$ref": "#/components/parameters/ProgressID"},
+          {"$ref": "#/components/parameters/ProgressIDQuery"}
+        ],
+        "responses": {
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
           "200": {
             "description": "PNG image",
+            "headers": {
+              "X-Blocky-Warnings": {"$ref": "#/components/headers/Warnings"},
+              "X-Blocky-Cache": {"$ref": "#/components/headers/RenderCache"},
+              "X-Blocky-Asset-Pack": {"$ref": "#/components/headers/AssetPack"},
+              "ETag": {"$ref": "#/components/headers/RenderETag"}
+            },
             "content": {
               "image/png": {
                 "schema": {
@@ -108,6 +214,12 @@ const OpenAPISpec = `{
           "400": {
             "$ref": "#/components/responses/BadRequest"
           },
+          "414": {
+            "$ref": "#/components/responses/URITooLong"
+          },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          },
           "500": {
             "$ref": "#/components/responses/InternalError"
           }
@@ -120,6 +232,10 @@ const OpenAPISpec = `{
         "description": "Renders a character as an animated rotating GIF.",
         "operationId": "renderGIF",
         "tags": ["Render"],
+        "parameters": [
+          {"$ref": "#/components/parameters/CharacterFormat"},
+          {"$ref": "#/components/parameters/ProgressID"}
+        ],
         "requestBody": {
           "required": true,
           "content": {
@@ -131,8 +247,69 @@ const OpenAPISpec = `{
           }
         },
         "responses": {
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
+          "200": {
+            "description": "Animated GIF",
+            "headers": {
+              "X-Blocky-Warnings": {"$ref": "#/components/headers/Warnings"},
+              "X-Blocky-Cache": {"$ref": "#/components/headers/RenderCache"},
+              "X-Blocky-Asset-Pack": {"$ref": "#/components/headers/AssetPack"},
+              "ETag": {"$ref": "#/components/headers/RenderETag"}
+            },
+            "content": {
+              "image/gif": {
+                "schema": {
+                  "type": "string",
+                  "format": "binary"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      },
+      "get": {
+        "summary": "Render character as animated GIF from the query string",
+        "description": "Same as POST with the request fields as query parameters, so the render can be used directly as an image or video URL. URLs longer than BLOCKY_MAX_URL_LENGTH are rejected with 414.",
+        "operationId": "renderGIFGet",
+        "tags": ["Render"],
+        "parameters": [
+          {"$ref": "#/components/parameters/Character"},
+          {"name": "background", "in": "query", "schema": {"type": "string"}, "description": "Hex color \"#RRGGBB\""},
+          {"name": "frames", "in": "query", "schema": {"type": "integer", "default": 36, "minimum": 1, "maximum": 720}},
+          {"name": "width", "in": "query", "schema": {"type": "integer", "default": 512, "minimum": 1, "maximum": 4096}},
+          {"name": "height", "in": "query", "schema": {"type": "integer", "default": 512, "minimum": 1, "maximum": 4096}},
+          {"name": "delay", "in": "query", "schema": {"type": "integer", "default": 5, "minimum": 1, "maximum": 65535}, "description": "Centiseconds between frames"},
+          {"name": "dithering", "in": "query", "schema": {"type": "boolean", "default": true}},
+          {"name": "autoZoom", "in": "query", "schema": {"type": "boolean", "default": true}},
+          {"$ref": "#/components/parameters/Strict"},
+          {"$ref": "#/components/parameters/AssetPack"},
+          {"$ref": "#/components/parameters/CharacterFormat"},
+          {"$ref": "#/components/parameters/ProgressID"},
+          {"$ref": "#/components/parameters/ProgressIDQuery"}
+        ],
+        "responses": {
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
           "200": {
             "description": "Animated GIF",
+            "headers": {
+              "X-Blocky-Warnings": {"$ref": "#/components/headers/Warnings"},
+              "X-Blocky-Cache": {"$ref": "#/components/headers/RenderCache"},
+              "X-Blocky-Asset-Pack": {"$ref": "#/components/headers/AssetPack"},
+              "ETag": {"$ref": "#/components/headers/RenderETag"}
+            },
             "content": {
               "image/gif": {
                 "schema": {
@@ -145,6 +322,12 @@ const OpenAPISpec = `{
           "400": {
             "$ref": "#/components/responses/BadRequest"
           },
+          "414": {
+            "$ref": "#/components/responses/URITooLong"
+          },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          },
           "500": {
             "$ref": "#/components/responses/InternalError"
           }
@@ -157,6 +340,10 @@ const OpenAPISpec = `{
         "description": "Renders a character as an MP4 video rotating 360 degrees.",
         "operationId": "renderMP4",
         "tags": ["Render"],
+        "parameters": [
+          {"$ref": "#/components/parameters/CharacterFormat"},
+          {"$ref": "#/components/parameters/ProgressID"}
+        ],
         "requestBody": {
           "required": true,
           "content": {
@@ -168,8 +355,17 @@ const OpenAPISpec = `{
           }
         },
         "responses": {
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
           "200": {
             "description": "MP4 video",
+            "headers": {
+              "X-Blocky-Warnings": {"$ref": "#/components/headers/Warnings"},
+              "X-Blocky-Cache": {"$ref": "#/components/headers/RenderCache"},
+              "X-Blocky-Asset-Pack": {"$ref": "#/components/headers/AssetPack"},
+              "ETag": {"$ref": "#/components/headers/RenderETag"}
+            },
             "content": {
               "video/mp4": {
                 "schema": {
@@ -182,89 +378,1778 @@ const OpenAPISpec = `{
           "400": {
             "$ref": "#/components/responses/BadRequest"
           },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          },
           "500": {
             "$ref": "#/components/responses/InternalError"
           }
         }
-      }
-    }
-  },
-  "components": {
-    "schemas": {
-      "CharacterConfig": {
-        "type": "object",
-        "description": "Character appearance configuration. All fields are optional. Format: \"AccessoryId.Color.Variant\"",
-        "properties": {
-          "bodyCharacteristic": {"type": "string", "example": "Default.02", "description": "Body type and skin tone"},
-          "underwear": {"type": "string", "example": "Underwear_Male", "description": "Base underwear"},
-          "face": {"type": "string", "example": "Face_A", "description": "Face shape"},
-          "ears": {"type": "string", "example": "Ears_A", "description": "Ear type"},
-          "mouth": {"type": "string", "example": "Mouth_A", "description": "Mouth shape"},
-          "haircut": {"type": "string", "example": "Scavenger_Hair.PitchBlack", "description": "Hair style and color"},
-          "facialHair": {"type": "string", "example": "Beard_A.Brown", "description": "Beard/mustache"},
-          "eyebrows": {"type": "string", "example": "Eyebrows_A.Black", "description": "Eyebrow style and color"},
-          "eyes": {"type": "string", "example": "Large_Eyes.Pink", "description": "Eye style and color"},
-          "pants": {"type": "string", "example": "Pants_A.Blue", "description": "Lower body clothing"},
-          "overpants": {"type": "string", "description": "Pants overlay (belt, etc.)"},
-          "undertop": {"type": "string", "example": "Shirt_A.White", "description": "Shirt/undershirt"},
-          "overtop": {"type": "string", "example": "Jacket_A.Red", "description": "Jacket/coat"},
-          "shoes": {"type": "string", "example": "Boots_A.Brown", "description": "Footwear"},
-          "headAccessory": {"type": "string", "description": "Hat/helmet"},
-          "faceAccessory": {"type": "string", "description": "Glasses/mask"},
-          "earAccessory": {"type": "string", "description": "Earrings"},
-          "skinFeature": {"type": "string", "description": "Tattoos/markings"},
-          "gloves": {"type": "string", "description": "Hand accessories"},
-          "cape": {"type": "string", "description": "Back cape"}
-        }
       },
-      "PNGRequest": {
-        "type": "object",
-        "required": ["character"],
-        "properties": {
-          "character": {"$ref": "#/components/schemas/CharacterConfig"},
-          "rotation": {"type": "number", "default": 0, "description": "Rotation in degrees"},
-          "background": {"type": "string", "default": "transparent", "description": "\"transparent\" or hex color \"#RRGGBB\""},
-          "width": {"type": "integer", "default": 512, "description": "Image width in pixels"},
-          "height": {"type": "integer", "default": 512, "description": "Image height in pixels"}
+      "get": {
+        "summary": "Render character as MP4 from the query string",
+        "description": "Same as POST with the request fields as query parameters, so the render can be used directly as an image or video URL. URLs longer than BLOCKY_MAX_URL_LENGTH are rejected with 414.",
+        "operationId": "renderMP4Get",
+        "tags": ["Render"],
+        "parameters": [
+          {"$ref": "#/components/parameters/Character"},
+          {"name": "background", "in": "query", "schema": {"type": "string", "default": "#FFFFFF"}, "description": "Hex color \"#RRGGBB\""},
+          {"name": "frames", "in": "query", "schema": {"type": "integer", "default": 36, "minimum": 1, "maximum": 720}},
+          {"name": "width", "in": "query", "schema": {"type": "integer", "default": 512, "minimum": 1, "maximum": 4096}},
+          {"name": "height", "in": "query", "schema": {"type": "integer", "default": 512, "minimum": 1, "maximum": 4096}},
+          {"name": "fps", "in": "query", "schema": {"type": "integer", "default": 12, "minimum": 1, "maximum": 120}},
+          {"name": "autoZoom", "in": "query", "schema": {"type": "boolean", "default": true}},
+          {"$ref": "#/components/parameters/Strict"},
+          {"$ref": "#/components/parameters/AssetPack"},
+          {"$ref": "#/components/parameters/CharacterFormat"},
+          {"$ref": "#/components/parameters/ProgressID"},
+          {"$ref": "#/components/parameters/ProgressIDQuery"}
+        ],
+        "responses": {
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
+          "200": {
+            "description": "MP4 video",
+            "headers": {
+              "X-Blocky-Warnings": {"$ref": "#/components/headers/Warnings"},
+              "X-Blocky-Cache": {"$ref": "#/components/headers/RenderCache"},
+              "X-Blocky-Asset-Pack": {"$ref": "#/components/headers/AssetPack"},
+              "ETag": {"$ref": "#/components/headers/RenderETag"}
+            },
+            "content": {
+              "video/mp4": {
+                "schema": {
+                  "type": "string",
+                  "format": "binary"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "414": {
+            "$ref": "#/components/responses/URITooLong"
+          },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
         }
-      },
-      "GIFRequest": {
-        "type": "object",
-        "required": ["character"],
-        "properties": {
-          "character": {"$ref": "#/components/schemas/CharacterConfig"},
-          "background": {"type": "string", "default": "#FFFFFF", "description": "Hex color (no transparency for GIF)"},
-          "frames": {"type": "integer", "default": 36, "description": "Number of frames (36 = 10° per frame)"},
-          "width": {"type": "integer", "default": 512, "description": "Image width in pixels"},
-          "height": {"type": "integer", "default": 512, "description": "Image height in pixels"},
-          "delay": {"type": "integer", "default": 5, "description": "Centiseconds between frames"},
-          "dithering": {"type": "boolean", "default": true, "description": "Enable Floyd-Steinberg dithering (disable for faster rendering)"},
-          "autoZoom": {"type": "boolean", "default": true, "description": "Auto-zoom camera to fit character tightly in frame"}
+      }
+    },
+    "/render/obj": {
+      "post": {
+        "summary": "Export character as OBJ",
+        "description": "Exports a character as a ZIP archive containing a Wavefront OBJ, its MTL material and the atlas texture as PNG.",
+        "operationId": "renderOBJ",
+        "tags": ["Export"],
+        "parameters": [
+          {"$ref": "#/components/parameters/Strict"},
+          {"$ref": "#/components/parameters/AssetPack"},
+          {"$ref": "#/components/parameters/CharacterFormat"},
+          {"$ref": "#/components/parameters/ProgressID"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/CharacterConfig"
+              }
+            }
+          }
+        },
+        "responses": {
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
+          "200": {
+            "description": "ZIP archive with character.obj, character.mtl and character.png",
+            "headers": {
+              "X-Blocky-Warnings": {"$ref": "#/components/headers/Warnings"},
+              "X-Blocky-Cache": {"$ref": "#/components/headers/RenderCache"},
+              "X-Blocky-Asset-Pack": {"$ref": "#/components/headers/AssetPack"},
+              "ETag": {"$ref": "#/components/headers/RenderETag"}
+            },
+            "content": {
+              "application/zip": {
+                "schema": {
+                  "type": "string",
+                  "format": "binary"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
         }
-      },
-      "MP4Request": {
-        "type": "object",
-        "required": ["character"],
-        "properties": {
-          "character": {"$ref": "#/components/schemas/CharacterConfig"},
-          "background": {"type": "string", "default": "#FFFFFF", "description": "Hex color background"},
-          "frames": {"type": "integer", "default": 36, "description": "Number of frames (36 = 10° per frame)"},
-          "width": {"type": "integer", "default": 512, "description": "Video width in pixels"},
-          "height": {"type": "integer", "default": 512, "description": "Video height in pixels"},
-          "fps": {"type": "integer", "default": 12, "description": "Frames per second"},
-          "autoZoom": {"type": "boolean", "default": true, "description": "Auto-zoom camera to fit character tightly in frame"}
+      }
+    },
+    "/render/stl": {
+      "post": {
+        "summary": "Export character as STL",
+        "description": "Exports a character as a binary STL for 3D printing, scaled to the requested height in millimetres. Every box is a closed shell; shapes that are not closed solids, such as single planes, are left out and reported as not_printable warnings.",
+        "operationId": "renderSTL",
+        "tags": ["Export"],
+        "parameters": [
+          {"$ref": "#/components/parameters/CharacterFormat"},
+          {"$ref": "#/components/parameters/ProgressID"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/STLRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
+          "200": {
+            "description": "Binary STL",
+            "headers": {
+              "X-Blocky-Warnings": {"$ref": "#/components/headers/Warnings"},
+              "X-Blocky-Cache": {"$ref": "#/components/headers/RenderCache"},
+              "X-Blocky-Asset-Pack": {"$ref": "#/components/headers/AssetPack"},
+              "ETag": {"$ref": "#/components/headers/RenderETag"}
+            },
+            "content": {
+              "model/stl": {
+                "schema": {
+                  "type": "string",
+                  "format": "binary"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
         }
-      },
-      "ErrorResponse": {
-        "type": "object",
-        "properties": {
-          "error": {"type": "string", "description": "Error message"}
+      }
+    },
+    "/render/vox": {
+      "post": {
+        "summary": "Export character as MagicaVoxel model",
+        "description": "Voxelizes a character at the requested resolution, sampling colours from the tinted atlas, and returns a .vox file with a palette of up to 255 colours.",
+        "operationId": "renderVOX",
+        "tags": ["Export"],
+        "parameters": [
+          {"$ref": "#/components/parameters/CharacterFormat"},
+          {"$ref": "#/components/parameters/ProgressID"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/VOXRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
+          "200": {
+            "description": "MagicaVoxel .vox file",
+            "headers": {
+              "X-Blocky-Warnings": {"$ref": "#/components/headers/Warnings"},
+              "X-Blocky-Cache": {"$ref": "#/components/headers/RenderCache"},
+              "X-Blocky-Asset-Pack": {"$ref": "#/components/headers/AssetPack"},
+              "ETag": {"$ref": "#/components/headers/RenderETag"}
+            },
+            "content": {
+              "application/octet-stream": {
+                "schema": {
+                  "type": "string",
+                  "format": "binary"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
         }
       }
     },
-    "responses": {
-      "BadRequest": {
-        "description": "Bad request",
+    "/render/atlas": {
+      "post": {
+        "summary": "Get packed texture atlas",
+        "description": "Returns the packed, tinted texture atlas used for a character, optionally with the UV layout drawn on top, and a manifest of each accessory's rectangle, source texture and tint.",
+        "operationId": "renderAtlas",
+        "tags": ["Debug"],
+        "parameters": [
+          {"$ref": "#/components/parameters/CharacterFormat"},
+          {"$ref": "#/components/parameters/ProgressID"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/AtlasRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
+          "200": {
+            "description": "ZIP with atlas.png and manifest.json, the atlas PNG, or the manifest JSON depending on format",
+            "headers": {
+              "X-Blocky-Warnings": {"$ref": "#/components/headers/Warnings"},
+              "X-Blocky-Cache": {"$ref": "#/components/headers/RenderCache"},
+              "X-Blocky-Asset-Pack": {"$ref": "#/components/headers/AssetPack"},
+              "ETag": {"$ref": "#/components/headers/RenderETag"}
+            },
+            "content": {
+              "application/zip": {
+                "schema": {
+                  "type": "string",
+                  "format": "binary"
+                }
+              },
+              "image/png": {
+                "schema": {
+                  "type": "string",
+                  "format": "binary"
+                }
+              },
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/AtlasManifest"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      }
+    },
+    "/render/blockymodel": {
+      "post": {
+        "summary": "Export character as .blockymodel",
+        "description": "Exports the merged character in Hytale's native .blockymodel JSON format as a ZIP archive together with the atlas texture. Texture layout offsets point into the included atlas.",
+        "operationId": "renderBlockyModel",
+        "tags": ["Export"],
+        "parameters": [
+          {"$ref": "#/components/parameters/Strict"},
+          {"$ref": "#/components/parameters/AssetPack"},
+          {"$ref": "#/components/parameters/CharacterFormat"},
+          {"$ref": "#/components/parameters/ProgressID"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/CharacterConfig"
+              }
+            }
+          }
+        },
+        "responses": {
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
+          "200": {
+            "description": "ZIP archive with character.blockymodel and character.png",
+            "headers": {
+              "X-Blocky-Warnings": {"$ref": "#/components/headers/Warnings"},
+              "X-Blocky-Cache": {"$ref": "#/components/headers/RenderCache"},
+              "X-Blocky-Asset-Pack": {"$ref": "#/components/headers/AssetPack"},
+              "ETag": {"$ref": "#/components/headers/RenderETag"}
+            },
+            "content": {
+              "application/zip": {
+                "schema": {
+                  "type": "string",
+                  "format": "binary"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      }
+    },
+    "/render/item": {
+      "post": {
+        "summary": "Render a single cosmetic as PNG",
+        "description": "Renders one cosmetic, such as \"Jacket_A.Red\" in the overtop category, without the player body, framed to the item. Intended for shop icons. With mannequin the item is shown on a faded player body. Slot rules are not applied.",
+        "operationId": "renderItem",
+        "tags": ["Render"],
+        "parameters": [
+          {"$ref": "#/components/parameters/ProgressID"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ItemRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
+          "200": {
+            "description": "PNG image",
+            "headers": {
+              "X-Blocky-Warnings": {"$ref": "#/components/headers/Warnings"},
+              "X-Blocky-Cache": {"$ref": "#/components/headers/RenderCache"},
+              "X-Blocky-Asset-Pack": {"$ref": "#/components/headers/AssetPack"},
+              "ETag": {"$ref": "#/components/headers/RenderETag"}
+            },
+            "content": {
+              "image/png": {
+                "schema": {
+                  "type": "string",
+                  "format": "binary"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      },
+      "get": {
+        "summary": "Render a single cosmetic as PNG from the query string",
+        "description": "Same as POST with the request fields as query parameters, so the render can be used directly as an image or video URL. URLs longer than BLOCKY_MAX_URL_LENGTH are rejected with 414.",
+        "operationId": "renderItemGet",
+        "tags": ["Render"],
+        "parameters": [
+          {"name": "category", "in": "query", "schema": {"type": "string"}, "description": "Character field of the item, e.g. \"overtop\". Looked up from the item ID when omitted; required only for IDs found in several categories"},
+          {"name": "item", "in": "query", "required": true, "schema": {"type": "string"}, "description": "Item as \"Id\", \"Id.Color\" or \"Id.Color.Variant\""},
+          {"name": "mannequin", "in": "query", "schema": {"type": "boolean", "default": false}, "description": "Show the item on a faded player body"},
+          {"name": "skinTone", "in": "query", "schema": {"type": "string"}, "description": "Skin tone for skin-colored items without a color"},
+          {"name": "rotation", "in": "query", "schema": {"type": "number"}, "description": "Rotation in degrees"},
+          {"name": "background", "in": "query", "schema": {"type": "string", "default": "transparent"}, "description": "\"transparent\" or hex \"#RRGGBB\""},
+          {"name": "width", "in": "query", "schema": {"type": "integer", "default": 512, "minimum": 1, "maximum": 4096}},
+          {"name": "height", "in": "query", "schema": {"type": "integer", "default": 512, "minimum": 1, "maximum": 4096}},
+          {"$ref": "#/components/parameters/Strict"},
+          {"$ref": "#/components/parameters/AssetPack"},
+          {"$ref": "#/components/parameters/ProgressID"},
+          {"$ref": "#/components/parameters/ProgressIDQuery"}
+        ],
+        "responses": {
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
+          "200": {
+            "description": "PNG image",
+            "headers": {
+              "X-Blocky-Warnings": {"$ref": "#/components/headers/Warnings"},
+              "X-Blocky-Cache": {"$ref": "#/components/headers/RenderCache"},
+              "X-Blocky-Asset-Pack": {"$ref": "#/components/headers/AssetPack"},
+              "ETag": {"$ref": "#/components/headers/RenderETag"}
+            },
+            "content": {
+              "image/png": {
+                "schema": {
+                  "type": "string",
+                  "format": "binary"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "414": {
+            "$ref": "#/components/responses/URITooLong"
+          },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      }
+    },
+    "/catalog": {
+      "get": {
+        "summary": "List cosmetics",
+        "description": "Lists every cosmetic in the loaded registry with its allowed colors, variants, type metadata and asset paths.",
+        "operationId": "getCatalog",
+        "tags": ["Catalog"],
+        "parameters": [
+          {"name": "category", "in": "query", "schema": {"type": "string"}, "description": "Comma-separated character fields, e.g. \"haircut,eyes\""},
+          {"name": "q", "in": "query", "schema": {"type": "string"}, "description": "Case-insensitive substring of the cosmetic ID or name"},
+          {"name": "gradientSet", "in": "query", "schema": {"type": "string"}, "description": "Only cosmetics tinted with this gradient set"},
+          {"name": "color", "in": "query", "schema": {"type": "string"}, "description": "Only cosmetics accepting this color"},
+          {"name": "type", "in": "query", "schema": {"type": "string"}, "description": "HeadAccessoryType or HairType"},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "default": 100, "minimum": 1, "maximum": 1000}},
+          {"name": "offset", "in": "query", "schema": {"type": "integer", "default": 0, "minimum": 0}},
+          {"$ref": "#/components/parameters/AssetPack"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Page of catalog items. Responses carry an ETag; send it back in If-None-Match to get 304.",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/CatalogResponse"}
+              }
+            }
+          },
+          "304": {
+            "description": "Not modified"
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          }
+        }
+      }
+    },
+    "/catalog/{category}": {
+      "get": {
+        "summary": "List cosmetics of a category",
+        "description": "Lists the cosmetics usable in a single character field.",
+        "operationId": "getCatalogCategory",
+        "tags": ["Catalog"],
+        "parameters": [
+          {"name": "category", "in": "path", "required": true, "schema": {"type": "string"}, "example": "haircut"},
+          {"name": "q", "in": "query", "schema": {"type": "string"}, "description": "Case-insensitive substring of the cosmetic ID or name"},
+          {"name": "gradientSet", "in": "query", "schema": {"type": "string"}, "description": "Only cosmetics tinted with this gradient set"},
+          {"name": "color", "in": "query", "schema": {"type": "string"}, "description": "Only cosmetics accepting this color"},
+          {"name": "type", "in": "query", "schema": {"type": "string"}, "description": "HeadAccessoryType or HairType"},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "default": 100, "minimum": 1, "maximum": 1000}},
+          {"name": "offset", "in": "query", "schema": {"type": "integer", "default": 0, "minimum": 0}},
+          {"$ref": "#/components/parameters/AssetPack"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Page of catalog items. Responses carry an ETag; send it back in If-None-Match to get 304.",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/CatalogResponse"}
+              }
+            }
+          },
+          "304": {
+            "description": "Not modified"
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          }
+        }
+      }
+    },
+    "/validate": {
+      "post": {
+        "summary": "Validate a character",
+        "description": "Checks every field of a character against the loaded cosmetics without rendering. Unknown IDs, colors and variants are reported with suggestions.",
+        "operationId": "validateCharacter",
+        "tags": ["Catalog"],
+        "parameters": [
+          {"$ref": "#/components/parameters/AssetPack"},
+          {"$ref": "#/components/parameters/CharacterFormat"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/CharacterConfig"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Validation result",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ValidationResult"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          }
+        }
+      }
+    },
+    "/resolve": {
+      "post": {
+        "summary": "Resolve a character",
+        "description": "Returns the normalized character that would actually be rendered: fallback haircuts, removed categories, and the model and texture paths and gradient sets used. With strict=true, warnings are returned as a 422.",
+        "operationId": "resolveCharacter",
+        "tags": ["Catalog"],
+        "parameters": [
+          {"$ref": "#/components/parameters/Strict"},
+          {"$ref": "#/components/parameters/AssetPack"},
+          {"$ref": "#/components/parameters/CharacterFormat"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/CharacterConfig"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Resolved character",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ResolveResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          }
+        }
+      }
+    },
+    "/random": {
+      "post": {
+        "summary": "Generate a random character",
+        "description": "Builds a valid character from the loaded cosmetics using a seed, optionally rendering it in the same call.",
+        "operationId": "randomCharacter",
+        "tags": ["Catalog"],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/RandomRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Generated character",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/RandomResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      }
+    },
+    "/ready": {
+      "get": {
+        "summary": "Readiness check",
+        "description": "Returns 200 once assets are loaded, with the loaded asset version",
+        "operationId": "getReady",
+        "tags": ["System"],
+        "responses": {
+          "200": {
+            "description": "Assets are loaded",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ReloadStatus"}
+              }
+            }
+          }
+        }
+      }
+    },
+    "/admin/reload": {
+      "post": {
+        "summary": "Reload assets",
+        "description": "Reloads assets and catalog data from disk. The new version is checked before it is swapped in; if loading fails the current version keeps serving. Requires BLOCKY_ADMIN_TOKEN.",
+        "operationId": "reloadAssets",
+        "tags": ["System"],
+        "security": [{"adminToken": []}],
+        "parameters": [
+          {"name": "assetPack", "in": "query", "schema": {"type": "string"}, "description": "Reload only this pack; all packs are reloaded if omitted"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Assets reloaded",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ReloadResponse"}
+              }
+            }
+          },
+          "401": {
+            "description": "Missing or invalid admin token",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ErrorResponse"}
+              }
+            }
+          },
+          "403": {
+            "description": "Admin endpoints are disabled because BLOCKY_ADMIN_TOKEN is not set",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ErrorResponse"}
+              }
+            }
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "500": {
+            "description": "Reload failed, the previous assets keep serving",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ReloadResponse"}
+              }
+            }
+          }
+        }
+      }
+    },
+    "/packs": {
+      "get": {
+        "summary": "List asset packs",
+        "description": "Lists the loaded asset packs with their cosmetic counts and reload status",
+        "operationId": "getPacks",
+        "tags": ["System"],
+        "responses": {
+          "200": {
+            "description": "Loaded asset packs",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/PacksResponse"}
+              }
+            }
+          }
+        }
+      }
+    },
+    "/cosmetics": {
+      "post": {
+        "summary": "Upload a custom cosmetic",
+        "description": "Uploads a .blockymodel with a pre-colored or greyscale texture. The upload is checked against the selected asset pack (size limits, node count, a test merge onto the player model), stored, and registered as \"custom:<name>\" in every pack. Requires BLOCKY_ADMIN_TOKEN. Uploads with a pre-colored texture are used with the color \"Default\", e.g. \"custom:MyHat.Default\".",
+        "operationId": "uploadCosmetic",
+        "tags": ["Catalog"],
+        "security": [{"adminToken": []}],
+        "parameters": [
+          {"$ref": "#/components/parameters/AssetPack"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "multipart/form-data": {
+              "schema": {"$ref": "#/components/schemas/CosmeticUpload"}
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "Cosmetic stored",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/CatalogItem"}
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "description": "Missing or invalid admin token",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ErrorResponse"}
+              }
+            }
+          },
+          "403": {
+            "description": "Uploads are disabled, or BLOCKY_ADMIN_TOKEN is not set",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ErrorResponse"}
+              }
+            }
+          },
+          "409": {
+            "description": "A custom cosmetic with this name already exists in the category",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ErrorResponse"}
+              }
+            }
+          },
+          "413": {
+            "description": "Upload exceeds BLOCKY_CUSTOM_MAX_KB",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ErrorResponse"}
+              }
+            }
+          },
+          "422": {
+            "description": "Upload is invalid, listing each problem field",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ErrorResponse"}
+              }
+            }
+          },
+          "507": {
+            "description": "BLOCKY_CUSTOM_MAX_COUNT cosmetics are already stored",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ErrorResponse"}
+              }
+            }
+          }
+        }
+      }
+    },
+    "/rules": {
+      "get": {
+        "summary": "List character rules",
+        "description": "Lists the rules that remove or replace character slots: built-in head accessory rules, rules from the Disable* fields of the registry files, and rules from the local rules file. Rules of an outer slot (accessories, then clothing from the top layer down) are applied first.",
+        "operationId": "getRules",
+        "tags": ["Catalog"],
+        "parameters": [
+          {"$ref": "#/components/parameters/AssetPack"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Rules in the order they are checked within a field",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "rules": {"type": "array", "items": {"$ref": "#/components/schemas/Rule"}}
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          }
+        }
+      }
+    },
+    "/codes/encode": {
+      "post": {
+        "summary": "Encode a share code",
+        "description": "Packs a valid character into a compact base64url share code. Cosmetics, colors and variants are stored as their position in the registry files, custom cosmetics and hex colors by name. Codes keep decoding after cosmetics are appended to the registry files.",
+        "operationId": "encodeCode",
+        "tags": ["Catalog"],
+        "parameters": [
+          {"$ref": "#/components/parameters/AssetPack"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/CharacterConfig"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Share code and the character it decodes to",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/CodeResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          }
+        }
+      }
+    },
+    "/codes/decode": {
+      "post": {
+        "summary": "Decode a share code",
+        "description": "Unpacks a share code into a character. Malformed or corrupted codes, and codes made before cosmetics were reordered or removed, are a 400; codes naming custom cosmetics or colors that are no longer loaded are a 422 listing each field.",
+        "operationId": "decodeCode",
+        "tags": ["Catalog"],
+        "parameters": [
+          {"$ref": "#/components/parameters/AssetPack"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "required": ["code"],
+                "properties": {
+                  "code": {"type": "string", "example": "ASD__wdEZWZhdWx0__8HI2FhYmJjYyXpUgq3Kd0b__8PI2ZmMDAwMC0jMDBmZjAwE2-rzmI"},
+                  "assetPack": {"type": "string", "description": "Asset pack to decode against"},
+                  "version": {"type": "string", "description": "Alias of assetPack"}
+                }
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Decoded character",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/CodeResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          }
+        }
+      }
+    },
+    "/render/batch": {
+      "post": {
+        "summary": "Render many characters at once",
+        "description": "Renders a list of jobs, each with its own character, format (glb, png, gif or mp4) and the options of that format's endpoint, on top of shared defaults. Each distinct character is merged once however many jobs use it, and renders are shared with the render cache of the single endpoints. Outputs are streamed as a ZIP or multipart/mixed response as they are rendered, followed by manifest.json recording the outcome of every job; failed jobs do not fail the batch. Limited to BLOCKY_BATCH_MAX_JOBS jobs and BLOCKY_BATCH_TIMEOUT seconds, after which the remaining jobs fail.",
+        "operationId": "renderBatch",
+        "tags": ["Render"],
+        "parameters": [
+          {"$ref": "#/components/parameters/AssetPack"},
+          {"$ref": "#/components/parameters/CharacterFormat"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/BatchRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "One entry per successful job, named <id>.<format>, then manifest.json (BatchManifest)",
+            "headers": {
+              "X-Blocky-Asset-Pack": {"$ref": "#/components/headers/AssetPack"}
+            },
+            "content": {
+              "application/zip": {
+                "schema": {"type": "string", "format": "binary"}
+              },
+              "multipart/mixed": {
+                "schema": {"type": "string", "format": "binary"}
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "403": {
+            "description": "Batch renders or the render endpoint of a job's format are disabled",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ErrorResponse"}
+              }
+            }
+          }
+        }
+      }
+    },
+    "/jobs": {
+      "post": {
+        "summary": "Queue a render job",
+        "description": "Queues a render that may take longer than a request is allowed to, such as a long MP4 or GIF. The body is a job as in /render/batch: the request of the glb, png, gif or mp4 endpoint plus its format. The job is checked and queued, and the response carries its ID; poll GET /jobs/{id} for its progress and download the output from GET /jobs/{id}/result. If webhook is set, the job status is POSTed to it when the job finishes, with the Unix send time in X-Blocky-Timestamp and X-Blocky-Signature set to sha256=<hex HMAC-SHA256 of \"<timestamp>.<body>\"> keyed with BLOCKY_WEBHOOK_SECRET; receivers should reject timestamps more than 5 minutes old. Webhooks must resolve to public addresses unless their host is listed in BLOCKY_WEBHOOK_ALLOWED_HOSTS. Jobs are stored in BLOCKY_JOBS_DIR and survive a restart; finished jobs are removed after BLOCKY_JOBS_TTL seconds.",
+        "operationId": "submitJob",
+        "tags": ["Jobs"],
+        "parameters": [
+          {"$ref": "#/components/parameters/AssetPack"},
+          {"$ref": "#/components/parameters/CharacterFormat"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/JobRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "202": {
+            "description": "Job queued",
+            "headers": {
+              "Location": {"description": "URL of the job status", "schema": {"type": "string"}},
+              "X-Blocky-Asset-Pack": {"$ref": "#/components/headers/AssetPack"}
+            },
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/Job"}
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "403": {
+            "description": "Jobs or the render endpoint of the job's format are disabled",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ErrorResponse"}
+              }
+            }
+          },
+          "422": {
+            "$ref": "#/components/responses/ValidationFailed"
+          },
+          "503": {
+            "description": "BLOCKY_JOBS_MAX_QUEUED jobs are waiting; retry after Retry-After seconds",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ErrorResponse"}
+              }
+            }
+          }
+        }
+      }
+    },
+    "/jobs/{id}": {
+      "get": {
+        "summary": "Get a render job",
+        "description": "Returns the status and progress of a job. Progress counts rendered frames; PNG and GLB jobs have one.",
+        "operationId": "getJob",
+        "tags": ["Jobs"],
+        "parameters": [
+          {"$ref": "#/components/parameters/JobID"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Job status",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/Job"}
+              }
+            }
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          }
+        }
+      }
+    },
+    "/jobs/{id}/result": {
+      "get": {
+        "summary": "Download the output of a render job",
+        "description": "Returns the output of a finished job, as the render endpoint of its format would. Range requests are supported.",
+        "operationId": "getJobResult",
+        "tags": ["Jobs"],
+        "parameters": [
+          {"$ref": "#/components/parameters/JobID"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Job output",
+            "headers": {
+              "X-Blocky-Asset-Pack": {"$ref": "#/components/headers/AssetPack"}
+            },
+            "content": {
+              "model/gltf-binary": {"schema": {"type": "string", "format": "binary"}},
+              "image/png": {"schema": {"type": "string", "format": "binary"}},
+              "image/gif": {"schema": {"type": "string", "format": "binary"}},
+              "video/mp4": {"schema": {"type": "string", "format": "binary"}}
+            }
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "409": {
+            "description": "The job is queued, running or failed",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/Job"}
+              }
+            }
+          }
+        }
+      }
+    },
+    "/renders/{id}/events": {
+      "get": {
+        "summary": "Follow the progress of a render",
+        "description": "Streams the progress of the render sent with this X-Blocky-Progress-ID (or progressId) as Server-Sent Events. Open the stream before sending the render request; animations report each rendered frame, GIFs the start of quantization and encoding, and MP4s the encoding percentage reported by FFmpeg. The final event links the result, which stays available from GET /renders/{id}/result for 10 minutes while it is in the render cache. Streams for IDs no render uses end with an error after a minute.",
+        "operationId": "getRenderEvents",
+        "tags": ["Render"],
+        "parameters": [
+          {"$ref": "#/components/parameters/ProgressStreamID"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Server-Sent Events: \"progress\" whenever the render advances, then a final \"done\" carrying resultUrl or \"error\". Every event's data is a ProgressEvent.",
+            "content": {
+              "text/event-stream": {
+                "schema": {"type": "string"},
+                "example": "event: progress\ndata: {\"status\":\"running\",\"stage\":\"render\",\"done\":12,\"total\":60}\n\nevent: done\ndata: {\"status\":\"done\",\"done\":60,\"total\":60,\"resultUrl\":\"/renders/3f2b9c1e-8d4a-4e6b-9a7c-1d2e3f4a5b6c/result\"}\n\n"
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          }
+        }
+      }
+    },
+    "/renders/{id}/result": {
+      "get": {
+        "summary": "Download a followed render",
+        "description": "Returns the output of the render sent with this progress ID, as its render endpoint did.",
+        "operationId": "getRenderResult",
+        "tags": ["Render"],
+        "parameters": [
+          {"$ref": "#/components/parameters/ProgressStreamID"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Render output",
+            "headers": {
+              "X-Blocky-Asset-Pack": {"$ref": "#/components/headers/AssetPack"}
+            },
+            "content": {
+              "application/octet-stream": {"schema": {"type": "string", "format": "binary"}}
+            }
+          },
+          "404": {
+            "description": "Unknown progress ID, or the render is no longer cached",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ErrorResponse"}
+              }
+            }
+          },
+          "409": {
+            "description": "The render has not finished or failed",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/ProgressEvent"}
+              }
+            }
+          }
+        }
+      }
+    },
+    "/jobs/{id}/events": {
+      "get": {
+        "summary": "Follow the progress of a render job",
+        "description": "Streams the status and progress of a job as Server-Sent Events, ending with a \"done\" event carrying resultUrl or an \"error\" event.",
+        "operationId": "getJobEvents",
+        "tags": ["Jobs"],
+        "parameters": [
+          {"$ref": "#/components/parameters/JobID"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Server-Sent Events: \"progress\" whenever the job advances, then a final \"done\" carrying resultUrl or \"error\". Every event's data is a ProgressEvent.",
+            "content": {
+              "text/event-stream": {
+                "schema": {"type": "string"},
+                "example": "event: progress\ndata: {\"status\":\"running\",\"stage\":\"render\",\"done\":12,\"total\":60}\n\nevent: done\ndata: {\"status\":\"done\",\"done\":60,\"total\":60,\"resultUrl\":\"/jobs/6f1c0e2a9b8d4c7e8f3a2b1c0d9e8f7a/result\"}\n\n"
+              }
+            }
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "CharacterConfig": {
+        "type": "object",
+        "description": "Character appearance configuration. All fields are optional. Format: \"AccessoryId.Color.Variant\". Color is a name from the cosmetic's gradient set, a hex color such as \"#3A7BD5\", or gradient stops from dark to light such as \"#102030-#3A7BD5-#E0F0FF\"; hex colors tint greyscale textures only.",
+        "properties": {
+          "bodyCharacteristic": {"type": "string", "example": "Default.02", "description": "Body type and skin tone, a Skin gradient name or hex color"},
+          "underwear": {"type": "string", "example": "Underwear_Male", "description": "Base underwear"},
+          "face": {"type": "string", "example": "Face_A", "description": "Face shape"},
+          "ears": {"type": "string", "example": "Ears_A", "description": "Ear type"},
+          "mouth": {"type": "string", "example": "Mouth_A", "description": "Mouth shape"},
+          "haircut": {"type": "string", "example": "Scavenger_Hair.PitchBlack", "description": "Hair style and color"},
+          "facialHair": {"type": "string", "example": "Beard_A.Brown", "description": "Beard/mustache"},
+          "eyebrows": {"type": "string", "example": "Eyebrows_A.Black", "description": "Eyebrow style and color"},
+          "eyes": {"type": "string", "example": "Large_Eyes.Pink", "description": "Eye style and color"},
+          "pants": {"type": "string", "example": "Pants_A.Blue", "description": "Lower body clothing"},
+          "overpants": {"type": "string", "description": "Pants overlay (belt, etc.)"},
+          "undertop": {"type": "string", "example": "Shirt_A.White", "description": "Shirt/undershirt"},
+          "overtop": {"type": "string", "example": "Jacket_A.Red", "description": "Jacket/coat"},
+          "shoes": {"type": "string", "example": "Boots_A.Brown", "description": "Footwear"},
+          "headAccessory": {"type": "string", "description": "Hat/helmet"},
+          "faceAccessory": {"type": "string", "description": "Glasses/mask"},
+          "earAccessory": {"type": "string", "description": "Earrings"},
+          "skinFeature": {"type": "string", "description": "Tattoos/markings"},
+          "gloves": {"type": "string", "description": "Hand accessories"},
+          "cape": {"type": "string", "description": "Back cape"}
+        }
+      },
+      "PNGRequest": {
+        "type": "object",
+        "required": ["character"],
+        "properties": {
+          "character": {"oneOf": [{"$ref": "#/components/schemas/CharacterConfig"}, {"type": "string", "description": "Share code from /codes/encode"}]},
+          "strict": {"type": "boolean", "default": false, "description": "Return 422 instead of rendering when fallbacks are applied or parts are missing"},
+          "assetPack": {"type": "string", "description": "Asset pack to render with, default pack if omitted"},
+          "version": {"type": "string", "description": "Alias of assetPack"},
+          "rotation": {"type": "number", "default": 0, "description": "Rotation in degrees"},
+          "background": {"type": "string", "default": "transparent", "description": "\"transparent\" or hex color \"#RRGGBB\""},
+          "width": {"type": "integer", "default": 512, "minimum": 1, "maximum": 4096, "description": "Image width in pixels"},
+          "height": {"type": "integer", "default": 512, "minimum": 1, "maximum": 4096, "description": "Image height in pixels"}
+        }
+      },
+      "GIFRequest": {
+        "type": "object",
+        "required": ["character"],
+        "properties": {
+          "character": {"oneOf": [{"$ref": "#/components/schemas/CharacterConfig"}, {"type": "string", "description": "Share code from /codes/encode"}]},
+          "strict": {"type": "boolean", "default": false, "description": "Return 422 instead of rendering when fallbacks are applied or parts are missing"},
+          "assetPack": {"type": "string", "description": "Asset pack to render with, default pack if omitted"},
+          "version": {"type": "string", "description": "Alias of assetPack"},
+          "background": {"type": "string", "default": "#FFFFFF", "description": "Hex color (no transparency for GIF)"},
+          "frames": {"type": "integer", "default": 36, "minimum": 1, "maximum": 720, "description": "Number of frames (36 = 10° per frame)"},
+          "width": {"type": "integer", "default": 512, "minimum": 1, "maximum": 4096, "description": "Image width in pixels"},
+          "height": {"type": "integer", "default": 512, "minimum": 1, "maximum": 4096, "description": "Image height in pixels"},
+          "delay": {"type": "integer", "default": 5, "minimum": 1, "maximum": 65535, "description": "Centiseconds between frames"},
+          "dithering": {"type": "boolean", "default": true, "description": "Enable Floyd-Steinberg dithering (disable for faster rendering)"},
+          "autoZoom": {"type": "boolean", "default": true, "description": "Auto-zoom camera to fit character tightly in frame"}
+        }
+      },
+      "MP4Request": {
+        "type": "object",
+        "required": ["character"],
+        "properties": {
+          "character": {"oneOf": [{"$ref": "#/components/schemas/CharacterConfig"}, {"type": "string", "description": "Share code from /codes/encode"}]},
+          "strict": {"type": "boolean", "default": false, "description": "Return 422 instead of rendering when fallbacks are applied or parts are missing"},
+          "assetPack": {"type": "string", "description": "Asset pack to render with, default pack if omitted"},
+          "version": {"type": "string", "description": "Alias of assetPack"},
+          "background": {"type": "string", "default": "#FFFFFF", "description": "Hex color background"},
+          "frames": {"type": "integer", "default": 36, "minimum": 1, "maximum": 720, "description": "Number of frames (36 = 10° per frame)"},
+          "width": {"type": "integer", "default": 512, "minimum": 1, "maximum": 4096, "description": "Video width in pixels"},
+          "height": {"type": "integer", "default": 512, "minimum": 1, "maximum": 4096, "description": "Video height in pixels"},
+          "fps": {"type": "integer", "default": 12, "minimum": 1, "maximum": 120, "description": "Frames per second"},
+          "autoZoom": {"type": "boolean", "default": true, "description": "Auto-zoom camera to fit character tightly in frame"}
+        }
+      },
+      "STLRequest": {
+        "type": "object",
+        "required": ["character"],
+        "properties": {
+          "character": {"$ref": "#/components/schemas/CharacterConfig"},
+          "strict": {"type": "boolean", "default": false, "description": "Return 422 instead of rendering when fallbacks are applied or parts are missing"},
+          "assetPack": {"type": "string", "description": "Asset pack to render with, default pack if omitted"},
+          "version": {"type": "string", "description": "Alias of assetPack"},
+          "height": {"type": "number", "default": 100, "description": "Model height in millimetres"},
+          "basePlate": {"type": "boolean", "default": false, "description": "Add a rectangular base plate under the feet"},
+          "basePlateThickness": {"type": "number", "default": 2, "description": "Base plate thickness in millimetres"},
+          "basePlateMargin": {"type": "number", "default": 3, "description": "Base plate margin around the model in millimetres"}
+        }
+      },
+      "VOXRequest": {
+        "type": "object",
+        "required": ["character"],
+        "properties": {
+          "character": {"$ref": "#/components/schemas/CharacterConfig"},
+          "strict": {"type": "boolean", "default": false, "description": "Return 422 instead of rendering when fallbacks are applied or parts are missing"},
+          "assetPack": {"type": "string", "description": "Asset pack to render with, default pack if omitted"},
+          "version": {"type": "string", "description": "Alias of assetPack"},
+          "resolution": {"type": "integer", "default": 64, "minimum": 1, "maximum": 256, "description": "Number of voxels along the model's longest axis"}
+        }
+      },
+      "AtlasRequest": {
+        "type": "object",
+        "required": ["character"],
+        "properties": {
+          "character": {"$ref": "#/components/schemas/CharacterConfig"},
+          "strict": {"type": "boolean", "default": false, "description": "Return 422 instead of rendering when fallbacks are applied or parts are missing"},
+          "assetPack": {"type": "string", "description": "Asset pack to render with, default pack if omitted"},
+          "version": {"type": "string", "description": "Alias of assetPack"},
+          "format": {"type": "string", "enum": ["zip", "png", "json"], "default": "zip", "description": "zip: atlas.png + manifest.json, png: atlas only, json: manifest only"},
+          "wireframe": {"type": "boolean", "default": false, "description": "Draw the UV layout of every face on top of the atlas"},
+          "wireframeColor": {"type": "string", "default": "#FF00FF", "description": "Hex color of the UV wireframe"}
+        }
+      },
+      "AtlasManifest": {
+        "type": "object",
+        "properties": {
+          "width": {"type": "integer", "description": "Atlas width in pixels"},
+          "height": {"type": "integer", "description": "Atlas height in pixels"},
+          "warnings": {"type": "array", "items": {"$ref": "#/components/schemas/FieldIssue"}},
+          "entries": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "name": {"type": "string", "description": "Accessory ID, or \"_base\" for the player texture"},
+                "category": {"type": "string", "example": "haircut"},
+                "x": {"type": "integer"},
+                "y": {"type": "integer"},
+                "width": {"type": "integer"},
+                "height": {"type": "integer"},
+                "texture": {"type": "string", "description": "Source texture path"},
+                "gradientSet": {"type": "string", "description": "Gradient set used for tinting"},
+                "color": {"type": "string", "description": "Gradient colour applied"}
+              }
+            }
+          }
+        }
+      },
+      "CatalogResponse": {
+        "type": "object",
+        "properties": {
+          "categories": {
+            "type": "array",
+            "description": "Item count per category (only on /catalog)",
+            "items": {
+              "type": "object",
+              "properties": {
+                "name": {"type": "string"},
+                "count": {"type": "integer"}
+              }
+            }
+          },
+          "total": {"type": "integer", "description": "Number of items matching the filters"},
+          "offset": {"type": "integer"},
+          "limit": {"type": "integer"},
+          "items": {"type": "array", "items": {"$ref": "#/components/schemas/CatalogItem"}}
+        }
+      },
+      "CatalogItem": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string", "example": "Scavenger_Hair"},
+          "name": {"type": "string"},
+          "category": {"type": "string", "example": "haircut"},
+          "gradientSet": {"type": "string", "example": "Hair"},
+          "colors": {"type": "array", "items": {"type": "string"}, "description": "Allowed colors from the gradient set and pre-colored textures"},
+          "variants": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "name": {"type": "string"},
+                "model": {"type": "string"},
+                "greyscaleTexture": {"type": "string"},
+                "textures": {"type": "object", "additionalProperties": {"type": "string"}}
+              }
+            }
+          },
+          "headAccessoryType": {"type": "string", "example": "HalfCovering"},
+          "disableCharacterPartCategory": {"type": "string", "example": "Haircut"},
+          "hairType": {"type": "string"},
+          "disables": {"type": "array", "items": {"type": "string"}, "description": "Character fields hidden while this cosmetic is worn, from every Disable* registry field", "example": ["haircut"]},
+          "model": {"type": "string"},
+          "greyscaleTexture": {"type": "string"},
+          "textures": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Color to pre-colored texture path"}
+        }
+      },
+      "ValidationResult": {
+        "type": "object",
+        "properties": {
+          "valid": {"type": "boolean"},
+          "errors": {"type": "array", "items": {"$ref": "#/components/schemas/FieldIssue"}, "description": "Problems that prevent rendering"},
+          "warnings": {"type": "array", "items": {"$ref": "#/components/schemas/FieldIssue"}, "description": "Changes applied while rendering, e.g. a haircut hidden by a helmet"}
+        }
+      },
+      "FieldIssue": {
+        "type": "object",
+        "properties": {
+          "field": {"type": "string", "example": "haircut"},
+          "value": {"type": "string", "example": "Scavenger_Har.Black"},
+          "reason": {"type": "string", "enum": ["unknown_field", "invalid_format", "unknown_id", "ambiguous_id", "unknown_color", "unknown_variant", "category_disabled", "category_ignored", "fallback_applied", "model_missing", "texture_missing", "too_large", "not_imported", "not_printable"]},
+          "message": {"type": "string"},
+          "suggestions": {"type": "array", "items": {"type": "string"}, "example": ["Scavenger_Hair"]},
+          "rule": {"type": "string", "description": "Name of the rule that removed or replaced the field, see /rules", "example": "headAccessoryType/HalfCovering"}
+        }
+      },
+      "ResolveResponse": {
+        "type": "object",
+        "properties": {
+          "character": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Final field values after fallbacks", "example": {"haircut": "Short_Fallback.PitchBlack", "headAccessory": "Hat_A"}},
+          "accessories": {
+            "type": "array",
+            "description": "Accessories merged onto the base model, in merge order",
+            "items": {
+              "type": "object",
+              "properties": {
+                "category": {"type": "string", "example": "haircut"},
+                "id": {"type": "string"},
+                "color": {"type": "string"},
+                "variant": {"type": "string"},
+                "model": {"type": "string", "description": "Model path"},
+                "texture": {"type": "string", "description": "Texture path, empty if it could not be loaded"},
+                "gradientSet": {"type": "string"}
+              }
+            }
+          },
+          "textures": {
+            "type": "array",
+            "description": "Textures packed into the atlas, in packing order",
+            "items": {
+              "type": "object",
+              "properties": {
+                "name": {"type": "string", "description": "Accessory ID, or \"_base\" for the player texture"},
+                "category": {"type": "string"},
+                "texture": {"type": "string"},
+                "gradientSet": {"type": "string"},
+                "color": {"type": "string"}
+              }
+            }
+          },
+          "warnings": {"type": "array", "items": {"$ref": "#/components/schemas/FieldIssue"}}
+        }
+      },
+      "RandomRequest": {
+        "type": "object",
+        "properties": {
+          "assetPack": {"type": "string", "description": "Asset pack to pick cosmetics from, default pack if omitted"},
+          "version": {"type": "string", "description": "Alias of assetPack"},
+          "seed": {"type": "integer", "format": "int64", "description": "Same seed and options always give the same character. Random if omitted; the seed used is returned."},
+          "categories": {"type": "array", "items": {"type": "string"}, "description": "Fields to fill. By default the required fields are always filled and optional ones (headAccessory, cape, ...) sometimes.", "example": ["haircut", "eyes", "pants"]},
+          "probabilities": {"type": "object", "additionalProperties": {"type": "number", "minimum": 0, "maximum": 1}, "description": "Chance of each field being filled", "example": {"headAccessory": 0.5}},
+          "palettes": {"type": "object", "additionalProperties": {"type": "array", "items": {"type": "string"}}, "description": "Allowed colors per gradient set", "example": {"Hair": ["PitchBlack", "Brown"]}},
+          "locked": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Fields with a fixed value", "example": {"eyes": "Large_Eyes.Pink"}},
+          "render": {"type": "string", "enum": ["png", "glb"], "description": "Include a base64-encoded render of the character"},
+          "rotation": {"type": "number", "default": 0, "description": "PNG rotation in degrees"},
+          "background": {"type": "string", "default": "transparent", "description": "PNG background"},
+          "width": {"type": "integer", "default": 512, "minimum": 1, "maximum": 4096, "description": "PNG width"},
+          "height": {"type": "integer", "default": 512, "minimum": 1, "maximum": 4096, "description": "PNG height"}
+        }
+      },
+      "RandomResponse": {
+        "type": "object",
+        "properties": {
+          "seed": {"type": "integer", "format": "int64"},
+          "character": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Generated character, with the haircut fallback rules applied"},
+          "warnings": {"type": "array", "items": {"$ref": "#/components/schemas/FieldIssue"}},
+          "render": {"type": "string", "format": "byte", "description": "Base64-encoded render"},
+          "renderType": {"type": "string", "example": "image/png"}
+        }
+      },
+      "CacheStats": {
+        "type": "object",
+        "properties": {
+          "entries": {"type": "integer"},
+          "bytes": {"type": "integer", "description": "Estimated memory used"},
+          "maxBytes": {"type": "integer"},
+          "hits": {"type": "integer"},
+          "misses": {"type": "integer"},
+          "evictions": {"type": "integer"}
+        }
+      },
+      "ReloadStatus": {
+        "type": "object",
+        "properties": {
+          "assetVersion": {"type": "string", "description": "Fingerprint of the loaded assets"},
+          "loadedAt": {"type": "string", "format": "date-time"},
+          "reloads": {"type": "integer", "description": "Successful reloads since startup"},
+          "loadMs": {"type": "integer", "format": "int64", "description": "Duration of the last successful load in milliseconds"},
+          "lastAttempt": {"type": "string", "format": "date-time"},
+          "lastError": {"type": "string", "description": "Error of the last reload attempt, absent if it succeeded"}
+        }
+      },
+      "ReloadResponse": {
+        "type": "object",
+        "properties": {
+          "error": {"type": "string"},
+          "reload": {"$ref": "#/components/schemas/ReloadStatus"},
+          "packs": {"type": "object", "description": "Status per asset pack", "additionalProperties": {"$ref": "#/components/schemas/ReloadStatus"}}
+        }
+      },
+      "PacksResponse": {
+        "type": "object",
+        "properties": {
+          "default": {"type": "string", "description": "Pack used when a request names none"},
+          "packs": {"type": "array", "items": {"$ref": "#/components/schemas/PackInfo"}}
+        }
+      },
+      "PackInfo": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string", "example": "default"},
+          "default": {"type": "boolean"},
+          "cosmetics": {"type": "integer", "description": "Number of cosmetics in the pack"},
+          "categories": {
+            "type": "array",
+            "description": "Item count per category",
+            "items": {
+              "type": "object",
+              "properties": {
+                "name": {"type": "string"},
+                "count": {"type": "integer"}
+              }
+            }
+          },
+          "reload": {"$ref": "#/components/schemas/ReloadStatus"}
+        }
+      },
+      "CosmeticUpload": {
+        "type": "object",
+        "required": ["name", "category", "model"],
+        "properties": {
+          "name": {"type": "string", "pattern": "^[A-Za-z0-9_-]{1,64}$", "description": "ID without the \"custom:\" prefix", "example": "MyHat"},
+          "category": {"type": "string", "description": "Character field", "example": "headAccessory"},
+          "displayName": {"type": "string", "description": "Name shown in the catalog, defaults to name"},
+          "model": {"type": "string", "format": "binary", "description": ".blockymodel file"},
+          "texture": {"type": "string", "format": "binary", "description": "Pre-colored PNG texture"},
+          "greyscale": {"type": "string", "format": "binary", "description": "Greyscale PNG texture, tinted with gradientSet"},
+          "gradientSet": {"type": "string", "description": "Gradient set for greyscale, e.g. \"Hair\""}
+        }
+      },
+      "Rule": {
+        "type": "object",
+        "description": "Removes or replaces character slots while another slot holds a matching cosmetic",
+        "properties": {
+          "name": {"type": "string", "example": "headAccessory/Hood_A/DisableCharacterPartCategory"},
+          "source": {"type": "string", "enum": ["builtin", "registry", "file"]},
+          "field": {"type": "string", "description": "Slot that triggers the rule", "example": "headAccessory"},
+          "ids": {"type": "array", "items": {"type": "string"}, "description": "Cosmetic IDs or glob patterns; empty matches any", "example": ["Hood_*"]},
+          "type": {"type": "string", "description": "HeadAccessoryType or HairType the cosmetic must have", "example": "FullyCovering"},
+          "disable": {"type": "array", "items": {"type": "string"}, "description": "Slots removed", "example": ["haircut"]},
+          "fallback": {"type": "array", "items": {"type": "string"}, "description": "Slots replaced by the haircut fallback for their HairType"},
+          "replace": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Slot to replacement \"Id\" or \"Id.Color\"; the color is kept if omitted"}
+        }
+      },
+      "CodeResponse": {
+        "type": "object",
+        "properties": {
+          "code": {"type": "string", "description": "base64url share code with a checksum"},
+          "version": {"type": "integer", "description": "Code format version", "example": 1},
+          "character": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Character field -> \"Id.Color.Variant\" value"}
+        }
+      },
+      "BatchRequest": {
+        "type": "object",
+        "required": ["jobs"],
+        "properties": {
+          "defaults": {"type": "object", "additionalProperties": true, "description": "Job fields shared by every job; a job's own fields override them", "example": {"format": "png", "width": 256, "height": 256}},
+          "jobs": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "additionalProperties": true,
+              "description": "The request of the job's format endpoint (e.g. PNGRequest) plus id and format",
+              "properties": {
+                "id": {"type": "string", "pattern": "^[A-Za-z0-9_.-]{1,64}$", "description": "Entry name, default the job index"},
+                "format": {"type": "string", "enum": ["glb", "png", "gif", "mp4"]},
+                "character": {"oneOf": [{"$ref": "#/components/schemas/CharacterConfig"}, {"type": "string", "description": "Share code from /codes/encode"}]}
+              }
+            },
+            "example": [{"id": "player1", "character": {"haircut": "Scavenger_Hair.PitchBlack"}}, {"id": "player1-spin", "format": "gif", "character": {"haircut": "Scavenger_Hair.PitchBlack"}}]
+          },
+          "output": {"type": "string", "enum": ["zip", "multipart"], "default": "zip"},
+          "assetPack": {"type": "string", "description": "Asset pack to render with, default pack if omitted"},
+          "version": {"type": "string", "description": "Alias of assetPack"}
+        }
+      },
+      "BatchManifest": {
+        "type": "object",
+        "properties": {
+          "jobs": {"type": "array", "items": {"$ref": "#/components/schemas/BatchJobResult"}},
+          "merges": {"type": "integer", "description": "Distinct characters merged"}
+        }
+      },
+      "BatchJobResult": {
+        "type": "object",
+        "properties": {
+          "index": {"type": "integer"},
+          "id": {"type": "string"},
+          "format": {"type": "string"},
+          "status": {"type": "string", "enum": ["ok", "error"]},
+          "file": {"type": "string", "description": "Entry name, <id>.<format>"},
+          "bytes": {"type": "integer"},
+          "cache": {"type": "string", "enum": ["HIT", "MISS"]},
+          "warnings": {"type": "array", "items": {"$ref": "#/components/schemas/FieldIssue"}},
+          "error": {"type": "string"},
+          "fields": {"type": "array", "items": {"$ref": "#/components/schemas/FieldIssue"}}
+        }
+      },
+      "JobRequest": {
+        "type": "object",
+        "required": ["format", "character"],
+        "additionalProperties": true,
+        "description": "The request of the job's format endpoint (e.g. GIFRequest) plus format and webhook",
+        "properties": {
+          "format": {"type": "string", "enum": ["glb", "png", "gif", "mp4"]},
+          "character": {"oneOf": [{"$ref": "#/components/schemas/CharacterConfig"}, {"type": "string", "description": "Share code from /codes/encode"}]},
+          "webhook": {"type": "string", "format": "uri", "description": "http or https URL of a public host, or of a host in BLOCKY_WEBHOOK_ALLOWED_HOSTS, the job status is POSTed to when the job finishes; requires BLOCKY_WEBHOOK_SECRET"},
+          "assetPack": {"type": "string", "description": "Asset pack to render with, default pack if omitted"},
+          "version": {"type": "string", "description": "Alias of assetPack"}
+        },
+        "example": {"format": "mp4", "frames": 120, "character": {"haircut": "Scavenger_Hair.PitchBlack"}, "webhook": "https://example.com/hooks/blocky"}
+      },
+      "Job": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string"},
+          "status": {"type": "string", "enum": ["queued", "running", "done", "failed"]},
+          "format": {"type": "string"},
+          "assetPack": {"type": "string"},
+          "progress": {
+            "type": "object",
+            "properties": {
+              "stage": {"type": "string", "enum": ["render", "quantize", "encode"]},
+              "done": {"type": "integer", "description": "Frames rendered"},
+              "total": {"type": "integer"},
+              "percent": {"type": "integer", "description": "Of the encode stage, reported by FFmpeg for MP4"}
+            }
+          },
+          "resultUrl": {"type": "string", "description": "Set once done"},
+          "bytes": {"type": "integer"},
+          "warnings": {"type": "array", "items": {"$ref": "#/components/schemas/FieldIssue"}},
+          "error": {"type": "string"},
+          "fields": {"type": "array", "items": {"$ref": "#/components/schemas/FieldIssue"}, "description": "Per-field problems for invalid characters"},
+          "webhook": {"type": "string"},
+          "createdAt": {"type": "string", "format": "date-time"},
+          "startedAt": {"type": "string", "format": "date-time"},
+          "finishedAt": {"type": "string", "format": "date-time"}
+        }
+      },
+      "ProgressEvent": {
+        "type": "object",
+        "properties": {
+          "status": {"type": "string", "enum": ["queued", "running", "done", "failed"]},
+          "stage": {"type": "string", "enum": ["render", "quantize", "encode"], "description": "While running: frames are rendered, GIF frames are reduced to a palette, or the animation is encoded"},
+          "done": {"type": "integer", "description": "Frames rendered"},
+          "total": {"type": "integer", "description": "Frames, 1 for single images"},
+          "percent": {"type": "integer", "description": "Of the encode stage, reported by FFmpeg for MP4"},
+          "resultUrl": {"type": "string", "description": "Where to download the result, once done"},
+          "error": {"type": "string"}
+        }
+      },
+      "ItemRequest": {
+        "type": "object",
+        "required": ["item"],
+        "properties": {
+          "category": {"type": "string", "description": "Character field of the item, e.g. \"overtop\" or \"haircut\". Looked up from the item ID when omitted; required only for IDs found in several categories, which fail with ambiguous_id"},
+          "item": {"type": "string", "description": "Item as \"Id\", \"Id.Color\" or \"Id.Color.Variant\", e.g. \"Jacket_A.Red\""},
+          "strict": {"type": "boolean", "default": false, "description": "Return 422 instead of rendering when the texture is missing"},
+          "assetPack": {"type": "string", "description": "Asset pack to render with, default pack if omitted"},
+          "version": {"type": "string", "description": "Alias of assetPack"},
+          "mannequin": {"type": "boolean", "default": false, "description": "Show the item on a faded player body"},
+          "skinTone": {"type": "string", "description": "Skin tone for skin-colored items without a color, a Skin gradient name or hex color"},
+          "rotation": {"type": "number", "default": 0, "description": "Rotation in degrees"},
+          "background": {"type": "string", "default": "transparent", "description": "\"transparent\" or hex color \"#RRGGBB\""},
+          "width": {"type": "integer", "default": 512, "minimum": 1, "maximum": 4096, "description": "Image width in pixels"},
+          "height": {"type": "integer", "default": 512, "minimum": 1, "maximum": 4096, "description": "Image height in pixels"}
+        }
+      },
+      "ErrorResponse": {
+        "type": "object",
+        "properties": {
+          "error": {"type": "string", "description": "Error message"},
+          "fields": {"type": "array", "items": {"$ref": "#/components/schemas/FieldIssue"}, "description": "Per-field problems when the character is invalid"}
+        }
+      }
+    },
+    "securitySchemes": {
+      "adminToken": {
+        "type": "http",
+        "scheme": "bearer",
+        "description": "Value of BLOCKY_ADMIN_TOKEN"
+      }
+    },
+    "parameters": {
+      "ProgressID": {
+        "name": "X-Blocky-Progress-ID",
+        "in": "header",
+        "description": "ID picked by the client, 16-64 letters, digits, \"_\" or \"-\", to follow the render with GET /renders/{id}/events. Anyone knowing it can follow the render and fetch its result, so pick an unguessable one such as a UUID.",
+        "schema": {"type": "string", "pattern": "^[A-Za-z0-9_-]{16,64}$"}
+      },
+      "ProgressIDQuery": {
+        "name": "progressId",
+        "in": "query",
+        "description": "Same as the X-Blocky-Progress-ID header",
+        "schema": {"type": "string", "pattern": "^[A-Za-z0-9_-]{16,64}$"}
+      },
+      "ProgressStreamID": {
+        "name": "id",
+        "in": "path",
+        "required": true,
+        "description": "Progress ID sent with the render request",
+        "schema": {"type": "string", "pattern": "^[A-Za-z0-9_-]{16,64}$"}
+      },
+      "JobID": {
+        "name": "id",
+        "in": "path",
+        "required": true,
+        "description": "Job ID returned by POST /jobs",
+        "schema": {"type": "string"}
+      },
+      "Character": {
+        "name": "character",
+        "in": "query",
+        "required": true,
+        "schema": {"type": "string"},
+        "description": "Share code from /codes/encode, base64url-encoded character JSON, or URL-encoded character JSON"
+      },
+      "CharacterFormat": {
+        "name": "format",
+        "in": "query",
+        "schema": {"type": "string", "enum": ["auto", "blocky", "hytale"], "default": "auto"},
+        "description": "Format of the character: blocky is CharacterConfig, hytale is a player skin saved by the game or launcher. hytale is experimental: its layout is inferred from the registry files and has not been checked against skins saved by the game, so check the converted character with /resolve (PascalCase or registry file names, optionally wrapped in PlayerSkin, cosmetics as \"Id.Color.Variant\" strings or {\"Id\", \"Color\", \"Variant\"} objects, a separate SkinTone). auto detects it only when wrapped in PlayerSkin. Fields that cannot be mapped are reported as not_imported warnings."
+      },
+      "Strict": {
+        "name": "strict",
+        "in": "query",
+        "schema": {"type": "boolean", "default": false},
+        "description": "Return 422 instead of rendering when fallbacks are applied or parts are missing"
+      },
+      "AssetPack": {
+        "name": "assetPack",
+        "in": "query",
+        "schema": {"type": "string"},
+        "description": "Asset pack to use, default pack if omitted. \"version\" is accepted as an alias. Unknown packs return 400."
+      }
+    },
+    "headers": {
+      "AssetPack": {
+        "description": "Name of the asset pack that served the request",
+        "schema": {"type": "string", "example": "default"}
+      },
+      "RenderETag": {
+        "description": "Hash of the normalized request and asset version. Send it back in If-None-Match to get 304.",
+        "schema": {"type": "string"}
+      },
+      "RenderCache": {
+        "description": "HIT if the render was served from the render cache, MISS otherwise",
+        "schema": {"type": "string", "enum": ["HIT", "MISS"]}
+      },
+      "Warnings": {
+        "description": "JSON array of FieldIssue objects describing fallbacks and skipped parts applied while rendering",
+        "schema": {"type": "string", "example": "[{\"field\":\"haircut\",\"reason\":\"fallback_applied\"}]"}
+      }
+    },
+    "responses": {
+      "URITooLong": {
+        "description": "URL longer than BLOCKY_MAX_URL_LENGTH; use POST instead",
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/ErrorResponse"}
+          }
+        }
+      },
+      "NotModified": {
+        "description": "The render matching If-None-Match is unchanged",
+        "headers": {
+          "ETag": {"$ref": "#/components/headers/RenderETag"}
+        }
+      },
+      "NotFound": {
+        "description": "Not found",
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/ErrorResponse"}
+          }
+        }
+      },
+      "BadRequest": {
+        "description": "Bad request",
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/ErrorResponse"}
+          }
+        }
+      },
+      "ValidationFailed": {
+        "description": "Character has invalid fields",
         "content": {
           "application/json": {
             "schema": {"$ref": "#/components/schemas/ErrorResponse"}
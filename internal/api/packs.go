@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"blockyserver/internal/service"
+)
+
+// packHeader names the asset pack a response was served from
+const packHeader = "X-Blocky-Asset-Pack"
+
+// servedPack is the asset pack a request is served from
+type servedPack struct {
+	name    string
+	svc     *service.MergeService
+	release func() // lets a reload close svc, call once the request is done
+}
+
+// name returns the selected pack, preferring assetPack over version
+func (s PackSelector) name() string {
+	if s.AssetPack != "" {
+		return s.AssetPack
+	}
+	return s.Version
+}
+
+// pack selects the asset pack of a request: the selector fields if set,
+// otherwise the assetPack or version query parameter, otherwise the default
+// pack. Requests keep the MergeService they start with across reloads and
+// must release it when they are done.
+func (h *Handlers) pack(w http.ResponseWriter, r *http.Request, sel PackSelector) (*servedPack, bool) {
+	name := sel.name()
+	if name == "" {
+		query := r.URL.Query()
+		name = PackSelector{AssetPack: query.Get("assetPack"), Version: query.Get("version")}.name()
+	}
+	if name == "" {
+		name = h.packs.Default()
+	}
+
+	reloader, ok := h.packs.Get(name)
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown asset pack %q, available: %s", name, strings.Join(h.packs.Names(), ", ")))
+		return nil, false
+	}
+
+	w.Header().Set(packHeader, name)
+	svc, release := reloader.Acquire()
+	return &servedPack{name: name, svc: svc, release: release}, true
+}
+
+// splitPack removes the assetPack and version fields from a character body,
+// for endpoints whose body is the character itself. Bodies that are not a
+// JSON object are returned unchanged and rejected later.
+func splitPack(body []byte) (PackSelector, []byte) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return PackSelector{}, body
+	}
+
+	var sel PackSelector
+	found := false
+	for key, dst := range map[string]*string{"assetPack": &sel.AssetPack, "version": &sel.Version} {
+		if raw, ok := fields[key]; ok {
+			json.Unmarshal(raw, dst)
+			delete(fields, key)
+			found = true
+		}
+	}
+	if !found {
+		return sel, body
+	}
+
+	stripped, err := json.Marshal(fields)
+	if err != nil {
+		return sel, body
+	}
+	return sel, stripped
+}
+
+// packStatus returns the reload status of every pack by name
+func (h *Handlers) packStatus() map[string]service.ReloadStatus {
+	status := make(map[string]service.ReloadStatus)
+	for _, name := range h.packs.Names() {
+		pack, _ := h.packs.Get(name)
+		status[name] = pack.Status()
+	}
+	return status
+}
+
+// HandlePacks handles GET /packs
+func (h *Handlers) HandlePacks(w http.ResponseWriter, r *http.Request) {
+	resp := PacksResponse{Default: h.packs.Default(), Packs: []PackInfo{}}
+
+	for _, name := range h.packs.Names() {
+		pack, _ := h.packs.Get(name)
+		resp.Packs = append(resp.Packs, h.packInfo(name, pack))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// packInfo summarises the catalog of the current version of a pack
+func (h *Handlers) packInfo(name string, pack *service.Reloader) PackInfo {
+	svc, release := pack.Acquire()
+	defer release()
+	catalog := svc.Catalog()
+
+	info := PackInfo{
+		Name:       name,
+		Default:    name == h.packs.Default(),
+		Categories: []CatalogCategory{},
+		Reload:     pack.Status(),
+	}
+	for _, category := range catalog.Categories() {
+		items, _ := catalog.Items(category)
+		info.Categories = append(info.Categories, CatalogCategory{Name: category, Count: len(items)})
+		info.Cosmetics += len(items)
+	}
+	return info
+}
@@ -0,0 +1,284 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"blockyserver/internal/jobs"
+	"blockyserver/internal/render"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// progressHeader names the progress ID a client picks to follow a render
+// with GET /renders/{id}/events; GET renders take ?progressId= instead
+const progressHeader = "X-Blocky-Progress-ID"
+
+// progressIDPattern limits progress IDs. Anyone knowing an ID can follow
+// the render and fetch its result, so clients should pick unguessable ones
+// such as UUIDs.
+var progressIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{16,64}$`)
+
+const (
+	progressKeep      = 10 * time.Minute // how long finished renders can be followed and fetched
+	progressWait      = time.Minute      // how long a stream waits for its render to start
+	progressHeartbeat = 15 * time.Second // comment lines keeping idle streams open through proxies
+)
+
+// progressHub tracks the progress of renders followed by a progress ID
+type progressHub struct {
+	mu      sync.Mutex
+	renders map[string]*trackedRender
+}
+
+// trackedRender is a render followed by a progress ID
+type trackedRender struct {
+	event    ProgressEvent
+	format   string // render cache entry of the result, once done
+	key      string
+	pack     string
+	created  time.Time
+	updated  time.Time
+	watchers map[chan struct{}]struct{}
+}
+
+func newProgressHub() *progressHub {
+	return &progressHub{renders: make(map[string]*trackedRender)}
+}
+
+// progressID returns the progress ID of a render request, empty if none
+func progressID(r *http.Request) string {
+	if id := r.Header.Get(progressHeader); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("progressId")
+}
+
+// update changes a tracked render, starting to track it if needed, and
+// wakes its watchers
+func (p *progressHub) update(id string, fn func(t *trackedRender)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t := p.track(id)
+	fn(t)
+	t.updated = time.Now()
+	for ch := range t.watchers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// track returns a tracked render, creating it if needed, and forgets
+// renders finished or abandoned long enough ago; p.mu must be held
+func (p *progressHub) track(id string) *trackedRender {
+	now := time.Now()
+	for other, t := range p.renders {
+		if len(t.watchers) == 0 && now.Sub(t.updated) > progressKeep {
+			delete(p.renders, other)
+		}
+	}
+
+	t, ok := p.renders[id]
+	if !ok {
+		t = &trackedRender{
+			event:    ProgressEvent{Status: jobs.StatusQueued},
+			created:  now,
+			updated:  now,
+			watchers: make(map[chan struct{}]struct{}),
+		}
+		p.renders[id] = t
+	}
+	return t
+}
+
+// get returns the state of a tracked render. Renders that have not started
+// within progressWait of being followed fail, as no request uses their ID.
+func (p *progressHub) get(id string) (trackedRender, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t, ok := p.renders[id]
+	if !ok {
+		return trackedRender{}, false
+	}
+	state := *t
+	if state.event.Status == jobs.StatusQueued && time.Since(state.created) > progressWait {
+		state.event = ProgressEvent{Status: jobs.StatusFailed, Error: "no render started with this progress ID"}
+	}
+	return state, true
+}
+
+// watch follows a render that may not have started yet
+func (p *progressHub) watch(id string) (<-chan struct{}, func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ch := make(chan struct{}, 1)
+	p.track(id).watchers[ch] = struct{}{}
+	stop := func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if t, ok := p.renders[id]; ok {
+			delete(t.watchers, ch)
+			t.updated = time.Now()
+		}
+	}
+	return ch, stop
+}
+
+// start marks a render as running
+func (p *progressHub) start(id string) {
+	p.update(id, func(t *trackedRender) {
+		t.event = ProgressEvent{Status: jobs.StatusRunning, Stage: render.StageRender}
+	})
+}
+
+// progress returns the Progress of a render, nil if it is not followed
+func (p *progressHub) progress(id string) render.Progress {
+	if id == "" {
+		return nil
+	}
+	return func(e render.ProgressEvent) error {
+		p.update(id, func(t *trackedRender) {
+			t.event = ProgressEvent{Status: jobs.StatusRunning, Stage: e.Stage, Done: e.Done, Total: e.Total, Percent: e.Percent}
+		})
+		return nil
+	}
+}
+
+// finish records the outcome of a render. Renders with a cache key can be
+// fetched from GET /renders/{id}/result for as long as they stay cached.
+func (p *progressHub) finish(id, pack, format, key string, ok bool) {
+	p.update(id, func(t *trackedRender) {
+		if !ok {
+			t.event = ProgressEvent{Status: jobs.StatusFailed, Error: "render failed, see the render response"}
+			return
+		}
+		t.event.Status = jobs.StatusDone
+		if t.event.Total == 0 {
+			// A single image
+			t.event.Total = 1
+		}
+		t.event.Done, t.event.Percent = t.event.Total, 0
+		t.event.Stage = ""
+		t.pack, t.format, t.key = pack, format, key
+		if key != "" {
+			t.event.ResultURL = "/renders/" + id + "/result"
+		}
+	})
+}
+
+// HandleRenderEvents handles GET /renders/{id}/events, streaming the
+// progress of the render started with that progress ID as Server-Sent
+// Events. The stream may be opened before the render request is sent.
+func (h *Handlers) HandleRenderEvents(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !progressIDPattern.MatchString(id) {
+		writeError(w, http.StatusBadRequest, "progress ID must be 16-64 letters, digits, \"_\" or \"-\"")
+		return
+	}
+
+	changed, stop := h.progress.watch(id)
+	defer stop()
+	streamProgress(w, r, changed, func() ProgressEvent {
+		t, _ := h.progress.get(id)
+		return t.event
+	})
+}
+
+// HandleRenderResult handles GET /renders/{id}/result
+func (h *Handlers) HandleRenderResult(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.progress.get(chi.URLParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown progress ID")
+		return
+	}
+	if t.event.Status != jobs.StatusDone {
+		writeJSON(w, http.StatusConflict, t.event)
+		return
+	}
+	entry, hit := h.renders.Get(t.format, t.key)
+	if t.key == "" || !hit {
+		writeError(w, http.StatusNotFound, "the render is no longer cached")
+		return
+	}
+	w.Header().Set(packHeader, t.pack)
+	writeRenderEntry(w, entry)
+}
+
+// HandleJobEvents handles GET /jobs/{id}/events, streaming the progress of
+// a job as Server-Sent Events
+func (h *Handlers) HandleJobEvents(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	changed, stop, err := h.jobs.Watch(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	defer stop()
+
+	streamProgress(w, r, changed, func() ProgressEvent {
+		job, err := h.jobs.Get(id)
+		if err != nil {
+			return ProgressEvent{Status: jobs.StatusFailed, Error: err.Error()}
+		}
+		return ProgressEvent{
+			Status:    job.Status,
+			Stage:     job.Progress.Stage,
+			Done:      job.Progress.Done,
+			Total:     job.Progress.Total,
+			Percent:   job.Progress.Percent,
+			ResultURL: job.ResultURL,
+			Error:     job.Error,
+		}
+	})
+}
+
+// streamProgress writes a "progress" event whenever current changes, until
+// it reports a finished render with a final "done" or "error" event
+func streamProgress(w http.ResponseWriter, r *http.Request, changed <-chan struct{}, current func() ProgressEvent) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx would buffer the stream
+	w.WriteHeader(http.StatusOK)
+
+	heartbeat := time.NewTicker(progressHeartbeat)
+	defer heartbeat.Stop()
+
+	var last ProgressEvent
+	for sent := false; ; {
+		event := current()
+		if !sent || event != last {
+			name := "progress"
+			switch event.Status {
+			case jobs.StatusDone:
+				name = "done"
+			case jobs.StatusFailed:
+				name = "error"
+			}
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+			flush(w)
+			if name != "progress" {
+				return
+			}
+			last, sent = event, true
+		}
+
+		select {
+		case <-changed:
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flush(w)
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
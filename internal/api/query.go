@@ -0,0 +1,173 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// readRenderRequest decodes a render request from the JSON body, or from the
+// query string of a GET request so renders can be used as image URLs.
+// It writes an error response and returns false if the request is invalid.
+func (h *Handlers) readRenderRequest(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	if r.Method == http.MethodGet {
+		if len(r.URL.RequestURI()) > h.endpoints.MaxURLLength {
+			writeError(w, http.StatusRequestURITooLong, fmt.Sprintf("URL is longer than %d bytes, use POST instead", h.endpoints.MaxURLLength))
+			return false
+		}
+		query := r.URL.Query()
+		query.Del("format")     // character format, read by characterFormat
+		query.Del("progressId") // read by progressID
+		if err := queryRequest(query, req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return false
+		}
+		return true
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return false
+	}
+	defer r.Body.Close()
+
+	if err := json.Unmarshal(body, req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// queryRequest fills the fields of a request struct from query parameters
+// named after their JSON fields. The character parameter is a share code,
+// base64url-encoded JSON or plain JSON.
+func queryRequest(query url.Values, req interface{}) error {
+	fields := make(map[string]reflect.Value)
+	queryFields(reflect.ValueOf(req).Elem(), fields)
+
+	for _, name := range sortedQueryKeys(query) {
+		field, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("unknown query parameter %q", name)
+		}
+		if len(query[name]) > 1 {
+			return fmt.Errorf("query parameter %q is given more than once", name)
+		}
+		value := query[name][0]
+
+		if _, ok := field.Interface().(json.RawMessage); ok {
+			field.Set(reflect.ValueOf(queryCharacter(value)))
+			continue
+		}
+
+		target := field
+		if field.Kind() == reflect.Ptr {
+			target = reflect.New(field.Type().Elem()).Elem()
+		}
+		switch target.Kind() {
+		case reflect.String:
+			target.SetString(value)
+		case reflect.Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%s must be an integer", name)
+			}
+			target.SetInt(int64(n))
+		case reflect.Float64:
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("%s must be a number", name)
+			}
+			target.SetFloat(f)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%s must be true or false", name)
+			}
+			target.SetBool(b)
+		default:
+			return fmt.Errorf("%s cannot be set from the query string", name)
+		}
+		if field.Kind() == reflect.Ptr {
+			field.Set(target.Addr())
+		}
+	}
+	return nil
+}
+
+// queryFields indexes the settable fields of a struct by JSON name,
+// including those of embedded structs such as PackSelector
+func queryFields(v reflect.Value, fields map[string]reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			queryFields(v.Field(i), fields)
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = v.Field(i)
+		}
+	}
+}
+
+// queryCharacter turns the character query parameter into JSON. Values that
+// are neither JSON nor base64url-encoded JSON are passed on as a JSON string,
+// which expandCode decodes as a share code.
+func queryCharacter(value string) json.RawMessage {
+	if strings.HasPrefix(strings.TrimSpace(value), "{") {
+		return json.RawMessage(value)
+	}
+	if data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "=")); err == nil && json.Valid(data) && strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		return json.RawMessage(data)
+	}
+	quoted, _ := json.Marshal(value)
+	return quoted
+}
+
+// expandCode replaces a share code given as the character with the
+// character it stands for. Characters given as JSON objects are returned
+// unchanged. It writes an error response and returns false if the code does
+// not decode.
+func expandCode(w http.ResponseWriter, pack *servedPack, character json.RawMessage) (json.RawMessage, bool) {
+	expanded, err := decodeShareCode(pack, character)
+	if err != nil {
+		writeMergeError(w, err)
+		return nil, false
+	}
+	return expanded, true
+}
+
+// decodeShareCode is expandCode returning the error instead of writing it
+func decodeShareCode(pack *servedPack, character json.RawMessage) (json.RawMessage, error) {
+	var code string
+	if err := json.Unmarshal(character, &code); err != nil {
+		return character, nil
+	}
+
+	fields, err := pack.svc.DecodeCode(code)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// sortedQueryKeys returns the query parameter names in order, so the first
+// invalid parameter is always the one reported
+func sortedQueryKeys(query url.Values) []string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"blockyserver/internal/cache"
+	"blockyserver/internal/service"
+)
+
+// renderFormats are the render endpoints whose output is cached
+var renderFormats = []string{"glb", "png", "gif", "mp4", "obj", "stl", "vox", "atlas", "blockymodel", "item"}
+
+// renderCacheHeader reports whether a render was served from the cache
+const renderCacheHeader = "X-Blocky-Cache"
+
+// serveRender serves a render from the result cache, calling render on a miss.
+// render writes its own error response and returns nil if it fails.
+// Renders sent with a progress ID can be followed with GET /renders/{id}/events.
+func (h *Handlers) serveRender(w http.ResponseWriter, r *http.Request, pack *servedPack, format string, character json.RawMessage, options interface{}, render func() *cache.RenderEntry) {
+	id := progressID(r)
+	if id != "" && !progressIDPattern.MatchString(id) {
+		writeError(w, http.StatusBadRequest, "progress ID must be 16-64 letters, digits, \"_\" or \"-\"")
+		return
+	}
+
+	key, ok := renderKey(pack, format, character, characterFormat(r), options)
+	if !ok {
+		// Malformed characters are reported by render
+		if id != "" {
+			h.progress.start(id)
+		}
+		entry := render()
+		if id != "" {
+			h.progress.finish(id, pack.name, format, "", entry != nil)
+		}
+		if entry != nil {
+			writeRenderEntry(w, entry)
+		}
+		return
+	}
+
+	etag := `"` + key + `"`
+	setCacheHeaders := func() {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", h.cacheMaxAge))
+	}
+
+	// The ETag is derived from the request and asset version, so a match
+	// means the client already has this exact render
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		setCacheHeaders()
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if entry, hit := h.renders.Get(format, key); hit {
+		if id != "" {
+			h.progress.finish(id, pack.name, format, key, true)
+		}
+		setCacheHeaders()
+		w.Header().Set(renderCacheHeader, "HIT")
+		writeRenderEntry(w, entry)
+		return
+	}
+
+	if id != "" {
+		h.progress.start(id)
+	}
+	entry := render()
+	if entry != nil {
+		h.renders.Add(format, key, entry)
+	}
+	if id != "" {
+		h.progress.finish(id, pack.name, format, key, entry != nil)
+	}
+	if entry == nil {
+		return
+	}
+
+	setCacheHeaders()
+	w.Header().Set(renderCacheHeader, "MISS")
+	writeRenderEntry(w, entry)
+}
+
+// renderKey hashes the format, asset pack and version, canonical character
+// and its format, and render options into a cache key. It fails if the
+// character is not valid JSON.
+func renderKey(pack *servedPack, format string, character json.RawMessage, charFormat string, options interface{}) (string, bool) {
+	canonical, err := service.CanonicalCharacter(character)
+	if err != nil {
+		return "", false
+	}
+	opts, err := json.Marshal(options)
+	if err != nil {
+		return "", false
+	}
+
+	hash := sha256.New()
+	for _, part := range [][]byte{[]byte(format), []byte(pack.name), []byte(pack.svc.AssetVersion()), canonical, []byte(charFormat), opts} {
+		hash.Write(part)
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil)), true
+}
+
+// writeRenderEntry writes a render with its content type, filename and warnings
+func writeRenderEntry(w http.ResponseWriter, entry *cache.RenderEntry) {
+	w.Header().Set(warningsHeader, entry.Warnings)
+	w.Header().Set("Content-Type", entry.ContentType)
+	if entry.Filename != "" {
+		w.Header().Set("Content-Disposition", "attachment; filename="+entry.Filename)
+	}
+	w.Write(entry.Data)
+}
+
+// skippedShapes returns a warning for each shape left out of an STL print
+func skippedShapes(names []string) []service.FieldIssue {
+	var issues []service.FieldIssue
+	for _, name := range names {
+		issues = append(issues, service.FieldIssue{
+			Field:   "model",
+			Value:   name,
+			Reason:  service.ReasonNotPrintable,
+			Message: "shape " + name + " is not a closed solid and was left out of the print",
+		})
+	}
+	return issues
+}
+
+// encodeWarnings encodes merge warnings for the X-Blocky-Warnings header
+func encodeWarnings(warnings []service.FieldIssue) string {
+	if warnings == nil {
+		warnings = []service.FieldIssue{}
+	}
+	data, err := json.Marshal(warnings)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
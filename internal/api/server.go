@@ -1,10 +1,13 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
+	"blockyserver/internal/cache"
 	"blockyserver/internal/config"
+	"blockyserver/internal/jobs"
 	"blockyserver/internal/service"
 
 	"github.com/go-chi/chi/v5"
@@ -12,29 +15,101 @@ import (
 )
 
 // NewServer creates a new HTTP server with all routes configured
-func NewServer(svc *service.MergeService) http.Handler {
+func NewServer(packs *service.Packs) (http.Handler, error) {
 	r := chi.NewRouter()
 
 	// Middleware
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
 
 	// Load endpoint config
 	cfg := config.LoadEndpointConfig()
 	guards := NewEndpointGuards(cfg)
 
+	// Create render result cache
+	cacheCfg := config.LoadRenderCacheConfig(renderFormats)
+	renders, err := cache.NewRenderCache(cache.RenderCacheOptions{
+		MaxBytes:     cacheCfg.MaxBytes,
+		Dir:          cacheCfg.Dir,
+		DiskMaxBytes: cacheCfg.DiskMaxBytes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Open the job queue; jobs are rendered once the handlers exist
+	var queue *jobs.Queue
+	if cfg.JobsEnabled {
+		jobsCfg := config.LoadJobsConfig()
+		queue, err = jobs.Open(jobs.Options{
+			Dir:           jobsCfg.Dir,
+			Workers:       jobsCfg.Workers,
+			MaxQueued:     jobsCfg.MaxQueued,
+			TTL:           jobsCfg.TTL,
+			WebhookSecret: jobsCfg.WebhookSecret,
+			WebhookHosts:  jobsCfg.WebhookHosts,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("opening job queue: %w", err)
+		}
+	}
+
 	// Create handlers
-	h := NewHandlers(svc)
+	batchCfg := config.LoadBatchConfig()
+	h := NewHandlers(packs, renders, cacheCfg.MaxAge, cfg, batchCfg, queue)
+	if queue != nil {
+		queue.Start(h.runJob)
+	}
+
+	// Batches stream their results for longer than single requests may take,
+	// limited by BLOCKY_BATCH_TIMEOUT instead
+	r.With(guards["batch"]).Post("/render/batch", h.HandleBatch)
+
+	// Progress streams stay open until the render finishes
+	r.Get("/renders/{id}/events", h.HandleRenderEvents)
+	r.With(guards["jobs"]).Get("/jobs/{id}/events", h.HandleJobEvents)
 
 	// Routes
-	r.Get("/health", h.HandleHealth)
-	r.Get("/openapi.json", h.HandleOpenAPISpec)
-	r.Get("/docs", h.HandleSwaggerUI)
-	r.With(guards["glb"]).Post("/render/glb", h.HandleGLB)
-	r.With(guards["png"]).Post("/render/png", h.HandlePNG)
-	r.With(guards["gif"]).Post("/render/gif", h.HandleGIF)
-	r.With(guards["mp4"]).Post("/render/mp4", h.HandleMP4)
-
-	return r
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(60 * time.Second))
+
+		r.Get("/health", h.HandleHealth)
+		r.Get("/ready", h.HandleReady)
+		r.Get("/openapi.json", h.HandleOpenAPISpec)
+		r.Get("/docs", h.HandleSwaggerUI)
+		r.Get("/packs", h.HandlePacks)
+		r.Get("/catalog", h.HandleCatalog)
+		r.Get("/catalog/{category}", h.HandleCatalogCategory)
+		r.Post("/validate", h.HandleValidate)
+		r.Post("/resolve", h.HandleResolve)
+		r.Get("/rules", h.HandleRules)
+		r.Post("/codes/encode", h.HandleEncodeCode)
+		r.Post("/codes/decode", h.HandleDecodeCode)
+		r.With(guards["random"]).Post("/random", h.HandleRandom)
+		r.With(guards["glb"]).Post("/render/glb", h.HandleGLB)
+		r.With(guards["png"]).Post("/render/png", h.HandlePNG)
+		r.With(guards["png"]).Get("/render/png", h.HandlePNG)
+		r.With(guards["gif"]).Post("/render/gif", h.HandleGIF)
+		r.With(guards["gif"]).Get("/render/gif", h.HandleGIF)
+		r.With(guards["mp4"]).Post("/render/mp4", h.HandleMP4)
+		r.With(guards["mp4"]).Get("/render/mp4", h.HandleMP4)
+		r.With(guards["obj"]).Post("/render/obj", h.HandleOBJ)
+		r.With(guards["stl"]).Post("/render/stl", h.HandleSTL)
+		r.With(guards["vox"]).Post("/render/vox", h.HandleVOX)
+		r.With(guards["atlas"]).Post("/render/atlas", h.HandleAtlas)
+		r.With(guards["blockymodel"]).Post("/render/blockymodel", h.HandleBlockyModel)
+		r.With(guards["item"]).Post("/render/item", h.HandleItem)
+		r.With(guards["item"]).Get("/render/item", h.HandleItem)
+		r.With(guards["jobs"]).Post("/jobs", h.HandleSubmitJob)
+		r.With(guards["jobs"]).Get("/jobs/{id}", h.HandleJob)
+		r.With(guards["jobs"]).Get("/jobs/{id}/result", h.HandleJobResult)
+		r.Get("/renders/{id}/result", h.HandleRenderResult)
+
+		// Admin routes; uploads are stored and served to every client
+		reloadCfg := config.LoadReloadConfig()
+		r.With(AdminAuth(reloadCfg.AdminToken)).Post("/admin/reload", h.HandleReload)
+		r.With(guards["cosmetics"], AdminAuth(reloadCfg.AdminToken)).Post("/cosmetics", h.HandleUploadCosmetic)
+	})
+
+	return r, nil
 }
@@ -1,42 +1,281 @@
 package api
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"blockyserver/internal/cache"
+	"blockyserver/internal/service"
+)
+
+// PackSelector picks the asset pack a request is served from. Endpoints
+// whose body is the character take these fields at the top level of the
+// character, or as query parameters.
+type PackSelector struct {
+	AssetPack string `json:"assetPack,omitempty"` // asset pack name, default the server's default pack
+	Version   string `json:"version,omitempty"`   // alias of assetPack
+}
 
 // PNGRequest represents a request to render a character as PNG
 type PNGRequest struct {
-	Character  json.RawMessage `json:"character"`
-	Rotation   float64         `json:"rotation"`   // degrees, default 0
-	Background string          `json:"background"` // "transparent" or hex "#RRGGBB"
-	Width      int             `json:"width"`      // default 512
-	Height     int             `json:"height"`     // default 512
+	Character json.RawMessage `json:"character"`
+	PackSelector
+	Strict     bool    `json:"strict"`     // reject fallbacks and missing parts, default false
+	Rotation   float64 `json:"rotation"`   // degrees, default 0
+	Background string  `json:"background"` // "transparent" or hex "#RRGGBB"
+	Width      int     `json:"width"`      // default 512
+	Height     int     `json:"height"`     // default 512
+}
+
+// ItemRequest represents a request to render a single cosmetic as PNG
+type ItemRequest struct {
+	Category string `json:"category"` // character field, e.g. "overtop", default the category holding the item ID
+	Item     string `json:"item"`     // "Id.Color.Variant", e.g. "Jacket_A.Red"
+	PackSelector
+	Strict     bool    `json:"strict"`     // reject fallbacks and missing textures, default false
+	Mannequin  bool    `json:"mannequin"`  // show the item on a faded player body, default false
+	SkinTone   string  `json:"skinTone"`   // skin tone for skin-colored items and the mannequin
+	Rotation   float64 `json:"rotation"`   // degrees, default 0
+	Background string  `json:"background"` // "transparent" or hex "#RRGGBB"
+	Width      int     `json:"width"`      // default 512
+	Height     int     `json:"height"`     // default 512
 }
 
 // GIFRequest represents a request to render a character as animated GIF
 type GIFRequest struct {
-	Character  json.RawMessage `json:"character"`
-	Background string          `json:"background"` // hex color "#RRGGBB"
-	Frames     int             `json:"frames"`     // default 36 (10° per frame)
-	Width      int             `json:"width"`      // default 512
-	Height     int             `json:"height"`     // default 512
-	Delay      int             `json:"delay"`      // centiseconds between frames, default 5
-	Dithering  *bool           `json:"dithering"`  // Floyd-Steinberg dithering, default true
-	AutoZoom   *bool           `json:"autoZoom"`   // auto-zoom to fit character, default true
+	Character json.RawMessage `json:"character"`
+	PackSelector
+	Strict     bool   `json:"strict"`     // reject fallbacks and missing parts, default false
+	Background string `json:"background"` // hex color "#RRGGBB"
+	Frames     int    `json:"frames"`     // default 36 (10° per frame)
+	Width      int    `json:"width"`      // default 512
+	Height     int    `json:"height"`     // default 512
+	Delay      int    `json:"delay"`      // centiseconds between frames, default 5
+	Dithering  *bool  `json:"dithering"`  // Floyd-Steinberg dithering, default true
+	AutoZoom   *bool  `json:"autoZoom"`   // auto-zoom to fit character, default true
 }
 
 // MP4Request represents a request to render a character as MP4 video
 type MP4Request struct {
-	Character  json.RawMessage `json:"character"`
-	Background string          `json:"background"` // hex color "#RRGGBB", default "#FFFFFF"
-	Frames     int             `json:"frames"`     // default 36 (10° per frame)
-	Width      int             `json:"width"`      // default 512
-	Height     int             `json:"height"`     // default 512
-	FPS        int             `json:"fps"`        // frames per second, default 12
-	AutoZoom   *bool           `json:"autoZoom"`   // auto-zoom to fit character, default true
+	Character json.RawMessage `json:"character"`
+	PackSelector
+	Strict     bool   `json:"strict"`     // reject fallbacks and missing parts, default false
+	Background string `json:"background"` // hex color "#RRGGBB", default "#FFFFFF"
+	Frames     int    `json:"frames"`     // default 36 (10° per frame)
+	Width      int    `json:"width"`      // default 512
+	Height     int    `json:"height"`     // default 512
+	FPS        int    `json:"fps"`        // frames per second, default 12
+	AutoZoom   *bool  `json:"autoZoom"`   // auto-zoom to fit character, default true
+}
+
+// STLRequest represents a request to export a character as a printable STL
+type STLRequest struct {
+	Character json.RawMessage `json:"character"`
+	PackSelector
+	Strict             bool    `json:"strict"`             // reject fallbacks and missing parts, default false
+	Height             float64 `json:"height"`             // model height in millimetres, default 100
+	BasePlate          bool    `json:"basePlate"`          // add a base plate under the feet, default false
+	BasePlateThickness float64 `json:"basePlateThickness"` // plate thickness in millimetres, default 2
+	BasePlateMargin    float64 `json:"basePlateMargin"`    // plate margin around the model in millimetres, default 3
+}
+
+// VOXRequest represents a request to voxelize a character as a MagicaVoxel model
+type VOXRequest struct {
+	Character json.RawMessage `json:"character"`
+	PackSelector
+	Strict     bool `json:"strict"`     // reject fallbacks and missing parts, default false
+	Resolution int  `json:"resolution"` // voxels along the longest axis, default 64, max 256
+}
+
+// AtlasRequest represents a request for the packed texture atlas of a character
+type AtlasRequest struct {
+	Character json.RawMessage `json:"character"`
+	PackSelector
+	Strict         bool   `json:"strict"`         // reject fallbacks and missing parts, default false
+	Format         string `json:"format"`         // "zip" (PNG + manifest), "png" or "json", default "zip"
+	Wireframe      bool   `json:"wireframe"`      // draw the UV layout on top of the atlas, default false
+	WireframeColor string `json:"wireframeColor"` // hex color "#RRGGBB", default "#FF00FF"
+}
+
+// AtlasManifest describes the layout of a packed texture atlas
+type AtlasManifest struct {
+	Width    int                  `json:"width"`
+	Height   int                  `json:"height"`
+	Entries  []AtlasManifestEntry `json:"entries"`
+	Warnings []service.FieldIssue `json:"warnings"`
+}
+
+// AtlasManifestEntry describes a single texture placed in the atlas
+type AtlasManifestEntry struct {
+	Name        string `json:"name"`
+	Category    string `json:"category"`
+	X           int    `json:"x"`
+	Y           int    `json:"y"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Texture     string `json:"texture"`
+	GradientSet string `json:"gradientSet,omitempty"`
+	Color       string `json:"color,omitempty"`
+}
+
+// RandomRequest represents a request to generate a random character
+type RandomRequest struct {
+	PackSelector
+	Seed          *int64              `json:"seed"`          // default: random, returned in the response
+	Categories    []string            `json:"categories"`    // fields to fill, default all common fields
+	Probabilities map[string]float64  `json:"probabilities"` // field -> chance of being filled, 0-1
+	Palettes      map[string][]string `json:"palettes"`      // gradient set -> allowed colors
+	Locked        map[string]string   `json:"locked"`        // field -> fixed "Id.Color.Variant" value
+	Render        string              `json:"render"`        // "png" or "glb" to include a render, default none
+	Rotation      float64             `json:"rotation"`      // PNG rotation in degrees, default 0
+	Background    string              `json:"background"`    // PNG background, default "transparent"
+	Width         int                 `json:"width"`         // PNG width, default 512
+	Height        int                 `json:"height"`        // PNG height, default 512
+}
+
+// RandomResponse contains a generated character and its optional render
+type RandomResponse struct {
+	Seed       int64                `json:"seed"`
+	Character  map[string]string    `json:"character"`
+	Warnings   []service.FieldIssue `json:"warnings,omitempty"`   // render warnings, only with render
+	Render     string               `json:"render,omitempty"`     // base64-encoded render
+	RenderType string               `json:"renderType,omitempty"` // MIME type of the render
+}
+
+// ResolveResponse describes the character that is actually rendered
+type ResolveResponse struct {
+	Character   map[string]string           `json:"character"` // final field values after fallbacks
+	Accessories []service.ResolvedAccessory `json:"accessories"`
+	Textures    []ResolvedTexture           `json:"textures"` // textures packed into the atlas, in packing order
+	Warnings    []service.FieldIssue        `json:"warnings"`
+}
+
+// RulesResponse lists the rules that remove or replace character slots
+type RulesResponse struct {
+	Rules []service.Rule `json:"rules"` // in the order they are checked within a field
+}
+
+// CodeDecodeRequest holds a character code to unpack
+type CodeDecodeRequest struct {
+	PackSelector
+	Code string `json:"code"`
+}
+
+// CodeResponse pairs a character code with the character it stands for
+type CodeResponse struct {
+	Code      string            `json:"code"`
+	Version   int               `json:"version"` // code format version
+	Character map[string]string `json:"character"`
+}
+
+// BatchRequest represents a request to render many characters at once
+type BatchRequest struct {
+	PackSelector
+	Defaults map[string]json.RawMessage   `json:"defaults"` // fields shared by every job, overridden by the job
+	Jobs     []map[string]json.RawMessage `json:"jobs"`     // id, format (glb, png, gif or mp4), character and the options of the format
+	Output   string                       `json:"output"`   // "zip" or "multipart", default "zip"
+}
+
+// JobRequest holds the fields of a POST /jobs body that are not part of
+// the render request
+type JobRequest struct {
+	PackSelector
+	Webhook string `json:"webhook"` // notified with the job status when it finishes
+}
+
+// ProgressEvent is the data of a Server-Sent Event following a render or job
+type ProgressEvent struct {
+	Status    string `json:"status"`          // queued, running, done or failed
+	Stage     string `json:"stage,omitempty"` // render, quantize or encode while running
+	Done      int    `json:"done"`            // frames rendered
+	Total     int    `json:"total"`
+	Percent   int    `json:"percent,omitempty"`   // of the encode stage
+	ResultURL string `json:"resultUrl,omitempty"` // once done
+	Error     string `json:"error,omitempty"`
+}
+
+// BatchManifest records the outcome of every job of a batch
+type BatchManifest struct {
+	Jobs   []BatchJobResult `json:"jobs"`
+	Merges int              `json:"merges"` // distinct characters merged
+}
+
+// BatchJobResult records the outcome of a batch job
+type BatchJobResult struct {
+	Index    int                  `json:"index"`
+	ID       string               `json:"id"` // default the index
+	Format   string               `json:"format"`
+	Status   string               `json:"status"`         // "ok" or "error"
+	File     string               `json:"file,omitempty"` // entry name, "<id>.<format>"
+	Bytes    int                  `json:"bytes,omitempty"`
+	Cache    string               `json:"cache,omitempty"` // HIT if served from the render cache, MISS otherwise
+	Warnings []service.FieldIssue `json:"warnings,omitempty"`
+	Error    string               `json:"error,omitempty"`
+	Fields   []service.FieldIssue `json:"fields,omitempty"` // per-field problems for invalid characters
+}
+
+// ResolvedTexture describes a texture that will be packed into the atlas
+type ResolvedTexture struct {
+	Name        string `json:"name"`
+	Category    string `json:"category"`
+	Texture     string `json:"texture"`
+	GradientSet string `json:"gradientSet,omitempty"`
+	Color       string `json:"color,omitempty"`
+}
+
+// CatalogResponse represents a page of cosmetics from the catalog
+type CatalogResponse struct {
+	Categories []CatalogCategory      `json:"categories,omitempty"` // only on /catalog
+	Total      int                    `json:"total"`
+	Offset     int                    `json:"offset"`
+	Limit      int                    `json:"limit"`
+	Items      []*service.CatalogItem `json:"items"`
+}
+
+// CatalogCategory summarizes a catalog category
+type CatalogCategory struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// HealthResponse represents the server status
+type HealthResponse struct {
+	Status       string                          `json:"status"`
+	AssetVersion string                          `json:"assetVersion"`   // fingerprint of the default pack
+	Reload       service.ReloadStatus            `json:"reload"`         // default pack
+	Packs        map[string]service.ReloadStatus `json:"packs"`          // every pack by name
+	Cache        map[string]cache.Stats          `json:"cache"`          // asset cache statistics of the default pack by cache name
+	RenderCache  map[string]cache.Stats          `json:"renderCache"`    // render cache statistics by format
+	Jobs         map[string]int                  `json:"jobs,omitempty"` // render jobs by status, if enabled
+}
+
+// ReloadResponse reports the outcome of an asset reload
+type ReloadResponse struct {
+	Error  string                          `json:"error,omitempty"`
+	Reload service.ReloadStatus            `json:"reload"` // default pack
+	Packs  map[string]service.ReloadStatus `json:"packs"`
+}
+
+// PacksResponse lists the loaded asset packs
+type PacksResponse struct {
+	Default string     `json:"default"`
+	Packs   []PackInfo `json:"packs"`
+}
+
+// PackInfo describes a loaded asset pack
+type PackInfo struct {
+	Name       string               `json:"name"`
+	Default    bool                 `json:"default"`
+	Cosmetics  int                  `json:"cosmetics"` // total number of cosmetics
+	Categories []CatalogCategory    `json:"categories"`
+	Reload     service.ReloadStatus `json:"reload"` // asset version and load time
 }
 
 // ErrorResponse represents an error returned by the API
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error  string               `json:"error"`
+	Fields []service.FieldIssue `json:"fields,omitempty"` // per-field problems for invalid characters
 }
 
 // ApplyDefaults fills in default values for PNGRequest
@@ -52,6 +291,36 @@ func (r *PNGRequest) ApplyDefaults() {
 	}
 }
 
+// ApplyDefaults fills in default values for ItemRequest
+func (r *ItemRequest) ApplyDefaults() {
+	if r.Width == 0 {
+		r.Width = 512
+	}
+	if r.Height == 0 {
+		r.Height = 512
+	}
+	if r.Background == "" {
+		r.Background = "transparent"
+	}
+}
+
+// ApplyDefaults fills in default values for RandomRequest
+func (r *RandomRequest) ApplyDefaults() {
+	if r.Seed == nil {
+		seed := time.Now().UnixNano()
+		r.Seed = &seed
+	}
+	if r.Width == 0 {
+		r.Width = 512
+	}
+	if r.Height == 0 {
+		r.Height = 512
+	}
+	if r.Background == "" {
+		r.Background = "transparent"
+	}
+}
+
 // ApplyDefaults fills in default values for GIFRequest
 func (r *GIFRequest) ApplyDefaults() {
 	if r.Width == 0 {
@@ -101,3 +370,101 @@ func (r *MP4Request) ApplyDefaults() {
 		r.AutoZoom = &defaultAutoZoom
 	}
 }
+
+// Limits of the image and animation render requests, checked after their
+// defaults are applied
+const (
+	MaxImageSize = 4096  // pixels along either side
+	MaxFrames    = 720   // frames of a GIF or MP4
+	MaxDelay     = 65535 // centiseconds between GIF frames, as the format stores them
+	MaxFPS       = 120   // frames per second of an MP4
+)
+
+// validateSize checks the width and height of a render
+func validateSize(width, height int) error {
+	if width < 1 || width > MaxImageSize || height < 1 || height > MaxImageSize {
+		return fmt.Errorf("width and height must be between 1 and %d", MaxImageSize)
+	}
+	return nil
+}
+
+// validateFrames checks the frame count of an animation
+func validateFrames(frames int) error {
+	if frames < 1 || frames > MaxFrames {
+		return fmt.Errorf("frames must be between 1 and %d", MaxFrames)
+	}
+	return nil
+}
+
+// Validate checks the values of a PNGRequest
+func (r *PNGRequest) Validate() error {
+	return validateSize(r.Width, r.Height)
+}
+
+// Validate checks the values of an ItemRequest
+func (r *ItemRequest) Validate() error {
+	return validateSize(r.Width, r.Height)
+}
+
+// Validate checks the values of a RandomRequest
+func (r *RandomRequest) Validate() error {
+	return validateSize(r.Width, r.Height)
+}
+
+// Validate checks the values of a GIFRequest
+func (r *GIFRequest) Validate() error {
+	if err := validateSize(r.Width, r.Height); err != nil {
+		return err
+	}
+	if err := validateFrames(r.Frames); err != nil {
+		return err
+	}
+	if r.Delay < 1 || r.Delay > MaxDelay {
+		return fmt.Errorf("delay must be between 1 and %d", MaxDelay)
+	}
+	return nil
+}
+
+// Validate checks the values of an MP4Request
+func (r *MP4Request) Validate() error {
+	if err := validateSize(r.Width, r.Height); err != nil {
+		return err
+	}
+	if err := validateFrames(r.Frames); err != nil {
+		return err
+	}
+	if r.FPS < 1 || r.FPS > MaxFPS {
+		return fmt.Errorf("fps must be between 1 and %d", MaxFPS)
+	}
+	return nil
+}
+
+// ApplyDefaults fills in default values for STLRequest
+func (r *STLRequest) ApplyDefaults() {
+	if r.Height == 0 {
+		r.Height = 100
+	}
+	if r.BasePlateThickness == 0 {
+		r.BasePlateThickness = 2
+	}
+	if r.BasePlateMargin == 0 {
+		r.BasePlateMargin = 3
+	}
+}
+
+// ApplyDefaults fills in default values for VOXRequest
+func (r *VOXRequest) ApplyDefaults() {
+	if r.Resolution == 0 {
+		r.Resolution = 64
+	}
+}
+
+// ApplyDefaults fills in default values for AtlasRequest
+func (r *AtlasRequest) ApplyDefaults() {
+	if r.Format == "" {
+		r.Format = "zip"
+	}
+	if r.WireframeColor == "" {
+		r.WireframeColor = "#FF00FF"
+	}
+}
@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Stats reports the state of a cache
+type Stats struct {
+	Entries   int    `json:"entries"`
+	Bytes     int64  `json:"bytes"`
+	MaxBytes  int64  `json:"maxBytes"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+// LRU is a least-recently-used cache bounded by the total size of its values.
+// It is safe for concurrent use.
+type LRU[K comparable, V any] struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	sizeOf   func(V) int64
+	order    *list.List // front is most recently used
+	items    map[K]*list.Element
+	onEvict  func(K, V)
+
+	hits, misses, evictions uint64
+}
+
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+	size  int64
+}
+
+// New creates a cache holding at most maxBytes as measured by sizeOf.
+// A cache with maxBytes <= 0 stores nothing.
+func New[K comparable, V any](maxBytes int64, sizeOf func(V) int64) *LRU[K, V] {
+	return &LRU[K, V]{
+		maxBytes: maxBytes,
+		sizeOf:   sizeOf,
+		order:    list.New(),
+		items:    make(map[K]*list.Element),
+	}
+}
+
+// Get returns the value stored for key and marks it as recently used
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		c.misses++
+		var zero V
+		return zero, false
+	}
+	c.hits++
+	c.order.MoveToFront(el)
+	return el.Value.(*entry[K, V]).value, true
+}
+
+// Add stores a value, evicting the least recently used values until it fits.
+// Values larger than the whole cache are not stored.
+func (c *LRU[K, V]) Add(key K, value V) {
+	size := c.sizeOf(value)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+	if size > c.maxBytes {
+		return
+	}
+
+	for c.size+size > c.maxBytes {
+		e := c.removeElement(c.order.Back())
+		c.evictions++
+		if c.onEvict != nil {
+			c.onEvict(e.key, e.value)
+		}
+	}
+
+	c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, size: size})
+	c.size += size
+}
+
+// GetOrLoad returns the cached value for key, calling load and caching its
+// result on a miss. Errors are not cached.
+func (c *LRU[K, V]) GetOrLoad(key K, load func() (V, error)) (V, error) {
+	if v, ok := c.Get(key); ok {
+		return v, nil
+	}
+	v, err := load()
+	if err != nil {
+		return v, err
+	}
+	c.Add(key, v)
+	return v, nil
+}
+
+// Purge removes every value. Statistics are kept.
+func (c *LRU[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.items = make(map[K]*list.Element)
+	c.size = 0
+}
+
+// Stats returns a snapshot of the cache statistics
+func (c *LRU[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{
+		Entries:   len(c.items),
+		Bytes:     c.size,
+		MaxBytes:  c.maxBytes,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
+
+// OnEvict registers a function called with every value evicted to make room.
+// It runs while the cache is locked and must not call back into the cache.
+func (c *LRU[K, V]) OnEvict(fn func(key K, value V)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onEvict = fn
+}
+
+func (c *LRU[K, V]) removeElement(el *list.Element) *entry[K, V] {
+	e := c.order.Remove(el).(*entry[K, V])
+	delete(c.items, e.key)
+	c.size -= e.size
+	return e
+}
@@ -0,0 +1,208 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RenderEntry is an encoded render stored in the render cache
+type RenderEntry struct {
+	ContentType string
+	Filename    string // attachment filename, empty for inline responses
+	Warnings    string // X-Blocky-Warnings header value
+	Data        []byte
+}
+
+// RenderCacheOptions configures a RenderCache
+type RenderCacheOptions struct {
+	MaxBytes     map[string]int64 // format -> memory budget
+	Dir          string           // directory for the disk tier, empty disables it
+	DiskMaxBytes int64            // disk budget per format
+}
+
+// RenderCache stores encoded renders by format and request hash, in memory
+// and optionally on disk. Disk entries survive restarts.
+type RenderCache struct {
+	memory map[string]*LRU[string, *RenderEntry]
+	disk   map[string]*LRU[string, int64] // key -> file size
+	dir    string
+
+	diskMax int64
+	mu      sync.Mutex // orders disk writes with the evictions they cause
+}
+
+// NewRenderCache creates a render cache with one memory and disk tier per format
+func NewRenderCache(opts RenderCacheOptions) (*RenderCache, error) {
+	c := &RenderCache{
+		memory:  make(map[string]*LRU[string, *RenderEntry]),
+		disk:    make(map[string]*LRU[string, int64]),
+		dir:     opts.Dir,
+		diskMax: opts.DiskMaxBytes,
+	}
+
+	for format, maxBytes := range opts.MaxBytes {
+		c.memory[format] = New[string, *RenderEntry](maxBytes, entrySize)
+
+		if opts.Dir == "" || opts.DiskMaxBytes <= 0 {
+			continue
+		}
+		formatDir := filepath.Join(opts.Dir, format)
+		if err := os.MkdirAll(formatDir, 0755); err != nil {
+			return nil, fmt.Errorf("creating render cache directory: %w", err)
+		}
+
+		disk := New[string, int64](opts.DiskMaxBytes, func(size int64) int64 { return size })
+		disk.OnEvict(func(key string, _ int64) {
+			os.Remove(filepath.Join(formatDir, key))
+		})
+		if err := indexDisk(disk, formatDir, opts.DiskMaxBytes); err != nil {
+			return nil, err
+		}
+		c.disk[format] = disk
+	}
+
+	return c, nil
+}
+
+// indexDisk adds the entries already on disk, oldest first, so the most
+// recently written ones are evicted last. Files that do not fit the budget
+// are removed, so every file left in the directory is tracked.
+func indexDisk(disk *LRU[string, int64], dir string, maxBytes int64) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("reading render cache directory: %w", err)
+	}
+
+	type file struct {
+		name    string
+		size    int64
+		modTime int64
+	}
+	var files []file
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			// Left over from an interrupted write
+			os.Remove(filepath.Join(dir, e.Name()))
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, file{e.Name(), info.Size(), info.ModTime().UnixNano()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime < files[j].modTime })
+
+	for _, f := range files {
+		if f.size > maxBytes {
+			os.Remove(filepath.Join(dir, f.name))
+			continue
+		}
+		disk.Add(f.name, f.size)
+	}
+	return nil
+}
+
+// Get returns a cached render, promoting disk entries to memory
+func (c *RenderCache) Get(format, key string) (*RenderEntry, bool) {
+	memory, ok := c.memory[format]
+	if !ok {
+		return nil, false
+	}
+	if e, ok := memory.Get(key); ok {
+		return e, true
+	}
+
+	disk, ok := c.disk[format]
+	if !ok {
+		return nil, false
+	}
+	if _, ok := disk.Get(key); !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(filepath.Join(c.dir, format, key))
+	if err != nil {
+		return nil, false
+	}
+	var e RenderEntry
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&e); err != nil {
+		return nil, false
+	}
+	memory.Add(key, &e)
+	return &e, true
+}
+
+// Add stores a render in memory and, if enabled, on disk
+func (c *RenderCache) Add(format, key string, e *RenderEntry) {
+	memory, ok := c.memory[format]
+	if !ok {
+		return
+	}
+	memory.Add(key, e)
+
+	disk, ok := c.disk[format]
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(e); err != nil {
+		return
+	}
+	if int64(buf.Len()) > c.diskMax {
+		// The disk tier would refuse it, leaving an untracked file
+		return
+	}
+
+	// Write to a temporary file first so readers never see a partial entry
+	dir := filepath.Join(c.dir, format)
+	path := filepath.Join(dir, key)
+	tmp, err := os.CreateTemp(dir, key+".*.tmp")
+	if err != nil {
+		log.Printf("render cache: writing %s: %v", path, err)
+		return
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		log.Printf("render cache: writing %s: %v", path, err)
+		return
+	}
+
+	// An eviction between the rename and Add would delete the new file
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		log.Printf("render cache: writing %s: %v", path, err)
+		return
+	}
+	disk.Add(key, int64(buf.Len()))
+}
+
+// Stats returns statistics for each format, with disk tiers as "<format>:disk"
+func (c *RenderCache) Stats() map[string]Stats {
+	stats := make(map[string]Stats)
+	for format, memory := range c.memory {
+		stats[format] = memory.Stats()
+	}
+	for format, disk := range c.disk {
+		stats[format+":disk"] = disk.Stats()
+	}
+	return stats
+}
+
+func entrySize(e *RenderEntry) int64 {
+	return int64(len(e.Data) + len(e.ContentType) + len(e.Filename) + len(e.Warnings))
+}
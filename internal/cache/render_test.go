@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// diskSize is the size of an entry in the disk tier
+func diskSize(t *testing.T, e *RenderEntry) int64 {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(e); err != nil {
+		t.Fatal(err)
+	}
+	return int64(buf.Len())
+}
+
+// diskFiles lists the files in a format directory
+func diskFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRenderCacheDiskEviction(t *testing.T) {
+	small := &RenderEntry{ContentType: "image/png", Data: bytes.Repeat([]byte{1}, 100)}
+	large := &RenderEntry{ContentType: "image/png", Data: bytes.Repeat([]byte{2}, 1000)}
+
+	tests := []struct {
+		name    string
+		budget  func(entry int64) int64 // disk budget given the size of small
+		add     []string                // keys of small entries, "large" for large
+		get     []string                // keys read between the adds, before the last add
+		want    []string                // files left on disk
+		entries int
+	}{
+		{"all fit", func(n int64) int64 { return 3 * n }, []string{"a", "b", "c"}, nil, []string{"a", "b", "c"}, 3},
+		{"oldest is evicted", func(n int64) int64 { return 2 * n }, []string{"a", "b", "c"}, nil, []string{"b", "c"}, 2},
+		{"recently read is kept", func(n int64) int64 { return 2 * n }, []string{"a", "b", "c"}, []string{"a"}, []string{"a", "c"}, 2},
+		{"rewrite replaces the file", func(n int64) int64 { return 2 * n }, []string{"a", "a", "b"}, nil, []string{"a", "b"}, 2},
+		{"too large is not written", func(n int64) int64 { return 2 * n }, []string{"a", "large"}, nil, []string{"a"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c, err := NewRenderCache(RenderCacheOptions{
+				MaxBytes:     map[string]int64{"png": 1 << 20},
+				Dir:          dir,
+				DiskMaxBytes: tt.budget(diskSize(t, small)),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, key := range tt.add {
+				if i == len(tt.add)-1 {
+					for _, g := range tt.get {
+						// Read from disk, not memory
+						c.memory["png"].Purge()
+						if _, ok := c.Get("png", g); !ok {
+							t.Fatalf("%s is not cached", g)
+						}
+					}
+				}
+				if key == "large" {
+					c.Add("png", key, large)
+				} else {
+					c.Add("png", key, small)
+				}
+			}
+
+			if got := diskFiles(t, filepath.Join(dir, "png")); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got files %v, want %v", got, tt.want)
+			}
+			if got := c.Stats()["png:disk"].Entries; got != tt.entries {
+				t.Errorf("got %d disk entries, want %d", got, tt.entries)
+			}
+		})
+	}
+}
+
+func TestRenderCacheIndexDisk(t *testing.T) {
+	entry := &RenderEntry{ContentType: "image/png", Data: bytes.Repeat([]byte{1}, 100)}
+	size := diskSize(t, entry)
+	dir := t.TempDir()
+
+	c, err := NewRenderCache(RenderCacheOptions{
+		MaxBytes:     map[string]int64{"png": 1 << 20},
+		Dir:          dir,
+		DiskMaxBytes: 3 * size,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatDir := filepath.Join(dir, "png")
+	for i, key := range []string{"a", "b", "c"} {
+		c.Add("png", key, entry)
+		modTime := time.Now().Add(time.Duration(i-3) * time.Minute)
+		os.Chtimes(filepath.Join(formatDir, key), modTime, modTime)
+	}
+
+	// Leftovers of an interrupted write and files over the budget are removed
+	os.WriteFile(filepath.Join(formatDir, "d.123.tmp"), []byte("partial"), 0o644)
+	os.WriteFile(filepath.Join(formatDir, "huge"), bytes.Repeat([]byte{0}, int(3*size)), 0o644)
+
+	// A smaller budget after a restart evicts the oldest entries
+	c, err = NewRenderCache(RenderCacheOptions{
+		MaxBytes:     map[string]int64{"png": 1 << 20},
+		Dir:          dir,
+		DiskMaxBytes: 2 * size,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := diskFiles(t, formatDir); strings.Join(got, ",") != "b,c" {
+		t.Errorf("got files %v, want [b c]", got)
+	}
+	if got := c.Stats()["png:disk"]; got.Entries != 2 || got.Bytes != 2*size {
+		t.Errorf("got %d entries of %d bytes, want 2 of %d", got.Entries, got.Bytes, 2*size)
+	}
+	if e, ok := c.Get("png", "c"); !ok || !bytes.Equal(e.Data, entry.Data) {
+		t.Errorf("c is not read back from disk")
+	}
+}
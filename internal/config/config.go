@@ -1,31 +1,290 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // EndpointConfig holds enable/disable flags for render endpoints
 type EndpointConfig struct {
-	GLBEnabled bool
-	PNGEnabled bool
-	GIFEnabled bool
-	MP4Enabled bool
+	GLBEnabled         bool
+	PNGEnabled         bool
+	GIFEnabled         bool
+	MP4Enabled         bool
+	OBJEnabled         bool
+	STLEnabled         bool
+	VOXEnabled         bool
+	AtlasEnabled       bool
+	BlockyModelEnabled bool
+	ItemEnabled        bool
+	RandomEnabled      bool
+	CosmeticsEnabled   bool
+	BatchEnabled       bool
+	JobsEnabled        bool
+	MaxURLLength       int // longest URL accepted by GET render endpoints
 }
 
 // LoadEndpointConfig reads endpoint configuration from environment variables.
 // All endpoints are enabled by default.
 // Set BLOCKY_DISABLE_GLB=true, BLOCKY_DISABLE_PNG=true, etc. to disable.
+// BLOCKY_MAX_URL_LENGTH limits GET render URLs (default 4096 bytes).
 func LoadEndpointConfig() *EndpointConfig {
 	return &EndpointConfig{
-		GLBEnabled: !isDisabled("BLOCKY_DISABLE_GLB"),
-		PNGEnabled: !isDisabled("BLOCKY_DISABLE_PNG"),
-		GIFEnabled: !isDisabled("BLOCKY_DISABLE_GIF"),
-		MP4Enabled: !isDisabled("BLOCKY_DISABLE_MP4"),
+		GLBEnabled:         !isDisabled("BLOCKY_DISABLE_GLB"),
+		PNGEnabled:         !isDisabled("BLOCKY_DISABLE_PNG"),
+		GIFEnabled:         !isDisabled("BLOCKY_DISABLE_GIF"),
+		MP4Enabled:         !isDisabled("BLOCKY_DISABLE_MP4"),
+		OBJEnabled:         !isDisabled("BLOCKY_DISABLE_OBJ"),
+		STLEnabled:         !isDisabled("BLOCKY_DISABLE_STL"),
+		VOXEnabled:         !isDisabled("BLOCKY_DISABLE_VOX"),
+		AtlasEnabled:       !isDisabled("BLOCKY_DISABLE_ATLAS"),
+		BlockyModelEnabled: !isDisabled("BLOCKY_DISABLE_BLOCKYMODEL"),
+		ItemEnabled:        !isDisabled("BLOCKY_DISABLE_ITEM"),
+		RandomEnabled:      !isDisabled("BLOCKY_DISABLE_RANDOM"),
+		CosmeticsEnabled:   !isDisabled("BLOCKY_DISABLE_COSMETICS"),
+		BatchEnabled:       !isDisabled("BLOCKY_DISABLE_BATCH"),
+		JobsEnabled:        !isDisabled("BLOCKY_DISABLE_JOBS"),
+		MaxURLLength:       int(envInt("BLOCKY_MAX_URL_LENGTH", 4096)),
 	}
 }
 
+// CacheConfig holds limits for the in-memory asset cache
+type CacheConfig struct {
+	MaxBytes int64 // total budget for models, images and tinted textures
+	Preload  bool  // load every model and texture at startup
+}
+
+// LoadCacheConfig reads cache configuration from environment variables.
+// BLOCKY_CACHE_MAX_MB sets the budget in megabytes (default 256, 0 disables caching).
+// BLOCKY_PRELOAD_ASSETS=true loads every model and texture at startup.
+func LoadCacheConfig() *CacheConfig {
+	return &CacheConfig{
+		MaxBytes: envInt("BLOCKY_CACHE_MAX_MB", 256) << 20,
+		Preload:  envBool("BLOCKY_PRELOAD_ASSETS"),
+	}
+}
+
+// RenderCacheConfig holds limits for the render result cache
+type RenderCacheConfig struct {
+	MaxBytes     map[string]int64 // format -> memory budget
+	Dir          string           // disk tier directory, empty disables it
+	DiskMaxBytes int64            // disk budget per format
+	MaxAge       int              // Cache-Control max-age in seconds
+}
+
+// LoadRenderCacheConfig reads render cache configuration from environment variables.
+// BLOCKY_RENDER_CACHE_MB sets the memory budget per format (default 32, 0 disables caching);
+// BLOCKY_RENDER_CACHE_<FORMAT>_MB overrides it for one format, e.g. BLOCKY_RENDER_CACHE_MP4_MB.
+// BLOCKY_RENDER_CACHE_DIR enables the disk tier, limited by BLOCKY_RENDER_CACHE_DISK_MB per format (default 256).
+// BLOCKY_RENDER_CACHE_MAX_AGE sets the Cache-Control max-age in seconds (default 3600).
+func LoadRenderCacheConfig(formats []string) *RenderCacheConfig {
+	defaultMB := envInt("BLOCKY_RENDER_CACHE_MB", 32)
+
+	cfg := &RenderCacheConfig{
+		MaxBytes:     make(map[string]int64),
+		Dir:          os.Getenv("BLOCKY_RENDER_CACHE_DIR"),
+		DiskMaxBytes: envInt("BLOCKY_RENDER_CACHE_DISK_MB", 256) << 20,
+		MaxAge:       int(envInt("BLOCKY_RENDER_CACHE_MAX_AGE", 3600)),
+	}
+	for _, format := range formats {
+		envVar := "BLOCKY_RENDER_CACHE_" + strings.ToUpper(format) + "_MB"
+		cfg.MaxBytes[format] = envInt(envVar, defaultMB) << 20
+	}
+	return cfg
+}
+
+// ReloadConfig holds settings for reloading assets without a restart
+type ReloadConfig struct {
+	Interval   time.Duration // how often to poll the asset files for changes, 0 disables polling
+	AdminToken string        // bearer token for /admin endpoints, empty disables them
+}
+
+// LoadReloadConfig reads reload configuration from environment variables.
+// BLOCKY_RELOAD_INTERVAL sets the polling interval in seconds (default 0, disabled).
+// BLOCKY_ADMIN_TOKEN enables POST /admin/reload.
+func LoadReloadConfig() *ReloadConfig {
+	return &ReloadConfig{
+		Interval:   time.Duration(envInt("BLOCKY_RELOAD_INTERVAL", 0)) * time.Second,
+		AdminToken: os.Getenv("BLOCKY_ADMIN_TOKEN"),
+	}
+}
+
+// BatchConfig holds limits for batch renders
+type BatchConfig struct {
+	MaxJobs int           // jobs per batch
+	Timeout time.Duration // time a batch may take before the remaining jobs fail
+}
+
+// LoadBatchConfig reads batch configuration from environment variables.
+// BLOCKY_BATCH_MAX_JOBS limits the jobs per batch (default 1000) and
+// BLOCKY_BATCH_TIMEOUT the time a batch may take in seconds (default 600).
+func LoadBatchConfig() *BatchConfig {
+	return &BatchConfig{
+		MaxJobs: int(envInt("BLOCKY_BATCH_MAX_JOBS", 1000)),
+		Timeout: time.Duration(envInt("BLOCKY_BATCH_TIMEOUT", 600)) * time.Second,
+	}
+}
+
+// JobsConfig holds the storage and limits for asynchronous render jobs
+type JobsConfig struct {
+	Dir           string        // directory the jobs and their results are stored in
+	Workers       int           // jobs rendered at the same time
+	MaxQueued     int           // jobs waiting to run
+	TTL           time.Duration // how long finished jobs are kept
+	WebhookSecret string        // HMAC key for webhook signatures
+	WebhookHosts  []string      // webhook hosts allowed, empty allows any public host
+}
+
+// LoadJobsConfig reads job configuration from environment variables.
+// BLOCKY_JOBS_DIR sets the job directory (default "jobs").
+// BLOCKY_JOBS_WORKERS sets the jobs rendered at the same time (default 2),
+// BLOCKY_JOBS_MAX_QUEUED the jobs waiting to run (default 100) and
+// BLOCKY_JOBS_TTL how long finished jobs are kept in seconds (default 86400, 0 keeps them).
+// BLOCKY_WEBHOOK_SECRET enables completion webhooks signed with it, and
+// BLOCKY_WEBHOOK_ALLOWED_HOSTS restricts them to a comma-separated list of hosts.
+func LoadJobsConfig() *JobsConfig {
+	return &JobsConfig{
+		Dir:           envString("BLOCKY_JOBS_DIR", "jobs"),
+		Workers:       int(envInt("BLOCKY_JOBS_WORKERS", 2)),
+		MaxQueued:     int(envInt("BLOCKY_JOBS_MAX_QUEUED", 100)),
+		TTL:           time.Duration(envInt("BLOCKY_JOBS_TTL", 86400)) * time.Second,
+		WebhookSecret: os.Getenv("BLOCKY_WEBHOOK_SECRET"),
+		WebhookHosts:  envList("BLOCKY_WEBHOOK_ALLOWED_HOSTS"),
+	}
+}
+
+// CustomConfig holds the storage and limits for uploaded cosmetics
+type CustomConfig struct {
+	Dir            string // directory the uploads are stored in
+	MaxUploadBytes int64  // model and texture together
+	MaxNodes       int    // nodes per model
+	MaxTextureSize int    // texture width and height in pixels
+	MaxCount       int    // stored cosmetics
+}
+
+// LoadCustomConfig reads custom cosmetic configuration from environment variables.
+// BLOCKY_CUSTOM_DIR sets the upload directory (default "custom").
+// BLOCKY_CUSTOM_MAX_KB limits the size of an upload (default 1024),
+// BLOCKY_CUSTOM_MAX_NODES the nodes per model (default 256),
+// BLOCKY_CUSTOM_MAX_TEXTURE the texture size in pixels (default 512) and
+// BLOCKY_CUSTOM_MAX_COUNT the number of stored cosmetics (default 1000).
+func LoadCustomConfig() *CustomConfig {
+	return &CustomConfig{
+		Dir:            envString("BLOCKY_CUSTOM_DIR", "custom"),
+		MaxUploadBytes: envInt("BLOCKY_CUSTOM_MAX_KB", 1024) << 10,
+		MaxNodes:       int(envInt("BLOCKY_CUSTOM_MAX_NODES", 256)),
+		MaxTextureSize: int(envInt("BLOCKY_CUSTOM_MAX_TEXTURE", 512)),
+		MaxCount:       int(envInt("BLOCKY_CUSTOM_MAX_COUNT", 1000)),
+	}
+}
+
+// PathsConfig holds the locations of the asset and data files.
+// The individual files replace the ones in the directories or zip.
+type PathsConfig struct {
+	Packs            string // comma-separated name=path asset packs, see ParsePacks
+	DefaultPack      string // pack used when a request does not pick one, default the first
+	AssetsZip        string
+	AssetsDir        string
+	DataDir          string
+	BaseModel        string
+	BaseTexture      string
+	HeadAccessories  string
+	Haircuts         string
+	HaircutFallbacks string
+	GradientSets     string
+	Rules            string
+}
+
+// LoadPathsConfig reads asset and data locations from environment variables.
+// BLOCKY_ASSETS_ZIP reads everything from the official assets.zip.
+// Otherwise BLOCKY_ASSETS_DIR and BLOCKY_DATA_DIR set the extracted
+// directories (default "assets" and "data").
+// BLOCKY_BASE_MODEL, BLOCKY_BASE_TEXTURE, BLOCKY_HEAD_ACCESSORIES_FILE,
+// BLOCKY_HAIRCUTS_FILE, BLOCKY_HAIRCUT_FALLBACKS_FILE and
+// BLOCKY_GRADIENT_SETS_FILE override individual files, and
+// BLOCKY_RULES_FILE sets the optional character rules file.
+// BLOCKY_PACKS loads several asset packs instead, see ParsePacks, and
+// BLOCKY_DEFAULT_PACK picks the default one.
+func LoadPathsConfig() *PathsConfig {
+	return &PathsConfig{
+		Packs:            os.Getenv("BLOCKY_PACKS"),
+		DefaultPack:      os.Getenv("BLOCKY_DEFAULT_PACK"),
+		AssetsZip:        os.Getenv("BLOCKY_ASSETS_ZIP"),
+		AssetsDir:        envString("BLOCKY_ASSETS_DIR", "assets"),
+		DataDir:          envString("BLOCKY_DATA_DIR", "data"),
+		BaseModel:        os.Getenv("BLOCKY_BASE_MODEL"),
+		BaseTexture:      os.Getenv("BLOCKY_BASE_TEXTURE"),
+		HeadAccessories:  os.Getenv("BLOCKY_HEAD_ACCESSORIES_FILE"),
+		Haircuts:         os.Getenv("BLOCKY_HAIRCUTS_FILE"),
+		HaircutFallbacks: os.Getenv("BLOCKY_HAIRCUT_FALLBACKS_FILE"),
+		GradientSets:     os.Getenv("BLOCKY_GRADIENT_SETS_FILE"),
+		Rules:            os.Getenv("BLOCKY_RULES_FILE"),
+	}
+}
+
+// PackConfig names an asset pack: a directory containing assets/ and data/,
+// or a Hytale server assets.zip
+type PackConfig struct {
+	Name string
+	Path string
+}
+
+// ParsePacks parses a comma-separated list of asset packs such as
+// "current=/srv/hytale,2025.01=/srv/assets-2025.01.zip"
+func ParsePacks(s string) ([]PackConfig, error) {
+	var packs []PackConfig
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, path, ok := strings.Cut(entry, "=")
+		name, path = strings.TrimSpace(name), strings.TrimSpace(path)
+		if !ok || name == "" || path == "" {
+			return nil, fmt.Errorf("invalid asset pack %q, expected name=path", entry)
+		}
+		packs = append(packs, PackConfig{Name: name, Path: path})
+	}
+	return packs, nil
+}
+
 func isDisabled(envVar string) bool {
+	return envBool(envVar)
+}
+
+// envInt reads a non-negative integer environment variable, falling back to def
+func envInt(envVar string, def int64) int64 {
+	val, err := strconv.ParseInt(os.Getenv(envVar), 10, 64)
+	if err != nil || val < 0 {
+		return def
+	}
+	return val
+}
+
+// envBool reports whether an environment variable is set to true, 1 or yes
+func envBool(envVar string) bool {
 	val := strings.ToLower(os.Getenv(envVar))
 	return val == "true" || val == "1" || val == "yes"
 }
+
+// envList reads a comma-separated environment variable, skipping empty entries
+func envList(envVar string) []string {
+	var list []string
+	for _, entry := range strings.Split(os.Getenv(envVar), ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// envString returns an environment variable, or def if it is unset or empty
+func envString(envVar, def string) string {
+	if val := os.Getenv(envVar); val != "" {
+		return val
+	}
+	return def
+}
@@ -0,0 +1,500 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"blockyserver/internal/cache"
+	"blockyserver/internal/service"
+)
+
+// Job states
+const (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+const (
+	recordExt = ".json"
+	resultExt = ".result"
+
+	// maxRestarts is how many times a job interrupted while running is
+	// queued again on restart before it fails, so a job that takes the
+	// server down does not do so on every start
+	maxRestarts = 2
+)
+
+var (
+	// ErrQueueFull is returned when the configured number of jobs is waiting
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrNotFound is returned for unknown or expired job IDs
+	ErrNotFound = errors.New("job not found")
+)
+
+var idPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// Job is a queued render. The exported JSON fields are its status as
+// reported to clients and webhooks; the rest is kept on disk only.
+type Job struct {
+	ID        string               `json:"id"`
+	Status    string               `json:"status"`
+	Format    string               `json:"format"`
+	AssetPack string               `json:"assetPack"`
+	Progress  Progress             `json:"progress"`
+	ResultURL string               `json:"resultUrl,omitempty"` // set once done
+	Bytes     int                  `json:"bytes,omitempty"`
+	Warnings  []service.FieldIssue `json:"warnings,omitempty"`
+	Error     string               `json:"error,omitempty"`
+	Fields    []service.FieldIssue `json:"fields,omitempty"` // per-field problems for invalid characters
+	Webhook   string               `json:"webhook,omitempty"`
+	Created   time.Time            `json:"createdAt"`
+	Started   *time.Time           `json:"startedAt,omitempty"`
+	Finished  *time.Time           `json:"finishedAt,omitempty"`
+
+	Request         json.RawMessage `json:"-"` // render request, as accepted by the render endpoints
+	CharacterFormat string          `json:"-"`
+	ContentType     string          `json:"-"`
+	Filename        string          `json:"-"`
+	WebhookPending  bool            `json:"-"` // finished but not yet delivered
+	Restarts        int             `json:"-"` // times the job was interrupted while running
+}
+
+// Progress counts the rendered frames of a job; PNG and GLB jobs have one.
+// Animations go on to quantize (GIF) and encode once every frame is done.
+type Progress struct {
+	Stage   string `json:"stage,omitempty"` // render, quantize or encode
+	Done    int    `json:"done"`
+	Total   int    `json:"total"`
+	Percent int    `json:"percent,omitempty"` // of the encode stage
+}
+
+// record is a job as stored on disk
+type record struct {
+	Job
+	Request         json.RawMessage `json:"request"`
+	CharacterFormat string          `json:"characterFormat,omitempty"`
+	ContentType     string          `json:"contentType,omitempty"`
+	Filename        string          `json:"filename,omitempty"`
+	WebhookPending  bool            `json:"webhookPending,omitempty"`
+	Restarts        int             `json:"restarts,omitempty"`
+}
+
+// Runner renders a job, calling progress as it goes
+type Runner func(job *Job, progress func(Progress)) (*cache.RenderEntry, error)
+
+// Options configures a Queue
+type Options struct {
+	Dir           string        // directory the jobs and results are stored in
+	Workers       int           // jobs rendered at the same time
+	MaxQueued     int           // jobs waiting, further submissions fail with ErrQueueFull
+	TTL           time.Duration // how long finished jobs are kept, 0 keeps them
+	WebhookSecret string        // HMAC key for webhook signatures, empty disables webhooks
+	WebhookHosts  []string      // webhook hosts allowed, empty allows any host with public addresses
+}
+
+// Queue runs render jobs in the background. Jobs are stored in a
+// directory, so queued jobs survive a restart; jobs interrupted while
+// running start over.
+type Queue struct {
+	opts    Options
+	run     Runner
+	webhook *http.Client
+
+	mu       sync.Mutex
+	wake     *sync.Cond
+	jobs     map[string]*Job
+	pending  []string // queued job IDs, oldest first
+	watchers map[string]map[chan struct{}]struct{}
+}
+
+// Open creates dir if needed and loads the jobs stored in it. Jobs are not
+// run until Start is called.
+func Open(opts Options) (*Queue, error) {
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+
+	q := &Queue{
+		opts:     opts,
+		webhook:  newWebhookClient(opts.WebhookHosts),
+		jobs:     make(map[string]*Job),
+		watchers: make(map[string]map[chan struct{}]struct{}),
+	}
+	q.wake = sync.NewCond(&q.mu)
+
+	entries, err := os.ReadDir(opts.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading jobs directory: %w", err)
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasSuffix(name, ".tmp") {
+			// Left over from an interrupted write
+			os.Remove(filepath.Join(opts.Dir, name))
+			continue
+		}
+		if !strings.HasSuffix(name, recordExt) {
+			continue
+		}
+		job, err := readRecord(filepath.Join(opts.Dir, name))
+		if err != nil {
+			log.Printf("jobs: skipping %s: %v", name, err)
+			continue
+		}
+		if job.Status == StatusRunning {
+			q.interrupted(job)
+		}
+		q.jobs[job.ID] = job
+	}
+
+	for _, job := range q.jobs {
+		if job.Status == StatusQueued {
+			q.pending = append(q.pending, job.ID)
+		}
+	}
+	sort.Slice(q.pending, func(i, j int) bool {
+		return q.jobs[q.pending[i]].Created.Before(q.jobs[q.pending[j]].Created)
+	})
+	return q, nil
+}
+
+// interrupted queues a job that was running when the server stopped again,
+// or fails it once it has been interrupted more than maxRestarts times
+func (q *Queue) interrupted(job *Job) {
+	job.Restarts++
+	if job.Restarts > maxRestarts {
+		now := time.Now().UTC()
+		job.Status = StatusFailed
+		job.Error = fmt.Sprintf("job was interrupted %d times while running", job.Restarts)
+		job.Finished = &now
+		job.WebhookPending = job.Webhook != "" && q.WebhooksEnabled()
+	} else {
+		job.Status = StatusQueued
+		job.Progress = Progress{Total: job.Progress.Total}
+		job.Started = nil
+	}
+	if err := q.save(job); err != nil {
+		log.Printf("jobs: saving %s: %v", job.ID, err)
+	}
+}
+
+// Start runs the queued jobs with run and delivers the webhooks left over
+// from before a restart
+func (q *Queue) Start(run Runner) {
+	q.run = run
+	for i := 0; i < q.opts.Workers; i++ {
+		go q.work()
+	}
+
+	q.mu.Lock()
+	for _, job := range q.jobs {
+		if job.WebhookPending {
+			go q.notify(*job)
+		}
+	}
+	q.mu.Unlock()
+
+	if q.opts.TTL > 0 {
+		go q.expire()
+	}
+}
+
+// Count returns the number of jobs per status
+func (q *Queue) Count() map[string]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	count := map[string]int{StatusQueued: 0, StatusRunning: 0, StatusDone: 0, StatusFailed: 0}
+	for _, job := range q.jobs {
+		count[job.Status]++
+	}
+	return count
+}
+
+// WebhooksEnabled reports whether a webhook secret is configured
+func (q *Queue) WebhooksEnabled() bool {
+	return q.opts.WebhookSecret != ""
+}
+
+// Submit assigns the job an ID, stores it and queues it
+func (q *Queue) Submit(job *Job) error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.pending) >= q.opts.MaxQueued {
+		return ErrQueueFull
+	}
+
+	job.ID = id
+	job.Status = StatusQueued
+	job.Created = time.Now().UTC()
+	if err := q.save(job); err != nil {
+		return err
+	}
+
+	stored := *job
+	q.jobs[id] = &stored
+	q.pending = append(q.pending, id)
+	q.wake.Signal()
+	return nil
+}
+
+// Get returns a copy of a job
+func (q *Queue) Get(id string) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return *job, nil
+}
+
+// Watch returns a channel that receives a value whenever a job changes,
+// and a function to stop watching. Changes arriving faster than they are
+// received are merged; Get returns the latest state.
+func (q *Queue) Watch(id string) (<-chan struct{}, func(), error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.jobs[id]; !ok {
+		return nil, nil, ErrNotFound
+	}
+	ch := make(chan struct{}, 1)
+	if q.watchers[id] == nil {
+		q.watchers[id] = make(map[chan struct{}]struct{})
+	}
+	q.watchers[id][ch] = struct{}{}
+
+	stop := func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		delete(q.watchers[id], ch)
+		if len(q.watchers[id]) == 0 {
+			delete(q.watchers, id)
+		}
+	}
+	return ch, stop, nil
+}
+
+// changed wakes the watchers of a job; q.mu must be held
+func (q *Queue) changed(id string) {
+	for ch := range q.watchers[id] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// OpenResult opens the output of a finished job
+func (q *Queue) OpenResult(id string) (*os.File, error) {
+	if !idPattern.MatchString(id) {
+		return nil, ErrNotFound
+	}
+	f, err := os.Open(q.path(id, resultExt))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// work runs queued jobs one at a time
+func (q *Queue) work() {
+	for {
+		q.mu.Lock()
+		for len(q.pending) == 0 {
+			q.wake.Wait()
+		}
+		job := q.jobs[q.pending[0]]
+		q.pending = q.pending[1:]
+
+		now := time.Now().UTC()
+		job.Status = StatusRunning
+		job.Started = &now
+		snapshot := *job
+		if err := q.save(job); err != nil {
+			log.Printf("jobs: saving %s: %v", job.ID, err)
+		}
+		q.changed(job.ID)
+		q.mu.Unlock()
+
+		entry, err := q.runJob(&snapshot, func(progress Progress) {
+			q.mu.Lock()
+			job.Progress = progress
+			q.changed(job.ID)
+			q.mu.Unlock()
+		})
+		q.finish(job, entry, err)
+	}
+}
+
+// runJob runs a job, turning a panic into an error so it fails the job
+// instead of the server
+func (q *Queue) runJob(job *Job, progress func(Progress)) (entry *cache.RenderEntry, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("jobs: %s panicked: %v\n%s", job.ID, r, debug.Stack())
+			entry, err = nil, fmt.Errorf("render panicked: %v", r)
+		}
+	}()
+	return q.run(job, progress)
+}
+
+// finish stores the outcome of a job and sends its webhook
+func (q *Queue) finish(job *Job, entry *cache.RenderEntry, err error) {
+	if err == nil {
+		err = q.writeResult(job.ID, entry.Data)
+	}
+
+	q.mu.Lock()
+	now := time.Now().UTC()
+	job.Finished = &now
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			job.Error = "character has invalid fields"
+			job.Fields = validationErr.Issues
+		}
+	} else {
+		job.Status = StatusDone
+		job.Progress = Progress{Done: job.Progress.Total, Total: job.Progress.Total}
+		job.ResultURL = "/jobs/" + job.ID + "/result"
+		job.Bytes = len(entry.Data)
+		job.ContentType = entry.ContentType
+		job.Filename = entry.Filename
+		json.Unmarshal([]byte(entry.Warnings), &job.Warnings)
+	}
+	job.WebhookPending = job.Webhook != "" && q.WebhooksEnabled()
+	if err := q.save(job); err != nil {
+		log.Printf("jobs: saving %s: %v", job.ID, err)
+	}
+	finished := *job
+	q.changed(job.ID)
+	q.mu.Unlock()
+
+	if finished.WebhookPending {
+		go q.notify(finished)
+	}
+}
+
+// expire removes finished jobs older than the TTL
+func (q *Queue) expire() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		cutoff := time.Now().Add(-q.opts.TTL)
+
+		q.mu.Lock()
+		for id, job := range q.jobs {
+			if job.Finished == nil || job.Finished.After(cutoff) || job.WebhookPending {
+				continue
+			}
+			os.Remove(q.path(id, resultExt))
+			os.Remove(q.path(id, recordExt))
+			delete(q.jobs, id)
+		}
+		q.mu.Unlock()
+	}
+}
+
+// save writes the record of a job, renaming a temporary file into place so
+// a crash never leaves a truncated record
+func (q *Queue) save(job *Job) error {
+	data, err := json.MarshalIndent(record{
+		Job:             *job,
+		Request:         job.Request,
+		CharacterFormat: job.CharacterFormat,
+		ContentType:     job.ContentType,
+		Filename:        job.Filename,
+		WebhookPending:  job.WebhookPending,
+		Restarts:        job.Restarts,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(q.path(job.ID, recordExt), data)
+}
+
+// writeResult stores the output of a job
+func (q *Queue) writeResult(id string, data []byte) error {
+	if err := writeFile(q.path(id, resultExt), data); err != nil {
+		return fmt.Errorf("storing result: %w", err)
+	}
+	return nil
+}
+
+func (q *Queue) path(id, ext string) string {
+	return filepath.Join(q.opts.Dir, id+ext)
+}
+
+// readRecord reads a job stored by save
+func readRecord(path string) (*Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	if !idPattern.MatchString(rec.ID) || filepath.Base(path) != rec.ID+recordExt {
+		return nil, errors.New("job ID does not match the file name")
+	}
+
+	job := rec.Job
+	job.Request = rec.Request
+	job.CharacterFormat = rec.CharacterFormat
+	job.ContentType = rec.ContentType
+	job.Filename = rec.Filename
+	job.WebhookPending = rec.WebhookPending
+	job.Restarts = rec.Restarts
+	return &job, nil
+}
+
+func writeFile(path string, data []byte) error {
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		os.Remove(path + ".tmp")
+		return err
+	}
+	return nil
+}
+
+// newID returns a random job ID, unguessable so results are only
+// readable by whoever submitted the job
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
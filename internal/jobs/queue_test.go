@@ -0,0 +1,228 @@
+package jobs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"blockyserver/internal/cache"
+)
+
+// openQueue opens a queue in dir that accepts up to 10 jobs
+func openQueue(t *testing.T, dir string, opts Options) *Queue {
+	t.Helper()
+	opts.Dir = dir
+	if opts.MaxQueued == 0 {
+		opts.MaxQueued = 10
+	}
+	q, err := Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+// waitFor polls a job until it has the given status
+func waitFor(t *testing.T, q *Queue, id, status string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := q.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is %s, want %s", id, job.Status, status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestQueueReopen(t *testing.T) {
+	started := time.Now().UTC()
+
+	tests := []struct {
+		name     string
+		stored   Job
+		status   string
+		progress Progress
+		pending  bool
+		restarts int
+	}{
+		{"queued stays queued", Job{Status: StatusQueued}, StatusQueued, Progress{}, true, 0},
+		{"running starts over", Job{Status: StatusRunning, Started: &started, Progress: Progress{Stage: "render", Done: 3, Total: 10}},
+			StatusQueued, Progress{Total: 10}, true, 1},
+		{"running again starts over", Job{Status: StatusRunning, Started: &started, Progress: Progress{Total: 10}, Restarts: maxRestarts - 1},
+			StatusQueued, Progress{Total: 10}, true, maxRestarts},
+		{"running too often fails", Job{Status: StatusRunning, Started: &started, Progress: Progress{Total: 10}, Restarts: maxRestarts},
+			StatusFailed, Progress{Total: 10}, false, maxRestarts + 1},
+		{"done is kept", Job{Status: StatusDone, Finished: &started, Progress: Progress{Done: 1, Total: 1}},
+			StatusDone, Progress{Done: 1, Total: 1}, false, 0},
+		{"failed is kept", Job{Status: StatusFailed, Finished: &started, Error: "render failed"},
+			StatusFailed, Progress{}, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			q := openQueue(t, dir, Options{})
+			job := tt.stored
+			job.ID = "0123456789abcdef0123456789abcdef"
+			job.Format = "gif"
+			job.Request = json.RawMessage(`{"character":{}}`)
+			job.Created = started
+			if err := q.save(&job); err != nil {
+				t.Fatal(err)
+			}
+
+			reopened := openQueue(t, dir, Options{})
+			got, err := reopened.Get(job.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tt.status || got.Progress != tt.progress {
+				t.Errorf("got %s %+v, want %s %+v", got.Status, got.Progress, tt.status, tt.progress)
+			}
+			if tt.status == StatusQueued && got.Started != nil {
+				t.Errorf("queued job keeps its start time")
+			}
+			if tt.status == StatusFailed && (got.Finished == nil || got.Error == "") {
+				t.Errorf("failed job has no finish time or error: %+v", got)
+			}
+			// The count is stored, so a crash before the job runs still counts
+			if stored, _ := readRecord(reopened.path(job.ID, recordExt)); stored.Restarts != tt.restarts || got.Restarts != tt.restarts {
+				t.Errorf("got %d restarts, stored %d, want %d", got.Restarts, stored.Restarts, tt.restarts)
+			}
+			var request bytes.Buffer
+			json.Compact(&request, got.Request)
+			if request.String() != string(job.Request) {
+				t.Errorf("got request %s, want %s", got.Request, job.Request)
+			}
+			if pending := len(reopened.pending) == 1; pending != tt.pending {
+				t.Errorf("got pending %v, want %v", pending, tt.pending)
+			}
+		})
+	}
+}
+
+func TestQueueOpenSkipsBadFiles(t *testing.T) {
+	dir := t.TempDir()
+	q := openQueue(t, dir, Options{})
+	job := &Job{Format: "png", Request: json.RawMessage(`{}`)}
+	if err := q.Submit(job); err != nil {
+		t.Fatal(err)
+	}
+
+	// A record under another name, a broken record and a partial write
+	data, _ := os.ReadFile(q.path(job.ID, recordExt))
+	os.WriteFile(filepath.Join(dir, "ffffffffffffffffffffffffffffffff.json"), data, 0o644)
+	os.WriteFile(filepath.Join(dir, "eeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee.json"), []byte("{"), 0o644)
+	os.WriteFile(filepath.Join(dir, job.ID+".json.123.tmp"), []byte("{"), 0o644)
+
+	reopened := openQueue(t, dir, Options{})
+	if got := reopened.Count()[StatusQueued]; got != 1 {
+		t.Errorf("got %d queued jobs, want 1", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, job.ID+".json.123.tmp")); !os.IsNotExist(err) {
+		t.Errorf("temporary file was not removed")
+	}
+}
+
+func TestQueueRunsInOrder(t *testing.T) {
+	dir := t.TempDir()
+	q := openQueue(t, dir, Options{MaxQueued: 3})
+
+	var ids []string
+	for _, format := range []string{"png", "gif", "glb"} {
+		job := &Job{Format: format, Request: json.RawMessage(`{}`)}
+		if err := q.Submit(job); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, job.ID)
+		time.Sleep(time.Millisecond) // distinct creation times
+	}
+	if err := q.Submit(&Job{Format: "png"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("got %v, want ErrQueueFull", err)
+	}
+
+	// Queued jobs survive a restart and keep their order
+	q = openQueue(t, dir, Options{})
+	ran := make(chan string, len(ids))
+	q.Start(func(job *Job, progress func(Progress)) (*cache.RenderEntry, error) {
+		ran <- job.ID
+		if job.Format == "glb" {
+			return nil, errors.New("render failed")
+		}
+		progress(Progress{Stage: "render", Done: 1, Total: 1})
+		return &cache.RenderEntry{ContentType: "image/" + job.Format, Data: []byte(job.ID)}, nil
+	})
+
+	done := waitFor(t, q, ids[0], StatusDone)
+	if done.ResultURL != "/jobs/"+ids[0]+"/result" || done.Bytes != len(ids[0]) || done.Finished == nil {
+		t.Errorf("got %+v, want a finished job with its result", done)
+	}
+	f, err := q.OpenResult(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(f)
+	f.Close()
+	if string(data) != ids[0] {
+		t.Errorf("got result %q, want %q", data, ids[0])
+	}
+
+	waitFor(t, q, ids[1], StatusDone)
+	if failed := waitFor(t, q, ids[2], StatusFailed); failed.Error != "render failed" {
+		t.Errorf("got error %q, want %q", failed.Error, "render failed")
+	}
+	if _, err := q.OpenResult(ids[2]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound for a failed job", err)
+	}
+
+	var order []string
+	for range ids {
+		order = append(order, <-ran)
+	}
+	if !reflect.DeepEqual(order, ids) {
+		t.Errorf("ran %v, want %v", order, ids)
+	}
+
+	// Finished jobs are stored as such
+	reopened := openQueue(t, dir, Options{})
+	if got := reopened.Count(); got[StatusDone] != 2 || got[StatusFailed] != 1 || len(reopened.pending) != 0 {
+		t.Errorf("got %v after reopening, want 2 done and 1 failed", got)
+	}
+}
+
+func TestQueueRecoversPanic(t *testing.T) {
+	q := openQueue(t, t.TempDir(), Options{})
+	q.Start(func(job *Job, progress func(Progress)) (*cache.RenderEntry, error) {
+		if job.Format == "gif" {
+			panic("negative frame count")
+		}
+		return &cache.RenderEntry{ContentType: "image/png", Data: []byte("png")}, nil
+	})
+
+	panicking := &Job{Format: "gif", Request: json.RawMessage(`{}`)}
+	next := &Job{Format: "png", Request: json.RawMessage(`{}`)}
+	for _, job := range []*Job{panicking, next} {
+		if err := q.Submit(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if failed := waitFor(t, q, panicking.ID, StatusFailed); failed.Error != "render panicked: negative frame count" {
+		t.Errorf("got error %q", failed.Error)
+	}
+	// The worker goes on with the next job
+	waitFor(t, q, next.ID, StatusDone)
+}
@@ -0,0 +1,202 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// SignatureHeader carries the HMAC-SHA256 of "<timestamp>.<body>" of a
+// webhook as "sha256=<hex>", keyed with the webhook secret
+const SignatureHeader = "X-Blocky-Signature"
+
+// TimestampHeader carries the Unix time a webhook was sent at. It is part
+// of the signature, so receivers can reject old deliveries being replayed.
+const TimestampHeader = "X-Blocky-Timestamp"
+
+// webhookDelays are the waits before each delivery attempt
+var webhookDelays = []time.Duration{0, 5 * time.Second, 30 * time.Second, 2 * time.Minute}
+
+// ErrWebhookAddress is returned for webhooks that point at the server's own
+// network rather than a public host
+var ErrWebhookAddress = errors.New("webhook host is not a public address")
+
+// blockedPrefixes are ranges that are not public but are not covered by the
+// net.IP checks in publicAddr
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+}
+
+// publicAddr reports whether webhooks may be sent to an address. Loopback,
+// private, link-local (including cloud metadata services), multicast and
+// unspecified addresses are refused.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsMulticast() || addr.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// allowedHost reports whether a host is in the webhook allowlist
+func allowedHost(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidWebhook checks that a webhook URL is an absolute http or https URL
+// of an allowed host. Without an allowlist, every address the host resolves
+// to must be public; listed hosts are trusted wherever they resolve to.
+func (q *Queue) ValidWebhook(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("webhook must be an absolute http or https URL")
+	}
+	host := u.Hostname()
+	if len(q.opts.WebhookHosts) > 0 {
+		if !allowedHost(q.opts.WebhookHosts, host) {
+			return fmt.Errorf("webhook host %s is not allowed", host)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("webhook host %s cannot be resolved", host)
+	}
+	for _, addr := range addrs {
+		if !publicAddr(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrWebhookAddress, host, addr.Unmap())
+		}
+	}
+	return nil
+}
+
+// newWebhookClient returns the client webhooks are delivered with. Hosts
+// outside the allowlist are checked again when connecting, as they may
+// resolve differently than when the job was submitted. Redirects are not
+// followed and proxies are not used, so the checked address is the one
+// that receives the webhook.
+func newWebhookClient(hosts []string) *http.Client {
+	checked := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil || !publicAddr(addr.Addr()) {
+				return fmt.Errorf("%w: %s", ErrWebhookAddress, address)
+			}
+			return nil
+		},
+	}
+	trusted := &net.Dialer{Timeout: 10 * time.Second}
+
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			Proxy: nil,
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				host, _, err := net.SplitHostPort(address)
+				if err == nil && allowedHost(hosts, host) {
+					return trusted.DialContext(ctx, network, address)
+				}
+				return checked.DialContext(ctx, network, address)
+			},
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// sign returns the signature header value of a webhook body sent at timestamp
+func sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// notify POSTs the status of a finished job to its webhook, retrying
+// failed deliveries. Any 2xx response counts as delivered.
+func (q *Queue) notify(job Job) {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return
+	}
+
+	delivered := false
+	for attempt, delay := range webhookDelays {
+		time.Sleep(delay)
+		if err = q.postWebhook(job.Webhook, job.ID, body); err == nil {
+			delivered = true
+			break
+		}
+		log.Printf("jobs: webhook for %s, attempt %d: %v", job.ID, attempt+1, err)
+	}
+	if !delivered {
+		log.Printf("jobs: giving up on the webhook for %s", job.ID)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if stored, ok := q.jobs[job.ID]; ok {
+		stored.WebhookPending = false
+		if err := q.save(stored); err != nil {
+			log.Printf("jobs: saving %s: %v", job.ID, err)
+		}
+	}
+}
+
+// postWebhook sends one delivery, signed with the current time
+func (q *Queue) postWebhook(target, id string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "blockyserver")
+	req.Header.Set("X-Blocky-Job", id)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, sign(q.opts.WebhookSecret, timestamp, body))
+
+	resp, err := q.webhook.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
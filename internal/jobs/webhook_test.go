@@ -0,0 +1,148 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"blockyserver/internal/cache"
+)
+
+func TestValidWebhook(t *testing.T) {
+	tests := []struct {
+		name    string
+		hosts   []string
+		webhook string
+		ok      bool
+	}{
+		{"public address", nil, "https://93.184.216.34/hooks/blocky", true},
+		{"public IPv6 address", nil, "http://[2606:2800:220:1::1]:8080/hook", true},
+		{"not http", nil, "ftp://93.184.216.34/hook", false},
+		{"relative", nil, "/hook", false},
+		{"loopback", nil, "http://127.0.0.1:8080/hook", false},
+		{"loopback name", nil, "http://localhost/hook", false},
+		{"IPv6 loopback", nil, "http://[::1]/hook", false},
+		{"IPv4-mapped loopback", nil, "http://[::ffff:127.0.0.1]/hook", false},
+		{"private", nil, "http://10.0.0.5/hook", false},
+		{"private IPv6", nil, "http://[fd00::1]/hook", false},
+		{"metadata service", nil, "http://169.254.169.254/latest/meta-data", false},
+		{"unspecified", nil, "http://0.0.0.0/hook", false},
+		{"carrier-grade NAT", nil, "http://100.64.0.1/hook", false},
+		{"allowed internal host", []string{"hooks.internal"}, "http://Hooks.Internal:9000/done", true},
+		{"allowed loopback", []string{"127.0.0.1"}, "http://127.0.0.1/hook", true},
+		{"host not allowed", []string{"hooks.internal"}, "https://93.184.216.34/hook", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &Queue{opts: Options{WebhookHosts: tt.hosts}}
+			err := q.ValidWebhook(context.Background(), tt.webhook)
+			if (err == nil) != tt.ok {
+				t.Errorf("got %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	const secret = "s3cret"
+	type delivery struct {
+		header http.Header
+		body   []byte
+	}
+	received := make(chan delivery, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- delivery{r.Header, body}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	q := openQueue(t, t.TempDir(), Options{WebhookSecret: secret, WebhookHosts: []string{u.Hostname()}})
+	if err := q.ValidWebhook(context.Background(), server.URL); err != nil {
+		t.Fatal(err)
+	}
+	job := &Job{Format: "png", Webhook: server.URL + "/done", Request: json.RawMessage(`{}`)}
+	if err := q.Submit(job); err != nil {
+		t.Fatal(err)
+	}
+	q.Start(func(*Job, func(Progress)) (*cache.RenderEntry, error) {
+		return &cache.RenderEntry{ContentType: "image/png", Data: []byte("png")}, nil
+	})
+
+	var d delivery
+	select {
+	case d = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+
+	timestamp := d.header.Get(TimestampHeader)
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(sent, 0)).Abs() > time.Minute {
+		t.Errorf("got timestamp %q, want the current time", timestamp)
+	}
+	if got, want := d.header.Get(SignatureHeader), sign(secret, timestamp, d.body); got != want {
+		t.Errorf("got signature %s, want %s", got, want)
+	}
+	if got := sign(secret, strconv.FormatInt(sent-600, 10), d.body); got == d.header.Get(SignatureHeader) {
+		t.Error("signature does not depend on the timestamp")
+	}
+	var status Job
+	if err := json.Unmarshal(d.body, &status); err != nil || status.ID != job.ID || status.Status != StatusDone {
+		t.Errorf("got body %s, want the finished job", d.body)
+	}
+
+	// The delivery is recorded so it is not repeated after a restart
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if stored, _ := q.Get(job.ID); !stored.WebhookPending {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("webhook still pending after delivery")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebhookDialCheck(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	// A host that resolved to a public address when the job was submitted
+	// may point at the server's network by the time the webhook is sent
+	q := &Queue{webhook: newWebhookClient(nil)}
+	err := q.postWebhook(server.URL, "0123456789abcdef0123456789abcdef", []byte(`{}`))
+	if !errors.Is(err, ErrWebhookAddress) {
+		t.Errorf("got %v, want ErrWebhookAddress", err)
+	}
+	if hits != 0 {
+		t.Errorf("webhook reached the server")
+	}
+}
+
+func TestWebhookRedirectNotFollowed(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("redirect was followed")
+	}))
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+
+	u, _ := url.Parse(redirect.URL)
+	q := &Queue{webhook: newWebhookClient([]string{u.Hostname()})}
+	if err := q.postWebhook(redirect.URL, "0123456789abcdef0123456789abcdef", []byte(`{}`)); err == nil {
+		t.Error("got no error for a redirect")
+	}
+}
@@ -0,0 +1,127 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+
+	"github.com/fogleman/fauxgl"
+	"github.com/hytale-tools/blockymodel-merger/pkg/texture"
+)
+
+// RenderAtlas encodes the packed atlas as PNG, optionally drawing the UV
+// layout of every face of the model on top as a wireframe
+func RenderAtlas(glbBytes []byte, atlas *texture.Atlas, wireframe bool, wireColor string) ([]byte, error) {
+	if atlas == nil {
+		return nil, fmt.Errorf("character has no textures")
+	}
+
+	img := image.NewRGBA(atlas.Image.Bounds())
+	draw.Draw(img, img.Bounds(), atlas.Image, atlas.Image.Bounds().Min, draw.Src)
+
+	if wireframe {
+		lineColor, err := ParseHexColor(wireColor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid wireframe color: %w", err)
+		}
+
+		// Convert GLB to mesh
+		mesh, err := GLBToMesh(glbBytes, atlas.Image)
+		if err != nil {
+			return nil, fmt.Errorf("converting GLB to mesh: %w", err)
+		}
+
+		DrawUVWireframe(img, mesh, lineColor)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("encoding PNG: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// DrawUVWireframe draws the texture-space outline of every triangle in the mesh.
+// The diagonal of right triangles is skipped so box faces show up as rectangles.
+func DrawUVWireframe(img *image.RGBA, mesh *fauxgl.Mesh, lineColor color.Color) {
+	bounds := img.Bounds()
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+
+	// Mesh texture coordinates have a bottom-left origin
+	toPixel := func(uv fauxgl.Vector) fauxgl.Vector {
+		return fauxgl.V(uv.X*w, (1-uv.Y)*h, 0)
+	}
+
+	for _, tri := range mesh.Triangles {
+		p := [3]fauxgl.Vector{toPixel(tri.V1.Texture), toPixel(tri.V2.Texture), toPixel(tri.V3.Texture)}
+
+		skip := -1
+		for i := 0; i < 3; i++ {
+			a := p[(i+1)%3].Sub(p[i])
+			b := p[(i+2)%3].Sub(p[i])
+			if a.Length() > 0 && b.Length() > 0 && math.Abs(a.Normalize().Dot(b.Normalize())) < 1e-6 {
+				// Right angle at vertex i: the opposite edge is the diagonal
+				skip = (i + 1) % 3
+				break
+			}
+		}
+
+		for i := 0; i < 3; i++ {
+			if i == skip {
+				continue
+			}
+			drawLine(img, p[i], p[(i+1)%3], lineColor)
+		}
+	}
+}
+
+// drawLine draws a one-pixel line between two points using Bresenham's algorithm
+func drawLine(img *image.RGBA, from, to fauxgl.Vector, c color.Color) {
+	x0, y0 := int(math.Floor(from.X)), int(math.Floor(from.Y))
+	x1, y1 := int(math.Floor(to.X)), int(math.Floor(to.Y))
+
+	bounds := img.Bounds()
+	x0 = clampInt(x0, bounds.Min.X, bounds.Max.X-1)
+	x1 = clampInt(x1, bounds.Min.X, bounds.Max.X-1)
+	y0 = clampInt(y0, bounds.Min.Y, bounds.Max.Y-1)
+	y1 = clampInt(y1, bounds.Min.Y, bounds.Max.Y-1)
+
+	dx := absInt(x1 - x0)
+	dy := -absInt(y1 - y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+
+	err := dx + dy
+	for {
+		img.Set(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
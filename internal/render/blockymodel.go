@@ -0,0 +1,34 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hytale-tools/blockymodel-merger/pkg/blockymodel"
+	"github.com/hytale-tools/blockymodel-merger/pkg/texture"
+)
+
+// RenderBlockyModel packs a merged model as native .blockymodel JSON together
+// with its atlas texture into a ZIP archive. Texture layout offsets in the
+// model already point into the atlas.
+func RenderBlockyModel(model *blockymodel.BlockyModel, atlas *texture.Atlas) ([]byte, error) {
+	// Same formatting as blockymodel.Save
+	modelBytes, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding model: %w", err)
+	}
+
+	files := []ArchiveFile{
+		{Name: "character.blockymodel", Data: modelBytes},
+	}
+
+	if atlas != nil {
+		atlasBytes, err := texture.EncodePNG(atlas.Image)
+		if err != nil {
+			return nil, fmt.Errorf("encoding atlas: %w", err)
+		}
+		files = append(files, ArchiveFile{Name: textureFileName, Data: atlasBytes})
+	}
+
+	return BuildZip(files)
+}
@@ -13,8 +13,10 @@ import (
 	"github.com/hytale-tools/blockymodel-merger/pkg/texture"
 )
 
-// RenderGIF renders a GLB model to an animated GIF rotating 360 degrees
-func RenderGIF(glbBytes []byte, atlas *texture.Atlas, background string, frames, width, height, delay int, dithering, autoZoom bool) ([]byte, error) {
+// RenderGIF renders a GLB model to an animated GIF rotating 360 degrees.
+// progress, if not nil, receives each rendered frame and the start of
+// quantization and encoding, and may stop the render.
+func RenderGIF(glbBytes []byte, atlas *texture.Atlas, background string, frames, width, height, delay int, dithering, autoZoom bool, progress Progress) ([]byte, error) {
 	// Parse background color
 	bgColor, err := ParseHexColor(background)
 	if err != nil {
@@ -35,18 +37,27 @@ func RenderGIF(glbBytes []byte, atlas *texture.Atlas, background string, frames,
 
 	// Render all frames first (in parallel)
 	renderedFrames := make([]image.Image, frames)
+	reporter := newProgressReporter(progress, frames)
 	var wg sync.WaitGroup
 	for i := 0; i < frames; i++ {
 		wg.Add(1)
 		go func(frameIdx int) {
 			defer wg.Done()
+			if reporter.stopped() != nil {
+				return
+			}
 			rotation := float64(frameIdx) * rotationPerFrame
 			renderedFrames[frameIdx] = RenderScene(mesh, atlasImage, rotation, width, height, bgColor, autoZoom)
+			reporter.frameDone()
 		}(i)
 	}
 	wg.Wait()
 
 	// Determine palette
+	reporter.stage(StageQuantize)
+	if err := reporter.stopped(); err != nil {
+		return nil, err
+	}
 	var pal color.Palette
 	if dithering {
 		pal = palette.Plan9
@@ -80,10 +91,15 @@ func RenderGIF(glbBytes []byte, atlas *texture.Atlas, background string, frames,
 	wg.Wait()
 
 	// Encode GIF
+	reporter.stage(StageEncode)
+	if err := reporter.stopped(); err != nil {
+		return nil, err
+	}
 	var buf bytes.Buffer
 	if err := gif.EncodeAll(&buf, g); err != nil {
 		return nil, fmt.Errorf("encoding GIF: %w", err)
 	}
+	reporter.encoded(100)
 
 	return buf.Bytes(), nil
 }
@@ -1,18 +1,25 @@
 package render
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
 	"image/png"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/hytale-tools/blockymodel-merger/pkg/texture"
 )
 
-// RenderMP4 renders a GLB model to an MP4 video rotating 360 degrees
-func RenderMP4(glbBytes []byte, atlas *texture.Atlas, background string, frames, width, height, fps int, autoZoom bool) ([]byte, error) {
+// RenderMP4 renders a GLB model to an MP4 video rotating 360 degrees.
+// progress, if not nil, receives each rendered frame and the encoding
+// percentage reported by FFmpeg, and may stop the render.
+func RenderMP4(glbBytes []byte, atlas *texture.Atlas, background string, frames, width, height, fps int, autoZoom bool, progress Progress) ([]byte, error) {
 	// Parse background color
 	bgColor, err := ParseHexColor(background)
 	if err != nil {
@@ -41,11 +48,15 @@ func RenderMP4(glbBytes []byte, atlas *texture.Atlas, background string, frames,
 	// Render all frames in parallel
 	var wg sync.WaitGroup
 	errChan := make(chan error, frames)
+	reporter := newProgressReporter(progress, frames)
 
 	for i := 0; i < frames; i++ {
 		wg.Add(1)
 		go func(frameIdx int) {
 			defer wg.Done()
+			if reporter.stopped() != nil {
+				return
+			}
 			rotation := float64(frameIdx) * rotationPerFrame
 			img := RenderScene(mesh, atlasImage, rotation, width, height, bgColor, autoZoom)
 
@@ -62,6 +73,7 @@ func RenderMP4(glbBytes []byte, atlas *texture.Atlas, background string, frames,
 				errChan <- fmt.Errorf("encoding frame PNG: %w", err)
 				return
 			}
+			reporter.frameDone()
 		}(i)
 	}
 	wg.Wait()
@@ -73,6 +85,9 @@ func RenderMP4(glbBytes []byte, atlas *texture.Atlas, background string, frames,
 			return nil, err
 		}
 	}
+	if err := reporter.stopped(); err != nil {
+		return nil, err
+	}
 
 	// Run FFmpeg to encode MP4
 	outputPath := filepath.Join(tempDir, "output.mp4")
@@ -80,6 +95,8 @@ func RenderMP4(glbBytes []byte, atlas *texture.Atlas, background string, frames,
 
 	cmd := exec.Command("ffmpeg",
 		"-y",
+		"-nostats",
+		"-progress", "pipe:1",
 		"-framerate", fmt.Sprintf("%d", fps),
 		"-i", inputPattern,
 		"-c:v", "libx264",
@@ -87,9 +104,25 @@ func RenderMP4(glbBytes []byte, atlas *texture.Atlas, background string, frames,
 		"-movflags", "+faststart",
 		outputPath,
 	)
+	var output bytes.Buffer
+	cmd.Stderr = &output
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("starting ffmpeg: %w", err)
+	}
 
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return nil, fmt.Errorf("ffmpeg encoding failed: %w\nOutput: %s", err, string(output))
+	reporter.stage(StageEncode)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("ffmpeg encoding failed: %w", err)
+	}
+	readFFmpegProgress(stdout, frames, reporter)
+	if err := reporter.stopped(); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("ffmpeg encoding failed: %w\nOutput: %s", err, output.String())
 	}
 
 	// Read output file
@@ -100,3 +133,23 @@ func RenderMP4(glbBytes []byte, atlas *texture.Atlas, background string, frames,
 
 	return mp4Bytes, nil
 }
+
+// readFFmpegProgress reads the key=value lines FFmpeg writes with -progress
+// until it exits or the render is stopped, reporting the share of frames
+// encoded
+func readFFmpegProgress(r io.Reader, frames int, reporter *progressReporter) {
+	scanner := bufio.NewScanner(r)
+	for reporter.stopped() == nil && scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		switch key {
+		case "frame":
+			if n, err := strconv.Atoi(value); err == nil && frames > 0 {
+				reporter.encoded(n * 100 / frames)
+			}
+		case "progress":
+			if value == "end" {
+				reporter.encoded(100)
+			}
+		}
+	}
+}
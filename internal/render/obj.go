@@ -0,0 +1,141 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+
+	"github.com/fogleman/fauxgl"
+	"github.com/hytale-tools/blockymodel-merger/pkg/texture"
+)
+
+const (
+	objFileName     = "character.obj"
+	mtlFileName     = "character.mtl"
+	textureFileName = "character.png"
+)
+
+// RenderOBJ converts a GLB model to a ZIP archive containing a Wavefront OBJ,
+// its MTL material file and the atlas texture as PNG
+func RenderOBJ(glbBytes []byte, atlas *texture.Atlas) ([]byte, error) {
+	var atlasImage image.Image
+	if atlas != nil {
+		atlasImage = atlas.Image
+	}
+
+	// Convert GLB to mesh
+	mesh, err := GLBToMesh(glbBytes, atlasImage)
+	if err != nil {
+		return nil, fmt.Errorf("converting GLB to mesh: %w", err)
+	}
+
+	files := []ArchiveFile{
+		{Name: objFileName, Data: encodeOBJ(mesh, atlasImage != nil)},
+		{Name: mtlFileName, Data: encodeMTL(atlasImage != nil)},
+	}
+
+	if atlasImage != nil {
+		atlasBytes, err := texture.EncodePNG(atlasImage)
+		if err != nil {
+			return nil, fmt.Errorf("encoding atlas: %w", err)
+		}
+		files = append(files, ArchiveFile{Name: textureFileName, Data: atlasBytes})
+	}
+
+	return BuildZip(files)
+}
+
+// encodeOBJ writes the mesh as an indexed OBJ, sharing identical positions,
+// texture coordinates and normals between faces
+func encodeOBJ(mesh *fauxgl.Mesh, textured bool) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Exported by BlockyServer\n")
+	fmt.Fprintf(&buf, "mtllib %s\n", mtlFileName)
+	fmt.Fprintf(&buf, "o character\n")
+
+	positions := newVectorIndex()
+	uvs := newVectorIndex()
+	normals := newVectorIndex()
+
+	type faceRef struct{ v, vt, vn [3]int }
+	faces := make([]faceRef, 0, len(mesh.Triangles))
+
+	for _, tri := range mesh.Triangles {
+		var f faceRef
+		for i, v := range []fauxgl.Vertex{tri.V1, tri.V2, tri.V3} {
+			f.v[i] = positions.add(v.Position)
+			f.vt[i] = uvs.add(fauxgl.V(v.Texture.X, v.Texture.Y, 0))
+			f.vn[i] = normals.add(v.Normal)
+		}
+		faces = append(faces, f)
+	}
+
+	for _, p := range positions.values {
+		fmt.Fprintf(&buf, "v %.6f %.6f %.6f\n", p.X, p.Y, p.Z)
+	}
+	if textured {
+		// Mesh texture coordinates are already flipped to a bottom-left origin
+		for _, t := range uvs.values {
+			fmt.Fprintf(&buf, "vt %.6f %.6f\n", t.X, t.Y)
+		}
+	}
+	for _, n := range normals.values {
+		fmt.Fprintf(&buf, "vn %.6f %.6f %.6f\n", n.X, n.Y, n.Z)
+	}
+
+	fmt.Fprintf(&buf, "usemtl character\n")
+	for _, f := range faces {
+		if textured {
+			fmt.Fprintf(&buf, "f %d/%d/%d %d/%d/%d %d/%d/%d\n",
+				f.v[0], f.vt[0], f.vn[0],
+				f.v[1], f.vt[1], f.vn[1],
+				f.v[2], f.vt[2], f.vn[2])
+		} else {
+			fmt.Fprintf(&buf, "f %d//%d %d//%d %d//%d\n",
+				f.v[0], f.vn[0],
+				f.v[1], f.vn[1],
+				f.v[2], f.vn[2])
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// encodeMTL writes a single unlit material referencing the atlas texture
+func encodeMTL(textured bool) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Exported by BlockyServer\n")
+	fmt.Fprintf(&buf, "newmtl character\n")
+	fmt.Fprintf(&buf, "Ka 1.000000 1.000000 1.000000\n")
+	fmt.Fprintf(&buf, "Kd 1.000000 1.000000 1.000000\n")
+	fmt.Fprintf(&buf, "Ks 0.000000 0.000000 0.000000\n")
+	fmt.Fprintf(&buf, "d 1.000000\n")
+	fmt.Fprintf(&buf, "illum 1\n")
+	if textured {
+		fmt.Fprintf(&buf, "map_Kd %s\n", textureFileName)
+		fmt.Fprintf(&buf, "map_d %s\n", textureFileName)
+	}
+	return buf.Bytes()
+}
+
+// vectorIndex assigns 1-based OBJ indices to unique vectors
+type vectorIndex struct {
+	indices map[fauxgl.Vector]int
+	values  []fauxgl.Vector
+}
+
+func newVectorIndex() *vectorIndex {
+	return &vectorIndex{indices: make(map[fauxgl.Vector]int)}
+}
+
+func (vi *vectorIndex) add(v fauxgl.Vector) int {
+	// Round to avoid near-duplicates from floating point transforms
+	v = v.RoundPlaces(6)
+	if idx, ok := vi.indices[v]; ok {
+		return idx
+	}
+	vi.values = append(vi.values, v)
+	idx := len(vi.values)
+	vi.indices[v] = idx
+	return idx
+}
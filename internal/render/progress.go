@@ -0,0 +1,88 @@
+package render
+
+import "sync"
+
+// Stages of an animation render
+const (
+	StageRender   = "render"   // frames are being rendered
+	StageQuantize = "quantize" // GIF frames are being reduced to a palette
+	StageEncode   = "encode"   // frames are being encoded into the GIF or MP4
+)
+
+// ProgressEvent reports how far an animation render has got
+type ProgressEvent struct {
+	Stage   string
+	Done    int // frames rendered
+	Total   int // frames in the animation
+	Percent int // of the encode stage, from FFmpeg's progress output for MP4
+}
+
+// Progress receives the events of a render. Calls are serialized and Done
+// and Percent only increase. Returning an error stops the render, which
+// then fails with that error, e.g. once its request is cancelled.
+type Progress func(ProgressEvent) error
+
+// progressReporter sends the events of one render to a Progress, which may
+// be nil
+type progressReporter struct {
+	mu       sync.Mutex
+	event    ProgressEvent
+	progress Progress
+	stopErr  error // first error returned by progress
+}
+
+func newProgressReporter(progress Progress, frames int) *progressReporter {
+	return &progressReporter{event: ProgressEvent{Stage: StageRender, Total: frames}, progress: progress}
+}
+
+// frameDone records a rendered frame
+func (p *progressReporter) frameDone() {
+	p.update(func(e *ProgressEvent) bool {
+		e.Done++
+		return true
+	})
+}
+
+// stage starts a stage after the frames are rendered
+func (p *progressReporter) stage(stage string) {
+	p.update(func(e *ProgressEvent) bool {
+		e.Stage = stage
+		return true
+	})
+}
+
+// encoded records the encoding percentage, ignoring values that go back
+func (p *progressReporter) encoded(percent int) {
+	if percent > 100 {
+		percent = 100
+	}
+	p.update(func(e *ProgressEvent) bool {
+		if percent <= e.Percent {
+			return false
+		}
+		e.Stage, e.Percent = StageEncode, percent
+		return true
+	})
+}
+
+// update changes the event and sends it if fn reports a change
+func (p *progressReporter) update(fn func(*ProgressEvent) bool) {
+	if p.progress == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopErr == nil && fn(&p.event) {
+		p.stopErr = p.progress(p.event)
+	}
+}
+
+// stopped returns the error that stopped the render, nil while it may go on
+func (p *progressReporter) stopped() error {
+	if p.progress == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stopErr
+}
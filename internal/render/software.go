@@ -38,28 +38,8 @@ func GLBToMesh(glbBytes []byte, atlasImage image.Image) (*fauxgl.Mesh, error) {
 func processNode(doc *gltf.Document, nodeIdx int, parentTransform fauxgl.Matrix, mesh *fauxgl.Mesh, atlasImage image.Image) error {
 	node := doc.Nodes[nodeIdx]
 
-	// Build local transform in TRS order: Translation * Rotation * Scale
-	localTransform := fauxgl.Identity()
-
-	// Apply scale first (rightmost in matrix multiplication)
-	if node.Scale != [3]float64{1, 1, 1} && node.Scale != [3]float64{0, 0, 0} {
-		localTransform = fauxgl.Scale(fauxgl.V(node.Scale[0], node.Scale[1], node.Scale[2])).Mul(localTransform)
-	}
-
-	// Apply rotation
-	if node.Rotation != [4]float64{0, 0, 0, 1} {
-		qx, qy, qz, qw := node.Rotation[0], node.Rotation[1], node.Rotation[2], node.Rotation[3]
-		R := quaternionToMatrix(qx, qy, qz, qw)
-		localTransform = R.Mul(localTransform)
-	}
-
-	// Apply translation last (leftmost)
-	if node.Translation != [3]float64{0, 0, 0} {
-		localTransform = fauxgl.Translate(fauxgl.V(node.Translation[0], node.Translation[1], node.Translation[2])).Mul(localTransform)
-	}
-
 	// World transform = parent * local
-	worldTransform := parentTransform.Mul(localTransform)
+	worldTransform := parentTransform.Mul(nodeTransform(node))
 
 	// Process mesh if present
 	if node.Mesh != nil {
@@ -81,6 +61,31 @@ func processNode(doc *gltf.Document, nodeIdx int, parentTransform fauxgl.Matrix,
 	return nil
 }
 
+// nodeTransform returns the local transform of a node
+func nodeTransform(node *gltf.Node) fauxgl.Matrix {
+	// Build local transform in TRS order: Translation * Rotation * Scale
+	localTransform := fauxgl.Identity()
+
+	// Apply scale first (rightmost in matrix multiplication)
+	if node.Scale != [3]float64{1, 1, 1} && node.Scale != [3]float64{0, 0, 0} {
+		localTransform = fauxgl.Scale(fauxgl.V(node.Scale[0], node.Scale[1], node.Scale[2])).Mul(localTransform)
+	}
+
+	// Apply rotation
+	if node.Rotation != [4]float64{0, 0, 0, 1} {
+		qx, qy, qz, qw := node.Rotation[0], node.Rotation[1], node.Rotation[2], node.Rotation[3]
+		R := quaternionToMatrix(qx, qy, qz, qw)
+		localTransform = R.Mul(localTransform)
+	}
+
+	// Apply translation last (leftmost)
+	if node.Translation != [3]float64{0, 0, 0} {
+		localTransform = fauxgl.Translate(fauxgl.V(node.Translation[0], node.Translation[1], node.Translation[2])).Mul(localTransform)
+	}
+
+	return localTransform
+}
+
 func processPrimitive(doc *gltf.Document, prim *gltf.Primitive, transform fauxgl.Matrix, mesh *fauxgl.Mesh, atlasImage image.Image) error {
 	// Get position accessor
 	posAccessorIdx, ok := prim.Attributes[gltf.POSITION]
@@ -0,0 +1,458 @@
+package render
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/fogleman/fauxgl"
+	"github.com/qmuntal/gltf"
+)
+
+// RenderSTL converts a GLB model to a binary STL ready for 3D printing.
+// Each shape is welded into closed shells on its own, rotated to Z-up and
+// scaled so the model is heightMM millimetres tall. If basePlate is set, a
+// rectangular plate of the given thickness is added under the feet, extending
+// marginMM past the model. The names of shapes that are not closed solids,
+// such as single planes, are returned as they are left out of the print.
+func RenderSTL(glbBytes []byte, heightMM float64, basePlate bool, baseThicknessMM, baseMarginMM float64) ([]byte, []string, error) {
+	if heightMM <= 0 {
+		return nil, nil, fmt.Errorf("height must be positive")
+	}
+
+	parts, err := glbParts(glbBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("converting GLB to mesh: %w", err)
+	}
+
+	solid, skipped := weldParts(parts)
+	if len(solid.tris) == 0 {
+		return nil, skipped, fmt.Errorf("model has no closed geometry to print")
+	}
+
+	// Convert from glTF Y-up to the Z-up convention used by slicers
+	for i, v := range solid.verts {
+		solid.verts[i] = fauxgl.V(v.X, -v.Z, v.Y)
+	}
+
+	// Scale to the requested height, centre on XY and rest on Z=0
+	min, max := solid.bounds()
+	if max.Z-min.Z <= 0 {
+		return nil, skipped, fmt.Errorf("model is flat and cannot be scaled to a height")
+	}
+	scale := heightMM / (max.Z - min.Z)
+	center := fauxgl.V((min.X+max.X)/2, (min.Y+max.Y)/2, min.Z)
+	for i, v := range solid.verts {
+		solid.verts[i] = v.Sub(center).MulScalar(scale)
+	}
+
+	if basePlate {
+		min, max = solid.bounds()
+		plateMin := fauxgl.V(min.X-baseMarginMM, min.Y-baseMarginMM, -baseThicknessMM)
+		plateMax := fauxgl.V(max.X+baseMarginMM, max.Y+baseMarginMM, 0)
+		solid.addBox(plateMin, plateMax)
+
+		// Lift everything so the plate rests on Z=0
+		for i, v := range solid.verts {
+			solid.verts[i] = v.Add(fauxgl.V(0, 0, baseThicknessMM))
+		}
+	}
+
+	return solid.encodeBinarySTL(), skipped, nil
+}
+
+// meshPart is the geometry of one shape of a model
+type meshPart struct {
+	name string
+	mesh *fauxgl.Mesh
+}
+
+// glbParts returns the primitives of a GLB model as separate meshes, so
+// shapes that touch are not welded into one shell
+func glbParts(glbBytes []byte) ([]meshPart, error) {
+	doc := new(gltf.Document)
+	if err := gltf.NewDecoder(bytes.NewReader(glbBytes)).Decode(doc); err != nil {
+		return nil, fmt.Errorf("parsing GLB: %w", err)
+	}
+	if len(doc.Scenes) == 0 || len(doc.Scenes[0].Nodes) == 0 {
+		return nil, fmt.Errorf("GLB has no scene nodes")
+	}
+
+	var parts []meshPart
+	var walk func(nodeIdx int, parentTransform fauxgl.Matrix) error
+	walk = func(nodeIdx int, parentTransform fauxgl.Matrix) error {
+		node := doc.Nodes[nodeIdx]
+		worldTransform := parentTransform.Mul(nodeTransform(node))
+		if node.Mesh != nil {
+			for _, prim := range doc.Meshes[*node.Mesh].Primitives {
+				mesh := fauxgl.NewEmptyMesh()
+				if err := processPrimitive(doc, prim, worldTransform, mesh, nil); err != nil {
+					return err
+				}
+				parts = append(parts, meshPart{name: node.Name, mesh: mesh})
+			}
+		}
+		for _, childIdx := range node.Children {
+			if err := walk(int(childIdx), worldTransform); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, nodeIdx := range doc.Scenes[0].Nodes {
+		if err := walk(int(nodeIdx), fauxgl.Identity()); err != nil {
+			return nil, err
+		}
+	}
+
+	return parts, nil
+}
+
+// weldParts welds each part on its own and combines the closed shells into
+// one mesh. Touching shapes stay separate shells, which slicers join. The
+// names of parts that lost geometry because it was not closed are returned.
+func weldParts(parts []meshPart) (*weldedMesh, []string) {
+	solid := &weldedMesh{}
+	var skipped []string
+	for _, part := range parts {
+		if len(part.mesh.Triangles) == 0 {
+			continue
+		}
+		wm, dropped := weldMesh(part.mesh)
+		if dropped > 0 || len(wm.tris) == 0 {
+			skipped = append(skipped, part.name)
+		}
+
+		base := len(solid.verts)
+		solid.verts = append(solid.verts, wm.verts...)
+		for _, t := range wm.tris {
+			solid.tris = append(solid.tris, [3]int{base + t[0], base + t[1], base + t[2]})
+		}
+	}
+	return solid, skipped
+}
+
+// weldedMesh is an indexed triangle mesh with shared vertices
+type weldedMesh struct {
+	verts []fauxgl.Vector
+	tris  [][3]int
+}
+
+// weldMesh merges coincident vertices, removes degenerate and cancelling
+// faces, drops shells that are not closed and orients every shell outwards.
+// It also returns the number of shells dropped.
+func weldMesh(mesh *fauxgl.Mesh) (*weldedMesh, int) {
+	wm := &weldedMesh{}
+	index := make(map[fauxgl.Vector]int)
+
+	weld := func(v fauxgl.Vector) int {
+		v = v.RoundPlaces(5)
+		if idx, ok := index[v]; ok {
+			return idx
+		}
+		idx := len(wm.verts)
+		wm.verts = append(wm.verts, v)
+		index[v] = idx
+		return idx
+	}
+
+	// Faces sharing the same three vertices cancel out when wound in opposite
+	// directions (touching boxes, zero-thickness planes) and collapse to one
+	// face when wound the same way.
+	type faceCount struct {
+		tri   [3]int
+		count int
+	}
+	faces := make(map[[3]int]*faceCount)
+	var order [][3]int
+
+	for _, t := range mesh.Triangles {
+		tri := [3]int{weld(t.V1.Position), weld(t.V2.Position), weld(t.V3.Position)}
+		if tri[0] == tri[1] || tri[1] == tri[2] || tri[0] == tri[2] {
+			continue
+		}
+		e1 := wm.verts[tri[1]].Sub(wm.verts[tri[0]])
+		e2 := wm.verts[tri[2]].Sub(wm.verts[tri[0]])
+		if e1.Cross(e2).Length() < 1e-12 {
+			continue
+		}
+
+		key, even := sortedTriangle(tri)
+		fc, ok := faces[key]
+		if !ok {
+			fc = &faceCount{tri: tri}
+			faces[key] = fc
+			order = append(order, key)
+		}
+		if even == isEvenTriangle(fc.tri) {
+			fc.count++
+		} else {
+			fc.count--
+		}
+	}
+
+	var tris [][3]int
+	for _, key := range order {
+		fc := faces[key]
+		switch {
+		case fc.count > 0:
+			tris = append(tris, fc.tri)
+		case fc.count < 0:
+			tris = append(tris, [3]int{fc.tri[0], fc.tri[2], fc.tri[1]})
+		}
+	}
+
+	var dropped int
+	wm.tris, dropped = wm.closedShells(tris)
+	return wm, dropped
+}
+
+// closedShells groups triangles into connected shells, keeps only the shells
+// where every edge is shared by exactly two faces (closing boxes with missing
+// faces), and flips inward-facing shells. It also returns the number of
+// shells dropped.
+func (wm *weldedMesh) closedShells(tris [][3]int) ([][3]int, int) {
+	parent := make([]int, len(wm.verts))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	for _, t := range tris {
+		a, b, c := find(t[0]), find(t[1]), find(t[2])
+		parent[b] = a
+		parent[find(c)] = a
+	}
+
+	shells := make(map[int][][3]int)
+	var roots []int
+	for _, t := range tris {
+		root := find(t[0])
+		if _, ok := shells[root]; !ok {
+			roots = append(roots, root)
+		}
+		shells[root] = append(shells[root], t)
+	}
+
+	var result [][3]int
+	dropped := 0
+	for _, root := range roots {
+		shell := shells[root]
+
+		edges := make(map[[2]int]int)
+		for _, t := range shell {
+			for i := 0; i < 3; i++ {
+				a, b := t[i], t[(i+1)%3]
+				if a > b {
+					a, b = b, a
+				}
+				edges[[2]int{a, b}]++
+			}
+		}
+		closed := true
+		for _, n := range edges {
+			if n != 2 {
+				closed = false
+				break
+			}
+		}
+		if !closed {
+			// Boxes with untextured faces are exported without those faces;
+			// rebuild the full box from its corners, drop anything else
+			box, ok := wm.rebuildBox(shell)
+			if !ok {
+				dropped++
+				continue
+			}
+			shell = box
+		}
+
+		// Negative signed volume means the shell is wound inside out
+		// (e.g. geometry mirrored with a negative stretch)
+		var volume float64
+		for _, t := range shell {
+			volume += wm.verts[t[0]].Dot(wm.verts[t[1]].Cross(wm.verts[t[2]]))
+		}
+		for _, t := range shell {
+			if volume < 0 {
+				t[1], t[2] = t[2], t[1]
+			}
+			result = append(result, t)
+		}
+	}
+
+	return result, dropped
+}
+
+// rebuildBox returns the 12 triangles of the parallelepiped spanned by the
+// shell's vertices, if the shell has exactly the 8 corners of one
+func (wm *weldedMesh) rebuildBox(shell [][3]int) ([][3]int, bool) {
+	var corners []int
+	seen := make(map[int]bool)
+	for _, t := range shell {
+		for _, idx := range t {
+			if !seen[idx] {
+				seen[idx] = true
+				corners = append(corners, idx)
+			}
+		}
+	}
+	if len(corners) != 8 {
+		return nil, false
+	}
+
+	// Find three edges from the first corner whose sums reach every other corner
+	origin := wm.verts[corners[0]]
+	find := func(v fauxgl.Vector) int {
+		for _, idx := range corners {
+			if wm.verts[idx].Distance(v) < 1e-4 {
+				return idx
+			}
+		}
+		return -1
+	}
+
+	for a := 1; a < 8; a++ {
+		for b := a + 1; b < 8; b++ {
+			for c := b + 1; c < 8; c++ {
+				ea := wm.verts[corners[a]].Sub(origin)
+				eb := wm.verts[corners[b]].Sub(origin)
+				ec := wm.verts[corners[c]].Sub(origin)
+				if math.Abs(ea.Dot(eb.Cross(ec))) < 1e-12 {
+					continue
+				}
+
+				var idx [8]int
+				ok := true
+				for i := 0; i < 8 && ok; i++ {
+					v := origin
+					if i&1 != 0 {
+						v = v.Add(ea)
+					}
+					if i&2 != 0 {
+						v = v.Add(eb)
+					}
+					if i&4 != 0 {
+						v = v.Add(ec)
+					}
+					idx[i] = find(v)
+					ok = idx[i] >= 0
+				}
+				if !ok {
+					continue
+				}
+
+				var tris [][3]int
+				for _, q := range boxQuads {
+					tris = append(tris,
+						[3]int{idx[q[0]], idx[q[1]], idx[q[2]]},
+						[3]int{idx[q[0]], idx[q[2]], idx[q[3]]},
+					)
+				}
+				return tris, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// boxQuads lists the faces of a box whose corner i has bit 0 set for +X,
+// bit 1 for +Y and bit 2 for +Z, wound counter-clockwise seen from outside
+var boxQuads = [][4]int{
+	{0, 2, 3, 1}, // -Z
+	{4, 5, 7, 6}, // +Z
+	{0, 1, 5, 4}, // -Y
+	{2, 6, 7, 3}, // +Y
+	{0, 4, 6, 2}, // -X
+	{1, 3, 7, 5}, // +X
+}
+
+// bounds returns the axis-aligned bounding box of all referenced vertices
+func (wm *weldedMesh) bounds() (fauxgl.Vector, fauxgl.Vector) {
+	min := fauxgl.V(math.Inf(1), math.Inf(1), math.Inf(1))
+	max := fauxgl.V(math.Inf(-1), math.Inf(-1), math.Inf(-1))
+	for _, t := range wm.tris {
+		for _, idx := range t {
+			min = min.Min(wm.verts[idx])
+			max = max.Max(wm.verts[idx])
+		}
+	}
+	return min, max
+}
+
+// addBox appends an outward-facing axis-aligned box
+func (wm *weldedMesh) addBox(min, max fauxgl.Vector) {
+	base := len(wm.verts)
+	for i := 0; i < 8; i++ {
+		v := min
+		if i&1 != 0 {
+			v.X = max.X
+		}
+		if i&2 != 0 {
+			v.Y = max.Y
+		}
+		if i&4 != 0 {
+			v.Z = max.Z
+		}
+		wm.verts = append(wm.verts, v)
+	}
+
+	for _, q := range boxQuads {
+		wm.tris = append(wm.tris,
+			[3]int{base + q[0], base + q[1], base + q[2]},
+			[3]int{base + q[0], base + q[2], base + q[3]},
+		)
+	}
+}
+
+// encodeBinarySTL writes the mesh in the binary STL format
+func (wm *weldedMesh) encodeBinarySTL() []byte {
+	var buf bytes.Buffer
+	buf.Grow(84 + len(wm.tris)*50)
+
+	header := make([]byte, 80)
+	copy(header, "BlockyServer STL export")
+	buf.Write(header)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(wm.tris)))
+
+	for _, t := range wm.tris {
+		v0, v1, v2 := wm.verts[t[0]], wm.verts[t[1]], wm.verts[t[2]]
+		n := v1.Sub(v0).Cross(v2.Sub(v0)).Normalize()
+		for _, v := range []fauxgl.Vector{n, v0, v1, v2} {
+			binary.Write(&buf, binary.LittleEndian, [3]float32{float32(v.X), float32(v.Y), float32(v.Z)})
+		}
+		binary.Write(&buf, binary.LittleEndian, uint16(0))
+	}
+
+	return buf.Bytes()
+}
+
+// sortedTriangle returns the vertex indices in ascending order, and whether
+// the original winding is an even permutation of that order
+func sortedTriangle(tri [3]int) ([3]int, bool) {
+	sorted := tri
+	sort.Ints(sorted[:])
+	return sorted, isEvenTriangle(tri)
+}
+
+// isEvenTriangle reports whether tri is a rotation of its ascending order
+func isEvenTriangle(tri [3]int) bool {
+	inversions := 0
+	for i := 0; i < 3; i++ {
+		for j := i + 1; j < 3; j++ {
+			if tri[i] > tri[j] {
+				inversions++
+			}
+		}
+	}
+	return inversions%2 == 0
+}
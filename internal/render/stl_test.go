@@ -0,0 +1,210 @@
+package render
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+	"github.com/hytale-tools/blockymodel-merger/pkg/blockymodel"
+	"github.com/hytale-tools/blockymodel-merger/pkg/export"
+)
+
+// boxTriangles returns the triangles of an axis-aligned box, facing outwards
+// unless inward is set, leaving out the faces in skip (indices into boxQuads)
+func boxTriangles(min, max fauxgl.Vector, inward bool, skip ...int) []*fauxgl.Triangle {
+	corner := func(i int) fauxgl.Vector {
+		v := min
+		if i&1 != 0 {
+			v.X = max.X
+		}
+		if i&2 != 0 {
+			v.Y = max.Y
+		}
+		if i&4 != 0 {
+			v.Z = max.Z
+		}
+		return v
+	}
+	tri := func(a, b, c int) *fauxgl.Triangle {
+		if inward {
+			b, c = c, b
+		}
+		return fauxgl.NewTriangleForPoints(corner(a), corner(b), corner(c))
+	}
+
+	var tris []*fauxgl.Triangle
+faces:
+	for f, q := range boxQuads {
+		for _, s := range skip {
+			if s == f {
+				continue faces
+			}
+		}
+		tris = append(tris, tri(q[0], q[1], q[2]), tri(q[0], q[2], q[3]))
+	}
+	return tris
+}
+
+func TestWeldMesh(t *testing.T) {
+	unit := fauxgl.V(1, 1, 1)
+	plane := []*fauxgl.Triangle{
+		fauxgl.NewTriangleForPoints(fauxgl.V(0, 0, 0), fauxgl.V(1, 0, 0), fauxgl.V(1, 1, 0)),
+		fauxgl.NewTriangleForPoints(fauxgl.V(0, 0, 0), fauxgl.V(1, 1, 0), fauxgl.V(0, 1, 0)),
+	}
+	backface := []*fauxgl.Triangle{
+		fauxgl.NewTriangleForPoints(fauxgl.V(0, 0, 0), fauxgl.V(1, 1, 0), fauxgl.V(1, 0, 0)),
+		fauxgl.NewTriangleForPoints(fauxgl.V(0, 0, 0), fauxgl.V(0, 1, 0), fauxgl.V(1, 1, 0)),
+	}
+	degenerate := fauxgl.NewTriangleForPoints(fauxgl.V(0, 0, 0), fauxgl.V(1, 0, 0), fauxgl.V(2, 0, 0))
+
+	tests := []struct {
+		name    string
+		tris    []*fauxgl.Triangle
+		faces   int
+		dropped int
+	}{
+		{"closed box", boxTriangles(fauxgl.V(0, 0, 0), unit, false), 12, 0},
+		{"inside-out box is flipped", boxTriangles(fauxgl.V(0, 0, 0), unit, true), 12, 0},
+		{"box with a missing face is rebuilt", boxTriangles(fauxgl.V(0, 0, 0), unit, false, 1), 12, 0},
+		{"touching boxes drop their shared faces", append(
+			boxTriangles(fauxgl.V(0, 0, 0), unit, false),
+			boxTriangles(fauxgl.V(1, 0, 0), fauxgl.V(2, 1, 1), false)...), 20, 0},
+		{"separate boxes are kept", append(
+			boxTriangles(fauxgl.V(0, 0, 0), unit, false),
+			boxTriangles(fauxgl.V(3, 0, 0), fauxgl.V(4, 1, 1), false)...), 24, 0},
+		{"duplicate faces collapse", append(
+			boxTriangles(fauxgl.V(0, 0, 0), unit, false),
+			boxTriangles(fauxgl.V(0, 0, 0), unit, false)...), 12, 0},
+		{"open plane is dropped", plane, 0, 1},
+		{"double-sided plane cancels out", append(plane, backface...), 0, 0},
+		{"degenerate triangles are ignored", append(boxTriangles(fauxgl.V(0, 0, 0), unit, false), degenerate), 12, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm, dropped := weldMesh(fauxgl.NewTriangleMesh(tt.tris))
+			if len(wm.tris) != tt.faces || dropped != tt.dropped {
+				t.Fatalf("got %d faces and %d dropped shells, want %d and %d", len(wm.tris), dropped, tt.faces, tt.dropped)
+			}
+			checkClosed(t, wm)
+		})
+	}
+}
+
+func TestWeldParts(t *testing.T) {
+	part := func(name string, tris []*fauxgl.Triangle) meshPart {
+		return meshPart{name: name, mesh: fauxgl.NewTriangleMesh(tris)}
+	}
+	unit := boxTriangles(fauxgl.V(0, 0, 0), fauxgl.V(1, 1, 1), false)
+	plane := []*fauxgl.Triangle{
+		fauxgl.NewTriangleForPoints(fauxgl.V(0, 0, 0), fauxgl.V(1, 0, 0), fauxgl.V(1, 1, 0)),
+		fauxgl.NewTriangleForPoints(fauxgl.V(0, 0, 0), fauxgl.V(1, 1, 0), fauxgl.V(0, 1, 0)),
+	}
+
+	tests := []struct {
+		name    string
+		parts   []meshPart
+		faces   int
+		skipped []string
+	}{
+		{"boxes touching along an edge", []meshPart{
+			part("a", unit),
+			part("b", boxTriangles(fauxgl.V(1, 1, 0), fauxgl.V(2, 2, 1), false)),
+		}, 24, nil},
+		{"boxes touching at a corner", []meshPart{
+			part("a", unit),
+			part("b", boxTriangles(fauxgl.V(1, 1, 1), fauxgl.V(2, 2, 2), false)),
+		}, 24, nil},
+		{"boxes sharing part of a face", []meshPart{
+			part("a", unit),
+			part("b", boxTriangles(fauxgl.V(1, 0, 0), fauxgl.V(2, 2, 1), false)),
+		}, 24, nil},
+		{"boxes sharing a whole face", []meshPart{
+			part("a", unit),
+			part("b", boxTriangles(fauxgl.V(1, 0, 0), fauxgl.V(2, 1, 1), false)),
+		}, 24, nil},
+		{"box with a missing face beside another", []meshPart{
+			part("a", boxTriangles(fauxgl.V(0, 0, 0), fauxgl.V(1, 1, 1), false, 5)),
+			part("b", boxTriangles(fauxgl.V(1, 0, 0), fauxgl.V(2, 2, 1), false)),
+		}, 24, nil},
+		{"plane is reported", []meshPart{
+			part("body", unit),
+			part("fringe", plane),
+		}, 12, []string{"fringe"}},
+		{"double-sided plane is reported", []meshPart{
+			part("body", unit),
+			part("cape", append(plane, fauxgl.NewTriangleForPoints(fauxgl.V(0, 0, 0), fauxgl.V(1, 1, 0), fauxgl.V(1, 0, 0)),
+				fauxgl.NewTriangleForPoints(fauxgl.V(0, 0, 0), fauxgl.V(0, 1, 0), fauxgl.V(1, 1, 0)))),
+		}, 12, []string{"cape"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm, skipped := weldParts(tt.parts)
+			if len(wm.tris) != tt.faces {
+				t.Fatalf("got %d faces, want %d", len(wm.tris), tt.faces)
+			}
+			if !reflect.DeepEqual(skipped, tt.skipped) {
+				t.Errorf("got skipped %v, want %v", skipped, tt.skipped)
+			}
+			checkClosed(t, wm)
+		})
+	}
+}
+
+// checkClosed fails unless every edge is shared by two faces and the solid
+// faces outwards
+func checkClosed(t *testing.T, wm *weldedMesh) {
+	t.Helper()
+	edges := make(map[[2]int]int)
+	var volume float64
+	for _, tri := range wm.tris {
+		for i := 0; i < 3; i++ {
+			edges[[2]int{tri[i], tri[(i+1)%3]}]++
+		}
+		volume += wm.verts[tri[0]].Dot(wm.verts[tri[1]].Cross(wm.verts[tri[2]]))
+	}
+	for e, n := range edges {
+		if n != 1 || edges[[2]int{e[1], e[0]}] != 1 {
+			t.Fatalf("edge %v is not shared by two consistently wound faces", e)
+		}
+	}
+	if len(wm.tris) > 0 && volume <= 0 {
+		t.Errorf("signed volume %v, want positive", volume)
+	}
+}
+
+func TestRenderSTL(t *testing.T) {
+	// A body with an arm beside it and a plane, as exported for a character
+	const layout = `{"front":{"offset":{"x":0,"y":0}},"back":{"offset":{"x":0,"y":0}},"left":{"offset":{"x":0,"y":0}},"right":{"offset":{"x":0,"y":0}},"top":{"offset":{"x":0,"y":0}},"bottom":{"offset":{"x":0,"y":0}}}`
+	const model = `{"nodes":[{"id":"1","name":"body","shape":{"type":"box","settings":{"size":{"x":8,"y":12,"z":4}},"textureLayout":` + layout + `},"children":[
+		{"id":"2","name":"arm","position":{"x":6,"y":2,"z":0},"shape":{"type":"box","settings":{"size":{"x":4,"y":8,"z":4}},"textureLayout":` + layout + `}},
+		{"id":"3","name":"fringe","position":{"x":0,"y":8,"z":2},"shape":{"type":"quad","settings":{"size":{"x":4,"y":4}},"textureLayout":` + layout + `}}
+	]}]}`
+	var m blockymodel.BlockyModel
+	if err := json.Unmarshal([]byte(model), &m); err != nil {
+		t.Fatal(err)
+	}
+	e := export.NewGLBExporter()
+	e.SetAtlasSize(16, 16)
+	if err := e.ExportModel(&m, e.AddMaterial("atlas", e.AddTexture(nil))); err != nil {
+		t.Fatal(err)
+	}
+	glb, err := e.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stl, skipped, err := RenderSTL(glb, 100, false, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := binary.LittleEndian.Uint32(stl[80:]); got != 24 {
+		t.Errorf("got %d triangles, want 24", got)
+	}
+	if !reflect.DeepEqual(skipped, []string{"fringe"}) {
+		t.Errorf("got skipped %v, want [fringe]", skipped)
+	}
+}
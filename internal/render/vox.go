@@ -0,0 +1,225 @@
+package render
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/fogleman/fauxgl"
+	"github.com/hytale-tools/blockymodel-merger/pkg/texture"
+)
+
+// MaxVoxelResolution is the largest model dimension supported by the .vox format
+const MaxVoxelResolution = 256
+
+// RenderVOX voxelizes a GLB model into a MagicaVoxel .vox file.
+// resolution is the number of voxels along the model's longest axis.
+// Voxel colours are sampled from the tinted atlas and reduced to a 255-colour palette.
+func RenderVOX(glbBytes []byte, atlas *texture.Atlas, resolution int) ([]byte, error) {
+	if resolution < 1 || resolution > MaxVoxelResolution {
+		return nil, fmt.Errorf("resolution must be between 1 and %d", MaxVoxelResolution)
+	}
+
+	var atlasImage image.Image
+	if atlas != nil {
+		atlasImage = atlas.Image
+	}
+
+	// Convert GLB to mesh
+	mesh, err := GLBToMesh(glbBytes, atlasImage)
+	if err != nil {
+		return nil, fmt.Errorf("converting GLB to mesh: %w", err)
+	}
+
+	grid := voxelize(mesh, atlasImage, resolution)
+	if len(grid.voxels) == 0 {
+		return nil, fmt.Errorf("model has no visible geometry to voxelize")
+	}
+
+	// Build a palette from the sampled colours. Index 0 is reserved for
+	// empty space in .vox, leaving 255 usable entries.
+	colors := image.NewRGBA(image.Rect(0, 0, len(grid.voxels), 1))
+	for i, v := range grid.voxels {
+		colors.SetRGBA(i, 0, v.color)
+	}
+	pal := MedianCutQuantize([]image.Image{colors}, 255)
+
+	return encodeVOX(grid, pal), nil
+}
+
+// voxel is a filled cell of the voxel grid
+type voxel struct {
+	x, y, z int
+	color   color.RGBA
+	dist    float64 // distance of the sample to the cell centre
+}
+
+// voxelGrid holds the filled cells of a voxelized mesh
+type voxelGrid struct {
+	sizeX, sizeY, sizeZ int
+	voxels              []*voxel
+}
+
+// voxelize samples every triangle densely and fills the cells it passes
+// through, keeping for each cell the colour of the sample nearest its centre
+func voxelize(mesh *fauxgl.Mesh, atlasImage image.Image, resolution int) *voxelGrid {
+	box := mesh.BoundingBox()
+	size := box.Size()
+	maxDim := math.Max(size.X, math.Max(size.Y, size.Z))
+	if maxDim <= 0 {
+		return &voxelGrid{}
+	}
+	voxelSize := maxDim / float64(resolution)
+
+	dims := func(extent float64) int {
+		n := int(math.Ceil(extent / voxelSize))
+		if n < 1 {
+			n = 1
+		}
+		if n > MaxVoxelResolution {
+			n = MaxVoxelResolution
+		}
+		return n
+	}
+	grid := &voxelGrid{sizeX: dims(size.X), sizeY: dims(size.Y), sizeZ: dims(size.Z)}
+	cells := make(map[[3]int]*voxel)
+
+	for _, tri := range mesh.Triangles {
+		p0, p1, p2 := tri.V1.Position, tri.V2.Position, tri.V3.Position
+		maxEdge := math.Max(p0.Distance(p1), math.Max(p1.Distance(p2), p2.Distance(p0)))
+
+		// Sample at half the voxel size so no cell along the surface is skipped
+		steps := int(math.Ceil(maxEdge/(voxelSize*0.5))) + 1
+		for i := 0; i <= steps; i++ {
+			for j := 0; j <= steps-i; j++ {
+				a := float64(i) / float64(steps)
+				b := float64(j) / float64(steps)
+				c := 1 - a - b
+
+				p := p0.MulScalar(c).Add(p1.MulScalar(a)).Add(p2.MulScalar(b))
+				uv := tri.V1.Texture.MulScalar(c).Add(tri.V2.Texture.MulScalar(a)).Add(tri.V3.Texture.MulScalar(b))
+
+				col, ok := sampleAtlas(atlasImage, uv)
+				if !ok {
+					continue
+				}
+
+				rel := p.Sub(box.Min).DivScalar(voxelSize)
+				cell := [3]int{
+					clampInt(int(rel.X), 0, grid.sizeX-1),
+					clampInt(int(rel.Y), 0, grid.sizeY-1),
+					clampInt(int(rel.Z), 0, grid.sizeZ-1),
+				}
+				centre := fauxgl.V(float64(cell[0])+0.5, float64(cell[1])+0.5, float64(cell[2])+0.5)
+				dist := rel.Distance(centre)
+
+				if v, ok := cells[cell]; ok {
+					if dist < v.dist {
+						v.color = col
+						v.dist = dist
+					}
+					continue
+				}
+				v := &voxel{x: cell[0], y: cell[1], z: cell[2], color: col, dist: dist}
+				cells[cell] = v
+				grid.voxels = append(grid.voxels, v)
+			}
+		}
+	}
+
+	return grid
+}
+
+// sampleAtlas returns the atlas colour at a mesh texture coordinate, or false
+// if the texel is transparent (alpha-tested geometry is not voxelized)
+func sampleAtlas(atlasImage image.Image, uv fauxgl.Vector) (color.RGBA, bool) {
+	if atlasImage == nil {
+		return color.RGBA{204, 204, 204, 255}, true
+	}
+
+	// Mesh texture coordinates have a bottom-left origin
+	bounds := atlasImage.Bounds()
+	x := bounds.Min.X + clampInt(int(uv.X*float64(bounds.Dx())), 0, bounds.Dx()-1)
+	y := bounds.Min.Y + clampInt(int((1-uv.Y)*float64(bounds.Dy())), 0, bounds.Dy()-1)
+
+	c := color.RGBAModel.Convert(atlasImage.At(x, y)).(color.RGBA)
+	if c.A < 128 {
+		return color.RGBA{}, false
+	}
+	c.A = 255
+	return c, true
+}
+
+// encodeVOX writes the grid as a MagicaVoxel .vox (version 150) file.
+// The model is converted from Y-up to the Z-up axes MagicaVoxel uses.
+func encodeVOX(grid *voxelGrid, pal color.Palette) []byte {
+	le := binary.LittleEndian
+
+	// SIZE chunk: x, y, z in .vox axes
+	var size bytes.Buffer
+	binary.Write(&size, le, [3]uint32{uint32(grid.sizeX), uint32(grid.sizeZ), uint32(grid.sizeY)})
+
+	// XYZI chunk: voxel count followed by x, y, z, colour index bytes
+	var xyzi bytes.Buffer
+	binary.Write(&xyzi, le, uint32(len(grid.voxels)))
+	indices := make(map[color.RGBA]uint8)
+	for _, v := range grid.voxels {
+		idx, ok := indices[v.color]
+		if !ok {
+			idx = uint8(pal.Index(v.color) + 1)
+			indices[v.color] = idx
+		}
+		xyzi.Write([]byte{
+			uint8(v.x),
+			uint8(grid.sizeZ - 1 - v.z),
+			uint8(v.y),
+			idx,
+		})
+	}
+
+	// RGBA chunk: entry i holds the colour for voxel index i+1
+	var rgba bytes.Buffer
+	for i := 0; i < 256; i++ {
+		c := color.RGBA{0, 0, 0, 255}
+		if i < len(pal) {
+			c = color.RGBAModel.Convert(pal[i]).(color.RGBA)
+		}
+		rgba.Write([]byte{c.R, c.G, c.B, c.A})
+	}
+
+	var children bytes.Buffer
+	writeVOXChunk(&children, "SIZE", size.Bytes())
+	writeVOXChunk(&children, "XYZI", xyzi.Bytes())
+	writeVOXChunk(&children, "RGBA", rgba.Bytes())
+
+	var buf bytes.Buffer
+	buf.WriteString("VOX ")
+	binary.Write(&buf, le, uint32(150))
+	buf.WriteString("MAIN")
+	binary.Write(&buf, le, uint32(0))
+	binary.Write(&buf, le, uint32(children.Len()))
+	buf.Write(children.Bytes())
+
+	return buf.Bytes()
+}
+
+// writeVOXChunk writes a leaf chunk with the given ID and content
+func writeVOXChunk(buf *bytes.Buffer, id string, content []byte) {
+	buf.WriteString(id)
+	binary.Write(buf, binary.LittleEndian, uint32(len(content)))
+	binary.Write(buf, binary.LittleEndian, uint32(0))
+	buf.Write(content)
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
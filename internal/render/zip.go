@@ -0,0 +1,35 @@
+package render
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+)
+
+// ArchiveFile is a single named entry written into a ZIP archive
+type ArchiveFile struct {
+	Name string
+	Data []byte
+}
+
+// BuildZip packs the given files into an in-memory ZIP archive
+func BuildZip(files []ArchiveFile) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, f := range files {
+		w, err := zw.Create(f.Name)
+		if err != nil {
+			return nil, fmt.Errorf("creating %s in archive: %w", f.Name, err)
+		}
+		if _, err := w.Write(f.Data); err != nil {
+			return nil, fmt.Errorf("writing %s to archive: %w", f.Name, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("closing archive: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io/fs"
+	"log"
+	"time"
+
+	"blockyserver/internal/cache"
+
+	"github.com/hytale-tools/blockymodel-merger/pkg/blockymodel"
+	"github.com/hytale-tools/blockymodel-merger/pkg/texture"
+)
+
+// estimatedNodeSize approximates the memory used by a parsed model node,
+// including its shape and texture layout
+const estimatedNodeSize = 1024
+
+// textureKey identifies a tinted texture
+type textureKey struct {
+	Path        string
+	GradientSet string
+	Color       string
+}
+
+// assetCache keeps parsed models, decoded images and tinted textures in memory.
+// Cached values are shared between requests and must not be modified; the
+// merger clones every node it takes from an accessory.
+type assetCache struct {
+	models *cache.LRU[string, *blockymodel.BlockyModel]
+	images *cache.LRU[string, image.Image]
+	tinted *cache.LRU[textureKey, image.Image]
+}
+
+// newAssetCache splits maxBytes between models (1/4), images (1/4) and
+// tinted textures (1/2)
+func newAssetCache(maxBytes int64) *assetCache {
+	return &assetCache{
+		models: cache.New[string, *blockymodel.BlockyModel](maxBytes/4, modelSize),
+		images: cache.New[string, image.Image](maxBytes/4, imageSize),
+		tinted: cache.New[textureKey, image.Image](maxBytes/2, imageSize),
+	}
+}
+
+// CacheStats returns statistics for each asset cache
+func (s *MergeService) CacheStats() map[string]cache.Stats {
+	return map[string]cache.Stats{
+		"models":   s.assets.models.Stats(),
+		"images":   s.assets.images.Stats(),
+		"textures": s.assets.tinted.Stats(),
+	}
+}
+
+// loadModel returns a parsed accessory model, reading it on a miss
+func (s *MergeService) loadModel(path string) (*blockymodel.BlockyModel, error) {
+	return s.assets.models.GetOrLoad(path, func() (*blockymodel.BlockyModel, error) {
+		return readModel(s.files, path)
+	})
+}
+
+// loadImage returns a decoded texture, reading it on a miss
+func (s *MergeService) loadImage(path string) (image.Image, error) {
+	return s.assets.images.GetOrLoad(path, func() (image.Image, error) {
+		return readPNG(s.files, path)
+	})
+}
+
+// tintTexture returns a greyscale texture tinted with a gradient color,
+// tinting it on a miss
+func (s *MergeService) tintTexture(name, path, gradientSet, color string) (*texture.TintedTexture, error) {
+	key := textureKey{Path: path, GradientSet: gradientSet, Color: color}
+	img, err := s.assets.tinted.GetOrLoad(key, func() (image.Image, error) {
+		greyscale, err := s.loadImage(path)
+		if err != nil {
+			return nil, err
+		}
+
+		// Hex colors generate their gradient. Unknown colors are tinted grey,
+		// and colors whose gradient texture is missing fall back to their
+		// base color, like the merger tool does.
+		var gradientImg image.Image
+		var baseColor string
+		if isHexColor(color) {
+			stops, err := parseColorRamp(color)
+			if err != nil {
+				return nil, err
+			}
+			gradientImg = gradientRamp(stops)
+		} else if gradient, ok := s.catalog.Gradient(gradientSet, color); ok {
+			gradientImg, _ = s.loadImage(assetPath(gradient.Texture))
+			if len(gradient.BaseColor) > 0 {
+				baseColor = gradient.BaseColor[0]
+			}
+		}
+
+		return tint(greyscale, gradientImg, baseColor), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &texture.TintedTexture{
+		Name:         name,
+		Image:        img,
+		OriginalPath: path,
+	}, nil
+}
+
+// preloadAssets loads every model and texture in the catalog into the
+// cache, and tints every greyscale texture, the player texture included, in
+// each color of its gradient set. Hex colors are tinted on first use, and
+// tinted textures beyond the cache budget are evicted again. Missing files
+// are skipped; they are reported per request.
+func (s *MergeService) preloadAssets() {
+	start := time.Now()
+	models, images, tinted := 0, 0, 0
+
+	if _, err := s.loadImage(baseTextureFile); err == nil {
+		images++
+	}
+	for _, tone := range s.catalog.GradientColors("Skin") {
+		if _, err := s.tintTexture("_base", baseTextureFile, "Skin", tone); err == nil {
+			tinted++
+		}
+	}
+
+	for _, category := range s.catalog.Categories() {
+		items, _ := s.catalog.Items(category)
+		for _, item := range items {
+			variants := []string{""}
+			for _, v := range item.Variants {
+				variants = append(variants, v.Name)
+			}
+
+			for _, variant := range variants {
+				if path := item.modelPath(variant); path != "" {
+					if _, err := s.loadModel(path); err == nil {
+						models++
+					}
+				}
+
+				if resolved := item.resolveTexture("", variant); resolved != nil && resolved.GreyscaleTexture != "" {
+					if _, err := s.loadImage(resolved.GreyscaleTexture); err == nil {
+						images++
+					}
+				}
+
+				for _, color := range item.Colors {
+					resolved := item.resolveTexture(color, variant)
+					switch {
+					case resolved == nil:
+					case resolved.DirectTexture != "":
+						if _, err := s.loadImage(resolved.DirectTexture); err == nil {
+							images++
+						}
+					case resolved.GreyscaleTexture != "":
+						if _, err := s.tintTexture(item.ID, resolved.GreyscaleTexture, resolved.GradientSet, color); err == nil {
+							tinted++
+						}
+					}
+				}
+			}
+		}
+	}
+
+	log.Printf("Preloaded %d models, %d textures and %d tinted textures in %s", models, images, tinted, time.Since(start).Round(time.Millisecond))
+}
+
+// readModel parses a .blockymodel file
+func readModel(files fs.FS, path string) (*blockymodel.BlockyModel, error) {
+	data, err := fs.ReadFile(files, path)
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := readModelBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return model, nil
+}
+
+// readModelBytes parses .blockymodel JSON
+func readModelBytes(data []byte) (*blockymodel.BlockyModel, error) {
+	var model blockymodel.BlockyModel
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// readPNG decodes a PNG file
+func readPNG(files fs.FS, path string) (image.Image, error) {
+	f, err := files.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return img, nil
+}
+
+func modelSize(model *blockymodel.BlockyModel) int64 {
+	return int64(countNodes(model.Nodes)) * estimatedNodeSize
+}
+
+func countNodes(nodes []blockymodel.Node) int {
+	n := len(nodes)
+	for i := range nodes {
+		n += countNodes(nodes[i].Children)
+	}
+	return n
+}
+
+func imageSize(img image.Image) int64 {
+	b := img.Bounds()
+	return int64(b.Dx()) * int64(b.Dy()) * 4
+}
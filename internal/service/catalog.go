@@ -0,0 +1,478 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/hytale-tools/blockymodel-merger/pkg/registry"
+	"github.com/hytale-tools/blockymodel-merger/pkg/texture"
+)
+
+// categoryFiles maps character fields to their registry files in data/,
+// in the same order the fields appear in a character
+var categoryFiles = []struct {
+	Category string
+	File     string
+}{
+	{"bodyCharacteristic", "BodyCharacteristics"},
+	{"underwear", "Underwear"},
+	{"face", "Faces"},
+	{"ears", "Ears"},
+	{"mouth", "Mouths"},
+	{"haircut", "Haircuts"},
+	{"facialHair", "FacialHair"},
+	{"eyebrows", "Eyebrows"},
+	{"eyes", "Eyes"},
+	{"pants", "Pants"},
+	{"overpants", "Overpants"},
+	{"undertop", "Undertops"},
+	{"overtop", "Overtops"},
+	{"shoes", "Shoes"},
+	{"headAccessory", "HeadAccessory"},
+	{"faceAccessory", "FaceAccessory"},
+	{"earAccessory", "EarAccessory"},
+	{"skinFeature", "SkinFeatures"},
+	{"gloves", "Gloves"},
+	{"cape", "Capes"},
+}
+
+// CatalogItem describes a single cosmetic that can be used in a character slot
+type CatalogItem struct {
+	ID                           string            `json:"id"`
+	Name                         string            `json:"name,omitempty"`
+	Category                     string            `json:"category"`
+	GradientSet                  string            `json:"gradientSet,omitempty"`
+	Colors                       []string          `json:"colors"`
+	Variants                     []CatalogVariant  `json:"variants,omitempty"`
+	HeadAccessoryType            string            `json:"headAccessoryType,omitempty"`
+	DisableCharacterPartCategory string            `json:"disableCharacterPartCategory,omitempty"`
+	HairType                     string            `json:"hairType,omitempty"`
+	Disables                     []string          `json:"disables,omitempty"` // fields hidden while this is worn
+	Model                        string            `json:"model,omitempty"`
+	GreyscaleTexture             string            `json:"greyscaleTexture,omitempty"`
+	Textures                     map[string]string `json:"textures,omitempty"` // color -> pre-colored texture
+
+	entry        registry.AccessoryEntry
+	disables     map[string][]string // Disable* registry field -> character fields
+	codeColors   []string            // Colors in registry file order, see codes.go
+	codeVariants []string            // variant names in registry file order
+}
+
+// CatalogVariant describes a variant of a cosmetic (e.g. "NoNeck")
+type CatalogVariant struct {
+	Name             string            `json:"name"`
+	Model            string            `json:"model,omitempty"`
+	GreyscaleTexture string            `json:"greyscaleTexture,omitempty"`
+	Textures         map[string]string `json:"textures,omitempty"`
+}
+
+// Catalog lists every cosmetic loaded from the registry files
+type Catalog struct {
+	categories []string
+	items      map[string][]*CatalogItem          // category -> items sorted by ID
+	byID       map[string]map[string]*CatalogItem // category -> ID -> item
+	gradients  map[string][]string                // gradient set -> sorted color names
+	sets       map[string]texture.GradientSet     // gradient set -> gradients by color
+	custom     *CustomCosmetics                   // uploaded cosmetics, nil if disabled
+
+	// Registry file order, which share codes index into
+	order         map[string][]string // category -> IDs
+	gradientOrder map[string][]string // gradient set -> color names
+}
+
+// catalogEntry holds the registry fields the catalog exposes beyond registry.AccessoryEntry
+type catalogEntry struct {
+	HeadAccessoryType            string `json:"HeadAccessoryType"`
+	DisableCharacterPartCategory string `json:"DisableCharacterPartCategory"`
+	HairType                     string `json:"HairType"`
+
+	disables map[string][]string // every Disable* field, see registryDisables
+	colors   []string            // pre-colored texture colors in file order, variants last
+	variants []string            // variant names in file order
+}
+
+// loadCatalog reads every registry file and the gradient sets from data/.
+// Missing registry files are skipped.
+func loadCatalog(files fs.FS) (*Catalog, error) {
+	c := &Catalog{
+		items:     make(map[string][]*CatalogItem),
+		byID:      make(map[string]map[string]*CatalogItem),
+		gradients: make(map[string][]string),
+		sets:      make(map[string]texture.GradientSet),
+
+		order:         make(map[string][]string),
+		gradientOrder: make(map[string][]string),
+	}
+
+	if err := c.loadGradients(files, gradientSetsFile); err != nil {
+		return nil, err
+	}
+
+	for _, cf := range categoryFiles {
+		data, err := fs.ReadFile(files, "data/"+cf.File+".json")
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var raw []json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("parsing %s.json: %w", cf.File, err)
+		}
+
+		c.categories = append(c.categories, cf.Category)
+		c.byID[cf.Category] = make(map[string]*CatalogItem)
+
+		for _, r := range raw {
+			var entry registry.AccessoryEntry
+			var extra catalogEntry
+			if err := json.Unmarshal(r, &entry); err != nil {
+				return nil, fmt.Errorf("parsing %s.json: %w", cf.File, err)
+			}
+			if err := json.Unmarshal(r, &extra); err != nil {
+				return nil, fmt.Errorf("parsing %s.json: %w", cf.File, err)
+			}
+			extra.disables = registryDisables(r)
+			extra.colors, extra.variants = registryOrder(r)
+			if entry.ID == "" {
+				continue
+			}
+
+			item := c.newItem(cf.Category, entry, extra)
+			c.items[cf.Category] = append(c.items[cf.Category], item)
+			c.byID[cf.Category][item.ID] = item
+			c.order[cf.Category] = append(c.order[cf.Category], item.ID)
+		}
+
+		sort.Slice(c.items[cf.Category], func(i, j int) bool {
+			return c.items[cf.Category][i].ID < c.items[cf.Category][j].ID
+		})
+	}
+
+	return c, nil
+}
+
+func (c *Catalog) loadGradients(files fs.FS, path string) error {
+	data, err := fs.ReadFile(files, path)
+	if err != nil {
+		return err
+	}
+
+	var sets []texture.GradientSet
+	if err := json.Unmarshal(data, &sets); err != nil {
+		return fmt.Errorf("parsing gradient sets: %w", err)
+	}
+	var order []struct {
+		ID        string          `json:"Id"`
+		Gradients json.RawMessage `json:"Gradients"`
+	}
+	if err := json.Unmarshal(data, &order); err != nil {
+		return fmt.Errorf("parsing gradient sets: %w", err)
+	}
+
+	for i, set := range sets {
+		c.gradients[set.ID] = sortedKeys(set.Gradients)
+		c.gradientOrder[set.ID] = objectKeys(order[i].Gradients)
+		c.sets[set.ID] = set
+	}
+	return nil
+}
+
+func (c *Catalog) newItem(category string, entry registry.AccessoryEntry, extra catalogEntry) *CatalogItem {
+	item := &CatalogItem{
+		ID:                           entry.ID,
+		Name:                         entry.Name,
+		Category:                     category,
+		GradientSet:                  entry.GradientSet,
+		HeadAccessoryType:            extra.HeadAccessoryType,
+		DisableCharacterPartCategory: extra.DisableCharacterPartCategory,
+		HairType:                     extra.HairType,
+		Model:                        entry.Model,
+		GreyscaleTexture:             entry.GreyscaleTexture,
+		Textures:                     texturePaths(entry.Textures),
+		entry:                        entry,
+		disables:                     extra.disables,
+	}
+	for _, fields := range extra.disables {
+		for _, field := range fields {
+			if !containsString(item.Disables, field) {
+				item.Disables = append(item.Disables, field)
+			}
+		}
+	}
+	sort.Strings(item.Disables)
+
+	// Allowed colors: the item's gradient set plus any pre-colored textures
+	colors := make(map[string]bool)
+	for _, color := range c.gradients[entry.GradientSet] {
+		colors[color] = true
+	}
+	for color := range entry.Textures {
+		colors[color] = true
+	}
+
+	for _, name := range sortedKeys(entry.Variants) {
+		v := entry.Variants[name]
+		item.Variants = append(item.Variants, CatalogVariant{
+			Name:             name,
+			Model:            v.Model,
+			GreyscaleTexture: v.GreyscaleTexture,
+			Textures:         texturePaths(v.Textures),
+		})
+		for color := range v.Textures {
+			colors[color] = true
+		}
+	}
+
+	item.Colors = sortedKeys(colors)
+
+	// Uploaded cosmetics have no file order and fall back to sorted names
+	item.codeColors = appendMissing(appendMissing(nil, c.gradientOrder[entry.GradientSet]...), extra.colors...)
+	item.codeColors = appendMissing(item.codeColors, item.Colors...)
+	item.codeVariants = appendMissing(nil, extra.variants...)
+	for _, v := range item.Variants {
+		item.codeVariants = appendMissing(item.codeVariants, v.Name)
+	}
+	return item
+}
+
+// Categories returns the loaded categories in character field order
+func (c *Catalog) Categories() []string {
+	return c.categories
+}
+
+// Items returns all cosmetics of a category sorted by ID
+func (c *Catalog) Items(category string) ([]*CatalogItem, bool) {
+	if _, ok := c.byID[category]; !ok {
+		return nil, false
+	}
+	return c.items[category], true
+}
+
+// Item looks up a single cosmetic by category and ID, including uploaded
+// cosmetics
+func (c *Catalog) Item(category, id string) (*CatalogItem, bool) {
+	if isCustomID(id) && c.custom != nil {
+		if _, ok := c.byID[category]; !ok {
+			return nil, false
+		}
+		entry, ok := c.custom.entry(category, id)
+		if !ok {
+			return nil, false
+		}
+		return c.newItem(category, entry, catalogEntry{}), true
+	}
+	item, ok := c.byID[category][id]
+	return item, ok
+}
+
+// CatalogFilter narrows down catalog items. Empty fields match everything.
+type CatalogFilter struct {
+	Categories  []string // character fields, e.g. "haircut"
+	Query       string   // case-insensitive substring of ID or name
+	GradientSet string   // exact gradient set
+	Color       string   // items accepting this color
+	Type        string   // HeadAccessoryType or HairType
+}
+
+// Filter returns all items matching the filter, in category order then by ID,
+// with uploaded cosmetics after the official ones of their category
+func (c *Catalog) Filter(f CatalogFilter) []*CatalogItem {
+	categories := f.Categories
+	if len(categories) == 0 {
+		categories = c.categories
+	}
+	query := strings.ToLower(f.Query)
+
+	var result []*CatalogItem
+	for _, category := range categories {
+		items := c.items[category]
+		if custom := c.customItems(category); len(custom) > 0 {
+			items = append(items[:len(items):len(items)], custom...)
+		}
+		for _, item := range items {
+			if query != "" && !strings.Contains(strings.ToLower(item.ID), query) && !strings.Contains(strings.ToLower(item.Name), query) {
+				continue
+			}
+			if f.GradientSet != "" && item.GradientSet != f.GradientSet {
+				continue
+			}
+			if f.Color != "" && !item.HasColor(f.Color) {
+				continue
+			}
+			if f.Type != "" && item.HeadAccessoryType != f.Type && item.HairType != f.Type {
+				continue
+			}
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// GradientColors returns the sorted color names of a gradient set
+func (c *Catalog) GradientColors(set string) []string {
+	return c.gradients[set]
+}
+
+// Gradient looks up the gradient texture of a color in a gradient set
+func (c *Catalog) Gradient(set, color string) (*texture.GradientEntry, bool) {
+	gradient, ok := c.sets[set].Gradients[color]
+	if !ok {
+		return nil, false
+	}
+	return &gradient, true
+}
+
+// HasColor reports whether the item accepts the given color name
+func (i *CatalogItem) HasColor(color string) bool {
+	idx := sort.SearchStrings(i.Colors, color)
+	return idx < len(i.Colors) && i.Colors[idx] == color
+}
+
+// Variant looks up a variant of the item by name
+func (i *CatalogItem) Variant(name string) (*CatalogVariant, bool) {
+	for idx := range i.Variants {
+		if i.Variants[idx].Name == name {
+			return &i.Variants[idx], true
+		}
+	}
+	return nil, false
+}
+
+// modelPath returns the model of a variant, falling back to the item's own
+// model and then to the first variant that has one. Empty if there is none.
+func (i *CatalogItem) modelPath(variant string) string {
+	if v, ok := i.entry.Variants[variant]; ok && v.Model != "" {
+		return assetPath(v.Model)
+	}
+	if i.entry.Model != "" {
+		return assetPath(i.entry.Model)
+	}
+	for _, v := range i.Variants {
+		if v.Model != "" {
+			return assetPath(v.Model)
+		}
+	}
+	return ""
+}
+
+// resolveTexture picks the texture for a color and variant: a pre-colored
+// texture if one exists, otherwise the greyscale texture to tint. Variant
+// textures take precedence. It returns nil if the item has no texture.
+func (i *CatalogItem) resolveTexture(color, variant string) *registry.ResolvedTexture {
+	result := &registry.ResolvedTexture{GradientSet: i.entry.GradientSet}
+
+	if v, ok := i.entry.Variants[variant]; ok {
+		if tex, ok := v.Textures[color]; ok && color != "" {
+			result.DirectTexture = assetPath(tex.Texture)
+			result.BaseColor = tex.BaseColor
+			return result
+		}
+		if v.GreyscaleTexture != "" {
+			result.GreyscaleTexture = assetPath(v.GreyscaleTexture)
+			return result
+		}
+	}
+
+	if tex, ok := i.entry.Textures[color]; ok && color != "" {
+		result.DirectTexture = assetPath(tex.Texture)
+		result.BaseColor = tex.BaseColor
+		return result
+	}
+	if i.entry.GreyscaleTexture != "" {
+		result.GreyscaleTexture = assetPath(i.entry.GreyscaleTexture)
+		return result
+	}
+	return nil
+}
+
+// assetPath prefixes a registry path, which is relative to the assets root,
+// with "assets/". Paths that already have the prefix are kept.
+func assetPath(path string) string {
+	if strings.HasPrefix(path, assetPrefix) {
+		return path
+	}
+	return assetPrefix + path
+}
+
+func texturePaths(textures map[string]registry.TextureEntry) map[string]string {
+	if len(textures) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(textures))
+	for color, tex := range textures {
+		result[color] = tex.Texture
+	}
+	return result
+}
+
+// objectKeys returns the keys of a JSON object in the order they appear,
+// or nil if data is not an object
+func objectKeys(data json.RawMessage) []string {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return keys
+		}
+		keys = append(keys, tok.(string))
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return keys
+		}
+	}
+	return keys
+}
+
+// registryOrder returns the pre-colored texture colors of a registry entry,
+// followed by those of its variants, and its variant names in file order
+func registryOrder(raw json.RawMessage) (colors, variants []string) {
+	var entry struct {
+		Textures json.RawMessage `json:"Textures"`
+		Variants json.RawMessage `json:"Variants"`
+	}
+	if json.Unmarshal(raw, &entry) != nil {
+		return nil, nil
+	}
+	colors = objectKeys(entry.Textures)
+	variants = objectKeys(entry.Variants)
+
+	var variantTextures map[string]struct {
+		Textures json.RawMessage `json:"Textures"`
+	}
+	json.Unmarshal(entry.Variants, &variantTextures)
+	for _, name := range variants {
+		colors = appendMissing(colors, objectKeys(variantTextures[name].Textures)...)
+	}
+	return colors, variants
+}
+
+// appendMissing appends the names not in list yet
+func appendMissing(list []string, names ...string) []string {
+	for _, name := range names {
+		if !containsString(list, name) {
+			list = append(list, name)
+		}
+	}
+	return list
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strings"
+
+	"github.com/hytale-tools/blockymodel-merger/pkg/character"
+)
+
+// ErrInvalidCode is returned when a character code is malformed or corrupted
+var ErrInvalidCode = errors.New("invalid character code")
+
+// CodeVersion is the version of the character code format written by EncodeCode
+const CodeVersion = 1
+
+// Character code layout, after base64url decoding:
+//
+//	version   1 byte
+//	slot      header byte, ID ref, color ref if set, variant ref if set
+//	...
+//	checksum  2 bytes, low 16 bits of the CRC-32 of everything before,
+//	          followed by the decoded field values
+//
+// The header holds the field index in codeFields (bits 0-4) and whether a
+// color (bit 5) and variant (bit 6) follow. A ref is a uvarint: one plus the
+// position of the name in registry file order (IDs in the category's
+// registry file, colors in the gradient set then the item's pre-colored
+// textures, variants in the item), or 0 followed by a length byte and the
+// name for names outside that order, such as custom cosmetics and hex
+// colors. New cosmetics are appended to the registry files, so their
+// positions stay put; as the checksum covers the names, a code made before
+// the order changed fails to decode instead of turning into something else.
+const (
+	codeFieldMask  = 0x1f
+	codeHasColor   = 0x20
+	codeHasVariant = 0x40
+	literalRef     = 0
+	maxLiteral     = 255
+	checksumSize   = 2
+	maxCodeLength  = 1024
+)
+
+// codeFields are the character fields by their index in a code. Fields may
+// only be appended, or existing codes would decode into the wrong slots.
+var codeFields = []string{
+	"bodyCharacteristic", "underwear", "face", "ears", "mouth",
+	"haircut", "facialHair", "eyebrows", "eyes", "pants",
+	"overpants", "undertop", "overtop", "shoes", "headAccessory",
+	"faceAccessory", "earAccessory", "skinFeature", "gloves", "cape",
+}
+
+// EncodeCode packs a valid character into a compact base64url code
+func (s *MergeService) EncodeCode(charJSON []byte) (string, error) {
+	validation, err := s.Validate(charJSON)
+	if err != nil {
+		return "", err
+	}
+	if !validation.Valid {
+		return "", &ValidationError{Issues: validation.Errors}
+	}
+
+	var charData character.CharacterData
+	if err := json.Unmarshal(charJSON, &charData); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+	values := make(map[string]string)
+	for _, f := range characterFields(&charData) {
+		if f.Value != nil && *f.Value != "" {
+			values[f.Name] = *f.Value
+		}
+	}
+
+	buf := []byte{CodeVersion}
+	var decoded []string
+	for index, field := range codeFields {
+		value, ok := values[field]
+		if !ok {
+			continue
+		}
+		spec := character.ParseAccessorySpec(value)
+		item, _ := s.catalog.Item(field, spec.ID)
+		if len(spec.ID) > maxLiteral || len(spec.Color) > maxLiteral || len(spec.Variant) > maxLiteral {
+			return "", &ValidationError{Issues: []FieldIssue{{
+				Field:   field,
+				Value:   value,
+				Reason:  ReasonInvalidFormat,
+				Message: fmt.Sprintf("names longer than %d bytes cannot be encoded", maxLiteral),
+			}}}
+		}
+
+		header := byte(index)
+		if spec.Color != "" {
+			header |= codeHasColor
+		}
+		if spec.Variant != "" {
+			header |= codeHasVariant
+		}
+		buf = append(buf, header)
+		buf = appendRef(buf, spec.ID, s.catalog.order[field])
+		if spec.Color != "" {
+			buf = appendRef(buf, spec.Color, s.codeColors(field, item))
+		}
+		if spec.Variant != "" {
+			buf = appendRef(buf, spec.Variant, codeVariants(item))
+		}
+		decoded = append(decoded, field+"="+joinSpec(spec))
+	}
+
+	sum := codeChecksum(buf, decoded)
+	buf = append(buf, byte(sum>>8), byte(sum))
+	code := base64.RawURLEncoding.EncodeToString(buf)
+	if len(code) > maxCodeLength {
+		return "", fmt.Errorf("%w: longer than %d characters", ErrInvalidCode, maxCodeLength)
+	}
+	return code, nil
+}
+
+// DecodeCode unpacks a character code into character fields. Malformed codes
+// and codes made with a registry order that has since changed return
+// ErrInvalidCode; codes naming custom cosmetics or colors that are no
+// longer loaded return a ValidationError.
+func (s *MergeService) DecodeCode(code string) (map[string]string, error) {
+	if len(code) > maxCodeLength {
+		return nil, fmt.Errorf("%w: longer than %d characters", ErrInvalidCode, maxCodeLength)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(code, "="))
+	if err != nil {
+		return nil, fmt.Errorf("%w: not base64url", ErrInvalidCode)
+	}
+	if len(data) < 1+checksumSize {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidCode)
+	}
+	payload, stored := data[:len(data)-checksumSize], data[len(data)-checksumSize:]
+	if payload[0] != CodeVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidCode, payload[0])
+	}
+
+	r := &codeReader{data: payload, pos: 1}
+	result := make(map[string]string)
+	var decoded []string
+	var issues []FieldIssue
+	for !r.done() {
+		header, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		index := int(header & codeFieldMask)
+		if header&^(codeFieldMask|codeHasColor|codeHasVariant) != 0 || index >= len(codeFields) {
+			return nil, fmt.Errorf("%w: unknown field %d", ErrInvalidCode, index)
+		}
+		field := codeFields[index]
+		if _, ok := result[field]; ok {
+			return nil, fmt.Errorf("%w: %s is set twice", ErrInvalidCode, field)
+		}
+
+		var spec character.AccessorySpec
+		if spec.ID, err = r.ref(s.catalog.order[field]); err != nil {
+			return nil, err
+		}
+		item, _ := s.catalog.Item(field, spec.ID)
+		if header&codeHasColor != 0 {
+			if spec.Color, err = r.ref(s.codeColors(field, item)); err != nil {
+				return nil, err
+			}
+		}
+		if header&codeHasVariant != 0 {
+			if spec.Variant, err = r.ref(codeVariants(item)); err != nil {
+				return nil, err
+			}
+		}
+
+		value := joinSpec(spec)
+		result[field] = value
+		decoded = append(decoded, field+"="+value)
+	}
+
+	sum := codeChecksum(payload, decoded)
+	if stored[0] != byte(sum>>8) || stored[1] != byte(sum) {
+		return nil, fmt.Errorf("%w: checksum mismatch, the code is corrupted or was made with other assets", ErrInvalidCode)
+	}
+
+	// Custom cosmetics and colors may have been removed since
+	for _, field := range codeFields {
+		if value, ok := result[field]; ok {
+			if issue := s.validateField(field, value); issue != nil {
+				issues = append(issues, *issue)
+			}
+		}
+	}
+	if len(issues) > 0 {
+		return nil, &ValidationError{Issues: issues}
+	}
+	return result, nil
+}
+
+// codeColors returns the colors of an item in the order codes index into.
+// The color of a body characteristic is the skin tone. Colors of custom
+// cosmetics are written as literals, so a code whose upload was removed
+// still decodes far enough to report it.
+func (s *MergeService) codeColors(field string, item *CatalogItem) []string {
+	if field == "bodyCharacteristic" {
+		return s.catalog.gradientOrder["Skin"]
+	}
+	if item == nil || isCustomID(item.ID) {
+		return nil
+	}
+	return item.codeColors
+}
+
+// codeVariants returns the variants of an item in the order codes index into
+func codeVariants(item *CatalogItem) []string {
+	if item == nil {
+		return nil
+	}
+	return item.codeVariants
+}
+
+// codeChecksum returns the CRC-32 of a code payload and the field values it
+// decodes to
+func codeChecksum(payload []byte, decoded []string) uint32 {
+	h := crc32.NewIEEE()
+	h.Write(payload)
+	for _, value := range decoded {
+		h.Write([]byte(value))
+		h.Write([]byte{0})
+	}
+	return h.Sum32()
+}
+
+// appendRef writes a name as its position in order, or as a literal if it
+// is not in order
+func appendRef(buf []byte, name string, order []string) []byte {
+	for i, n := range order {
+		if n == name {
+			return binary.AppendUvarint(buf, uint64(i)+1)
+		}
+	}
+	buf = append(buf, literalRef, byte(len(name)))
+	return append(buf, name...)
+}
+
+// codeReader reads the slots of a decoded code
+type codeReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *codeReader) done() bool {
+	return r.pos >= len(r.data)
+}
+
+func (r *codeReader) byte() (byte, error) {
+	if r.done() {
+		return 0, fmt.Errorf("%w: truncated", ErrInvalidCode)
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+// ref reads a name written by appendRef
+func (r *codeReader) ref(order []string) (string, error) {
+	n, size := binary.Uvarint(r.data[r.pos:])
+	if size <= 0 {
+		return "", fmt.Errorf("%w: truncated", ErrInvalidCode)
+	}
+	r.pos += size
+	if n != literalRef {
+		if n > uint64(len(order)) {
+			return "", fmt.Errorf("%w: refers to a cosmetic, color or variant that is not loaded, the code may have been made with other assets", ErrInvalidCode)
+		}
+		return order[n-1], nil
+	}
+
+	length, err := r.byte()
+	if err != nil {
+		return "", err
+	}
+	if length == 0 || r.pos+int(length) > len(r.data) {
+		return "", fmt.Errorf("%w: truncated", ErrInvalidCode)
+	}
+	name := string(r.data[r.pos : r.pos+int(length)])
+	r.pos += int(length)
+	return name, nil
+}
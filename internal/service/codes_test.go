@@ -0,0 +1,191 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// rawCode encodes a payload with the checksum of it and the field values it
+// decodes to, bypassing EncodeCode
+func rawCode(decoded []string, payload ...byte) string {
+	sum := codeChecksum(payload, decoded)
+	return base64.RawURLEncoding.EncodeToString(append(payload, byte(sum>>8), byte(sum)))
+}
+
+// literal returns a name as written outside the registry order
+func literal(name string) []byte {
+	return append([]byte{literalRef, byte(len(name))}, name...)
+}
+
+func TestCodeRoundTrip(t *testing.T) {
+	svc := newTestService(t)
+
+	tests := []struct {
+		name      string
+		character string
+		want      map[string]string
+		maxLength int
+	}{
+		{"empty", `{}`, map[string]string{}, 4},
+		{"ID only", `{"cape":"Cape_A"}`, map[string]string{"cape": "Cape_A"}, 8},
+		{"color", `{"haircut":"Scavenger_Hair.PitchBlack"}`, map[string]string{"haircut": "Scavenger_Hair.PitchBlack"}, 8},
+		{"color and variant", `{"pants":"Pants_A.Red.Long"}`, map[string]string{"pants": "Pants_A.Red.Long"}, 10},
+		{"skin tone", `{"bodyCharacteristic":"Default.02"}`, map[string]string{"bodyCharacteristic": "Default.02"}, 20},
+		{"hex colors", `{"haircut":"Scavenger_Hair.#3A7BD5-#E0F0FF","bodyCharacteristic":"Default.#C8966E"}`,
+			map[string]string{"haircut": "Scavenger_Hair.#3A7BD5-#E0F0FF", "bodyCharacteristic": "Default.#C8966E"}, 60},
+		{"every slot", `{"bodyCharacteristic":"Default.01","haircut":"Short_Fallback.Blonde","eyes":"Large_Eyes.Green","pants":"Pants_A.Blue.Short","headAccessory":"Helmet_A.Steel","cape":"Cape_A.Red"}`,
+			map[string]string{"bodyCharacteristic": "Default.01", "haircut": "Short_Fallback.Blonde", "eyes": "Large_Eyes.Green", "pants": "Pants_A.Blue.Short", "headAccessory": "Helmet_A.Steel", "cape": "Cape_A.Red"}, 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := svc.EncodeCode([]byte(tt.character))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.ContainsAny(code, "+/=") {
+				t.Errorf("code %q is not base64url without padding", code)
+			}
+			if len(code) > tt.maxLength {
+				t.Errorf("code %q is %d characters, want at most %d", code, len(code), tt.maxLength)
+			}
+			got, err := svc.DecodeCode(code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeCodeInvalid(t *testing.T) {
+	svc := newTestService(t)
+	_, err := svc.EncodeCode([]byte(`{"haircut":"Scavenger_Hiar.Brown"}`))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Errorf("got %v, want a ValidationError", err)
+	}
+}
+
+func TestDecodeCodeErrors(t *testing.T) {
+	svc := newTestService(t)
+	valid, err := svc.EncodeCode([]byte(`{"haircut":"Scavenger_Hair.Brown"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := base64.RawURLEncoding.DecodeString(valid)
+	flipped := append([]byte{}, data...)
+	flipped[len(flipped)/2] ^= 0x01
+	haircut := byte(5) // index of haircut in codeFields
+	cape := byte(19)
+	literalHair := append([]byte{CodeVersion, haircut | codeHasColor}, literal("Scavenger_Hair")...)
+
+	tests := []struct {
+		name    string
+		code    string
+		invalid bool // ErrInvalidCode, otherwise a ValidationError
+	}{
+		{"not base64url", "not a code!", true},
+		{"too short", "AQ", true},
+		{"too long", strings.Repeat("A", maxCodeLength+1), true},
+		{"checksum mismatch", base64.RawURLEncoding.EncodeToString(flipped), true},
+		{"checksum of other names", rawCode([]string{"cape=Cape_B"}, CodeVersion, cape, 1), true},
+		{"version 0", rawCode(nil, 0), true},
+		{"future version", rawCode(nil, CodeVersion+1), true},
+		{"unknown field", rawCode([]string{"x=Cape_A"}, CodeVersion, 31, 1), true},
+		{"unknown header bit", rawCode([]string{"haircut=Scavenger_Hair"}, CodeVersion, haircut|0x80, 1), true},
+		{"index out of range", rawCode([]string{"cape=Cape_A"}, CodeVersion, cape, 2), true},
+		{"truncated literal", rawCode([]string{"haircut=x"}, CodeVersion, haircut, literalRef, 10, 'x'), true},
+		{"empty literal", rawCode([]string{"haircut="}, CodeVersion, haircut, literalRef, 0), true},
+		{"missing color", rawCode([]string{"haircut=Scavenger_Hair"}, CodeVersion, haircut|codeHasColor, 1), true},
+		{"field set twice", rawCode([]string{"cape=Cape_A", "cape=Cape_A"}, CodeVersion, cape, 1, cape, 1), true},
+		{"removed custom cosmetic", rawCode([]string{"cape=custom:Gone"}, append([]byte{CodeVersion, cape}, literal("custom:Gone")...)...), false},
+		{"unknown literal color", rawCode([]string{"haircut=Scavenger_Hair.Red"}, append(literalHair, literal("Red")...)...), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.DecodeCode(tt.code)
+			var validationErr *ValidationError
+			switch {
+			case tt.invalid && !errors.Is(err, ErrInvalidCode):
+				t.Errorf("got %v, want ErrInvalidCode", err)
+			case !tt.invalid && !errors.As(err, &validationErr):
+				t.Errorf("got %v, want a ValidationError", err)
+			}
+		})
+	}
+}
+
+func TestCodeAssetUpdates(t *testing.T) {
+	const character = `{"haircut":"Short_Fallback.Blonde","cape":"Cape_A.Red"}`
+	code, err := newTestService(t).EncodeCode([]byte(character))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	haircuts := func(order ...string) string {
+		entries := make([]string, len(order))
+		for i, id := range order {
+			entries[i] = `{"Id":"` + id + `","Model":"Cosmetics/Hair/Short.blockymodel","GreyscaleTexture":"Cosmetics/Hair/Short_Greyscale.png","GradientSet":"Hair","HairType":"Short"}`
+		}
+		return "[" + strings.Join(entries, ",") + "]"
+	}
+
+	tests := []struct {
+		name     string
+		haircuts string
+		invalid  bool
+	}{
+		{"haircut appended", haircuts("Scavenger_Hair", "Short_Fallback", "Long_Hair"), false},
+		{"haircuts reordered", haircuts("Short_Fallback", "Scavenger_Hair"), true},
+		{"haircut removed", haircuts("Scavenger_Hair"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataDir := t.TempDir()
+			entries, err := os.ReadDir("testdata/data")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range entries {
+				data, err := os.ReadFile(filepath.Join("testdata/data", e.Name()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if e.Name() == "Haircuts.json" {
+					data = []byte(tt.haircuts)
+				}
+				if err := os.WriteFile(filepath.Join(dataDir, e.Name()), data, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			svc, err := NewMergeService(Options{Paths: Paths{AssetsDir: "testdata/assets", DataDir: dataDir}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := svc.DecodeCode(code)
+			if tt.invalid {
+				if !errors.Is(err, ErrInvalidCode) {
+					t.Errorf("got %v %v, want ErrInvalidCode", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := map[string]string{"haircut": "Short_Fallback.Blonde", "cape": "Cape_A.Red"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
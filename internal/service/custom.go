@@ -0,0 +1,400 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/hytale-tools/blockymodel-merger/pkg/merger"
+	"github.com/hytale-tools/blockymodel-merger/pkg/registry"
+)
+
+// CustomPrefix namespaces the IDs of uploaded cosmetics, e.g. "custom:MyHat"
+const CustomPrefix = "custom:"
+
+const (
+	customMount     = "assets/Custom" // where the upload directory appears in every pack
+	customIndexFile = "cosmetics.json"
+	customColor     = "Default" // color of uploads with a pre-colored texture
+)
+
+// ReasonTooLarge is reported for uploads exceeding the configured limits
+const ReasonTooLarge = "too_large"
+
+var (
+	// ErrCustomExists is returned when a custom cosmetic with the same ID exists
+	ErrCustomExists = errors.New("custom cosmetic already exists")
+	// ErrCustomFull is returned when the configured number of uploads is stored
+	ErrCustomFull = errors.New("custom cosmetic limit reached")
+)
+
+var customNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// CustomLimits bounds what an upload may contain
+type CustomLimits struct {
+	MaxUploadBytes int64 // whole upload, model and texture together
+	MaxNodes       int   // nodes in the model, including children
+	MaxTextureSize int   // texture width and height in pixels
+	MaxCount       int   // stored cosmetics across all categories
+}
+
+// CustomCosmetics stores uploaded cosmetics in a directory and registers them
+// with every asset pack. Uploads cannot be replaced, so renders cached under
+// a custom ID never go stale.
+type CustomCosmetics struct {
+	dir    string
+	limits CustomLimits
+
+	mu      sync.RWMutex
+	entries map[string]map[string]registry.AccessoryEntry // category -> ID -> entry
+}
+
+// CustomUpload is a cosmetic to validate and store. Exactly one of Texture
+// and Greyscale must be set; Greyscale is tinted with GradientSet.
+type CustomUpload struct {
+	Name        string // ID without the prefix, letters, digits, "_" and "-"
+	Category    string // character field, e.g. "headAccessory"
+	DisplayName string // optional, defaults to Name
+	Model       []byte // .blockymodel JSON
+	Texture     []byte // pre-colored PNG, used as color "Default"
+	Greyscale   []byte // greyscale PNG
+	GradientSet string // gradient set for Greyscale, e.g. "Hair"
+}
+
+// OpenCustomCosmetics creates dir if needed and loads the cosmetics
+// uploaded to it before
+func OpenCustomCosmetics(dir string, limits CustomLimits) (*CustomCosmetics, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	c := &CustomCosmetics{
+		dir:     dir,
+		limits:  limits,
+		entries: make(map[string]map[string]registry.AccessoryEntry),
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, customIndexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var index map[string][]registry.AccessoryEntry
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", customIndexFile, err)
+	}
+	for category, entries := range index {
+		c.entries[category] = make(map[string]registry.AccessoryEntry)
+		for _, e := range entries {
+			c.entries[category][e.ID] = e
+		}
+	}
+	return c, nil
+}
+
+// Limits returns the upload limits
+func (c *CustomCosmetics) Limits() CustomLimits {
+	return c.limits
+}
+
+// Count returns the number of stored cosmetics
+func (c *CustomCosmetics) Count() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.count()
+}
+
+func (c *CustomCosmetics) count() int {
+	n := 0
+	for _, entries := range c.entries {
+		n += len(entries)
+	}
+	return n
+}
+
+// entry looks up a stored cosmetic
+func (c *CustomCosmetics) entry(category, id string) (registry.AccessoryEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, ok := c.entries[category][id]
+	return e, ok
+}
+
+// list returns the stored cosmetics of a category sorted by ID
+func (c *CustomCosmetics) list(category string) []registry.AccessoryEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := make([]registry.AccessoryEntry, 0, len(c.entries[category]))
+	for _, id := range sortedKeys(c.entries[category]) {
+		result = append(result, c.entries[category][id])
+	}
+	return result
+}
+
+// add writes the files of a cosmetic and registers it. files maps paths
+// relative to the upload directory to their contents. The files are written
+// under temporary names and only renamed into place once the index lists
+// them, so a failed upload leaves nothing behind.
+func (c *CustomCosmetics) add(category string, entry registry.AccessoryEntry, files map[string][]byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[category][entry.ID]; ok {
+		return ErrCustomExists
+	}
+	if c.count() >= c.limits.MaxCount {
+		return ErrCustomFull
+	}
+
+	staged := make(map[string]string) // path -> temporary file
+	discard := func() {
+		for path, tmp := range staged {
+			os.Remove(tmp)
+			os.Remove(path)
+		}
+	}
+	for name, data := range files {
+		path := filepath.Join(c.dir, filepath.FromSlash(name))
+		tmp, err := writeTemp(path, data)
+		if err != nil {
+			discard()
+			return err
+		}
+		staged[path] = tmp
+	}
+
+	if c.entries[category] == nil {
+		c.entries[category] = make(map[string]registry.AccessoryEntry)
+	}
+	c.entries[category][entry.ID] = entry
+
+	if err := c.writeIndex(); err != nil {
+		delete(c.entries[category], entry.ID)
+		discard()
+		return err
+	}
+	for path, tmp := range staged {
+		if err := os.Rename(tmp, path); err != nil {
+			delete(c.entries[category], entry.ID)
+			c.writeIndex()
+			discard()
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTemp writes data to a new temporary file next to path
+func writeTemp(path string, data []byte) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	if err = f.Chmod(0o644); err == nil {
+		_, err = f.Write(data)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// writeIndex replaces the index file, renaming a temporary file into place
+// so a crash never leaves a truncated index
+func (c *CustomCosmetics) writeIndex() error {
+	index := make(map[string][]registry.AccessoryEntry)
+	for _, category := range sortedKeys(c.entries) {
+		for _, id := range sortedKeys(c.entries[category]) {
+			index[category] = append(index[category], c.entries[category][id])
+		}
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(c.dir, customIndexFile)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// AddCustom validates an upload against this pack and stores it. The
+// cosmetic becomes usable as "custom:<Name>" in every pack. Invalid uploads
+// return a ValidationError listing each problem.
+func (s *MergeService) AddCustom(up CustomUpload) (*CatalogItem, error) {
+	custom := s.catalog.custom
+	if custom == nil {
+		return nil, errors.New("custom cosmetics are disabled")
+	}
+
+	var issues []FieldIssue
+	issue := func(field, reason, message string, suggestions ...string) {
+		issues = append(issues, FieldIssue{Field: field, Reason: reason, Message: message, Suggestions: suggestions})
+	}
+
+	if !customNamePattern.MatchString(up.Name) {
+		issue("name", ReasonInvalidFormat, "name must be 1-64 letters, digits, \"_\" or \"-\"")
+	}
+
+	if _, ok := s.catalog.Items(up.Category); !ok || !containsString(mergeOrder, up.Category) {
+		var categories []string
+		for _, category := range s.catalog.Categories() {
+			if containsString(mergeOrder, category) {
+				categories = append(categories, category)
+			}
+		}
+		issue("category", ReasonUnknownID, fmt.Sprintf("unknown category %q", up.Category), suggest(up.Category, categories)...)
+	}
+
+	if size := int64(len(up.Model) + len(up.Texture) + len(up.Greyscale)); size > custom.limits.MaxUploadBytes {
+		issue("model", ReasonTooLarge, fmt.Sprintf("upload is %d bytes, the limit is %d", size, custom.limits.MaxUploadBytes))
+	}
+
+	if len(up.Model) == 0 {
+		issue("model", ReasonInvalidFormat, "model is required")
+	} else if message, reason := s.checkCustomModel(up.Model, custom.limits.MaxNodes); message != "" {
+		issue("model", reason, message)
+	}
+
+	switch {
+	case len(up.Texture) > 0 && len(up.Greyscale) > 0:
+		issue("texture", ReasonInvalidFormat, "send either texture or greyscale, not both")
+	case len(up.Texture) > 0:
+		if message, reason := checkCustomTexture(up.Texture, custom.limits.MaxTextureSize); message != "" {
+			issue("texture", reason, message)
+		}
+		if up.GradientSet != "" {
+			issue("gradientSet", ReasonInvalidFormat, "gradientSet only applies to greyscale textures")
+		}
+	case len(up.Greyscale) > 0:
+		if message, reason := checkCustomTexture(up.Greyscale, custom.limits.MaxTextureSize); message != "" {
+			issue("greyscale", reason, message)
+		}
+		if _, ok := s.catalog.sets[up.GradientSet]; !ok {
+			issue("gradientSet", ReasonUnknownID, fmt.Sprintf("unknown gradient set %q", up.GradientSet), suggest(up.GradientSet, sortedKeys(s.catalog.sets))...)
+		}
+	default:
+		issue("texture", ReasonInvalidFormat, "texture or greyscale is required")
+	}
+
+	if len(issues) > 0 {
+		return nil, &ValidationError{Issues: issues}
+	}
+
+	// Paths are built from the validated name and category only
+	dir := "Custom/" + up.Category + "/"
+	entry := registry.AccessoryEntry{
+		ID:    CustomPrefix + up.Name,
+		Name:  up.DisplayName,
+		Model: dir + up.Name + ".blockymodel",
+	}
+	if entry.Name == "" {
+		entry.Name = up.Name
+	}
+	files := map[string][]byte{
+		up.Category + "/" + up.Name + ".blockymodel": up.Model,
+	}
+	if len(up.Texture) > 0 {
+		entry.Textures = map[string]registry.TextureEntry{
+			customColor: {Texture: dir + up.Name + ".png"},
+		}
+		files[up.Category+"/"+up.Name+".png"] = up.Texture
+	} else {
+		entry.GreyscaleTexture = dir + up.Name + "_Greyscale.png"
+		entry.GradientSet = up.GradientSet
+		files[up.Category+"/"+up.Name+"_Greyscale.png"] = up.Greyscale
+	}
+
+	if err := custom.add(up.Category, entry, files); err != nil {
+		return nil, err
+	}
+
+	item, _ := s.catalog.Item(up.Category, entry.ID)
+	return item, nil
+}
+
+// checkCustomModel parses an uploaded model and test-merges it onto the
+// base model. It returns a message and reason if the model is rejected.
+func (s *MergeService) checkCustomModel(data []byte, maxNodes int) (string, string) {
+	model, err := readModelBytes(data)
+	if err != nil {
+		return err.Error(), ReasonInvalidFormat
+	}
+
+	nodes := countNodes(model.Nodes)
+	if nodes == 0 {
+		return "model has no nodes", ReasonInvalidFormat
+	}
+	if nodes > maxNodes {
+		return fmt.Sprintf("model has %d nodes, the limit is %d", nodes, maxNodes), ReasonTooLarge
+	}
+
+	m, err := merger.New(s.baseModel)
+	if err != nil {
+		return err.Error(), ReasonInvalidFormat
+	}
+	before := countNodes(m.Result().Nodes)
+	if err := m.Merge(model, "custom"); err != nil {
+		return "merging model: " + err.Error(), ReasonInvalidFormat
+	}
+	if countNodes(m.Result().Nodes) == before {
+		return "model has no nodes attached to a bone of the player model", ReasonInvalidFormat
+	}
+	return "", ""
+}
+
+// checkCustomTexture checks that an uploaded texture is a PNG within the
+// size limit. The header is checked before the image is decoded.
+func checkCustomTexture(data []byte, maxSize int) (string, string) {
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "not a PNG image: " + err.Error(), ReasonInvalidFormat
+	}
+	if cfg.Width > maxSize || cfg.Height > maxSize {
+		return fmt.Sprintf("texture is %dx%d, the limit is %dx%d", cfg.Width, cfg.Height, maxSize, maxSize), ReasonTooLarge
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		return "not a PNG image: " + err.Error(), ReasonInvalidFormat
+	}
+	return "", ""
+}
+
+// isCustomID reports whether an ID names an uploaded cosmetic
+func isCustomID(id string) bool {
+	return strings.HasPrefix(id, CustomPrefix)
+}
+
+// customItems returns the uploaded cosmetics of a category as catalog items
+func (c *Catalog) customItems(category string) []*CatalogItem {
+	if c.custom == nil {
+		return nil
+	}
+	entries := c.custom.list(category)
+	items := make([]*CatalogItem, len(entries))
+	for i, e := range entries {
+		items[i] = c.newItem(category, e, catalogEntry{})
+	}
+	return items
+}
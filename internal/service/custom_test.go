@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hytale-tools/blockymodel-merger/pkg/registry"
+)
+
+func TestCustomCosmeticsAdd(t *testing.T) {
+	dir := t.TempDir()
+	custom, err := OpenCustomCosmetics(dir, CustomLimits{MaxCount: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := registry.AccessoryEntry{ID: CustomPrefix + "Hat", Name: "Hat", Model: "Custom/headAccessory/Hat.blockymodel"}
+	files := map[string][]byte{
+		"headAccessory/Hat.blockymodel": []byte(`{"nodes":[]}`),
+		"headAccessory/Hat.png":         []byte("png"),
+	}
+
+	// A failed index write leaves no files behind
+	indexTmp := filepath.Join(dir, customIndexFile+".tmp")
+	if err := os.Mkdir(indexTmp, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := custom.add("headAccessory", entry, files); err == nil {
+		t.Fatal("got no error when the index cannot be written")
+	}
+	if names := dirNames(t, filepath.Join(dir, "headAccessory")); len(names) > 0 {
+		t.Errorf("got files %v after a failed upload, want none", names)
+	}
+	if _, ok := custom.entry("headAccessory", entry.ID); ok {
+		t.Error("failed upload is registered")
+	}
+	os.Remove(indexTmp)
+
+	if err := custom.add("headAccessory", entry, files); err != nil {
+		t.Fatal(err)
+	}
+	if names := dirNames(t, filepath.Join(dir, "headAccessory")); len(names) != 2 {
+		t.Errorf("got files %v, want the model and texture", names)
+	}
+	if err := custom.add("headAccessory", entry, files); !errors.Is(err, ErrCustomExists) {
+		t.Errorf("got %v, want ErrCustomExists", err)
+	}
+
+	reopened, err := OpenCustomCosmetics(dir, CustomLimits{MaxCount: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := reopened.entry("headAccessory", entry.ID); !ok || got.Model != entry.Model {
+		t.Errorf("got %+v after reopening, want %+v", got, entry)
+	}
+}
+
+// dirNames lists the files in dir, or nothing if it does not exist
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
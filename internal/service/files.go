@@ -0,0 +1,80 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// mappedFS combines folders and files from several file systems into one
+// tree, e.g. mapping "assets/Cosmetics" to "Common/Cosmetics" in assets.zip.
+// Longer mount names take precedence, so a single file can replace one
+// inside a mounted folder.
+type mappedFS struct {
+	mounts  []mount     // sorted by descending name length
+	closers []io.Closer // archives closed with the file system
+}
+
+type mount struct {
+	name   string // path in the combined tree
+	fsys   fs.FS
+	dir    string // path in fsys, "." for its root
+	source string // location reported in errors, e.g. a directory on disk
+}
+
+func (m *mappedFS) mount(name string, fsys fs.FS, dir, source string) {
+	m.mounts = append(m.mounts, mount{name: name, fsys: fsys, dir: dir, source: source})
+	sort.SliceStable(m.mounts, func(i, j int) bool {
+		return len(m.mounts[i].name) > len(m.mounts[j].name)
+	})
+}
+
+// Close closes the archives mounted in the tree
+func (m *mappedFS) Close() error {
+	var errs []error
+	for _, c := range m.closers {
+		errs = append(errs, c.Close())
+	}
+	m.closers = nil
+	return errors.Join(errs...)
+}
+
+// Open opens a file in the combined tree
+func (m *mappedFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	mt, rel, ok := m.lookup(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return mt.fsys.Open(path.Join(mt.dir, rel))
+}
+
+// where describes where a file in the combined tree is read from
+func (m *mappedFS) where(name string) string {
+	mt, rel, ok := m.lookup(name)
+	if !ok {
+		return name
+	}
+	if rel == "" {
+		return mt.source
+	}
+	return strings.TrimSuffix(mt.source, "/") + "/" + rel
+}
+
+// lookup finds the mount containing name and the path relative to it
+func (m *mappedFS) lookup(name string) (mount, string, bool) {
+	for _, mt := range m.mounts {
+		if name == mt.name {
+			return mt, "", true
+		}
+		if rel, ok := strings.CutPrefix(name, mt.name+"/"); ok {
+			return mt, rel, true
+		}
+	}
+	return mount{}, "", false
+}
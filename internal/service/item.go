@@ -0,0 +1,157 @@
+package service
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/hytale-tools/blockymodel-merger/pkg/blockymodel"
+	"github.com/hytale-tools/blockymodel-merger/pkg/character"
+)
+
+// ItemOptions controls how a single cosmetic is merged
+type ItemOptions struct {
+	Strict    bool   // fail with a ValidationError instead of rendering with warnings
+	Mannequin bool   // keep the player body as a faded mannequin under the item
+	SkinTone  string // skin tone for skin-colored items without a color, e.g. "01"
+}
+
+// mannequinColor is the pale grey the mannequin fades towards
+var mannequinColor = color.RGBA{R: 228, G: 230, B: 235, A: 255}
+
+// ItemCategory finds the character field of an "Id.Color.Variant" value
+// from the catalog. The ID must belong to exactly one item category;
+// otherwise the ValidationError lists the categories to choose from.
+func (s *MergeService) ItemCategory(value string) (string, error) {
+	id := character.ParseAccessorySpec(value).ID
+	var fields, ids []string
+	for _, field := range mergeOrder {
+		if _, ok := s.catalog.Item(field, id); ok {
+			fields = append(fields, field)
+		}
+		items, _ := s.catalog.Items(field)
+		for _, item := range items {
+			ids = append(ids, item.ID)
+		}
+	}
+
+	switch len(fields) {
+	case 1:
+		return fields[0], nil
+	case 0:
+		return "", &ValidationError{Issues: []FieldIssue{{
+			Field:       "item",
+			Value:       value,
+			Reason:      ReasonUnknownID,
+			Message:     fmt.Sprintf("no item category has %q", id),
+			Suggestions: suggest(id, ids),
+		}}}
+	default:
+		return "", &ValidationError{Issues: []FieldIssue{{
+			Field:       "category",
+			Value:       value,
+			Reason:      ReasonAmbiguousID,
+			Message:     fmt.Sprintf("%q is in several categories, set category to one of them", id),
+			Suggestions: fields,
+		}}}
+	}
+}
+
+// MergeItem merges a single cosmetic, given as a character field and an
+// "Id.Color.Variant" value such as "overtop" and "Jacket_A.Red". An empty
+// field is looked up with ItemCategory. The item is attached to the base
+// skeleton as in a full character, but only its own geometry is kept unless
+// opts.Mannequin is set. Slot rules are not applied.
+func (s *MergeService) MergeItem(field, value string, opts ItemOptions) (*MergeResult, error) {
+	if field == "" {
+		var err error
+		if field, err = s.ItemCategory(value); err != nil {
+			return nil, err
+		}
+	}
+	if !containsString(mergeOrder, field) {
+		issue := FieldIssue{
+			Field:       field,
+			Value:       value,
+			Reason:      ReasonUnknownField,
+			Message:     fmt.Sprintf("unknown item category %q", field),
+			Suggestions: suggest(field, mergeOrder),
+		}
+		if containsCategory(field) {
+			issue.Reason = ReasonCategoryIgnored
+			issue.Message = fmt.Sprintf("%s is not rendered as an item", field)
+			issue.Suggestions = nil
+		}
+		return nil, &ValidationError{Issues: []FieldIssue{issue}}
+	}
+	if issue := s.validateField(field, value); issue != nil {
+		return nil, &ValidationError{Issues: []FieldIssue{*issue}}
+	}
+	if opts.SkinTone != "" {
+		if issue := s.validateSkinTone("skinTone", opts.SkinTone, opts.SkinTone); issue != nil {
+			return nil, &ValidationError{Issues: []FieldIssue{*issue}}
+		}
+	}
+
+	res := &Resolution{
+		Character: map[string]string{field: value},
+		Warnings:  []FieldIssue{},
+	}
+	s.resolveAccessories(res, opts.SkinTone)
+	if len(res.paths) == 0 {
+		// The model is missing, so there is nothing to render
+		return nil, &ValidationError{Issues: res.Warnings}
+	}
+	s.resolveTextures(res, opts.SkinTone)
+	if opts.Strict && len(res.Warnings) > 0 {
+		return nil, &ValidationError{Issues: res.Warnings}
+	}
+
+	// The player texture is only needed for the mannequin
+	tinted, infos := res.tinted[:0], res.Textures[:0]
+	for i, tex := range res.tinted {
+		if tex.Name == "_base" {
+			if !opts.Mannequin {
+				continue
+			}
+			ghost := *tex
+			ghost.Image = ghostTexture(tex.Image)
+			tex = &ghost
+		}
+		tinted = append(tinted, tex)
+		infos = append(infos, res.Textures[i])
+	}
+	res.tinted, res.Textures = tinted, infos
+
+	return s.merge(res, !opts.Mannequin)
+}
+
+// hideGeometry removes the shapes of nodes that did not come from an
+// accessory, keeping them as bones so attached geometry stays in place
+func hideGeometry(nodes []blockymodel.Node, sources map[string]string) {
+	for i := range nodes {
+		node := &nodes[i]
+		if _, ok := sources[node.ID]; !ok && node.HasGeometry() {
+			node.Shape = &blockymodel.Shape{Type: "none", Offset: node.Shape.Offset}
+		}
+		hideGeometry(node.Children, sources)
+	}
+}
+
+// ghostTexture fades a texture to pale grey so items stand out on it
+func ghostTexture(img image.Image) image.Image {
+	bounds := img.Bounds()
+	result := image.NewRGBA(bounds)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			luma := uint8((299*uint32(c.R) + 587*uint32(c.G) + 114*uint32(c.B)) / 1000)
+			ghost := mixColor(color.RGBA{R: luma, G: luma, B: luma, A: 255}, mannequinColor, 0.75)
+			ghost.A = c.A
+			result.SetRGBA(x, y, ghost)
+		}
+	}
+
+	return result
+}
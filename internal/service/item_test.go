@@ -0,0 +1,61 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestItemCategory(t *testing.T) {
+	svc := newTestService(t)
+
+	tests := []struct {
+		name        string
+		value       string
+		category    string
+		field       string // field of the expected error
+		reason      string
+		suggestions []string
+	}{
+		{"only in one category", "Scavenger_Hair.Brown", "haircut", "", "", nil},
+		{"ID only", "Cape_A", "cape", "", "", nil},
+		{"in several categories", "Pants_A.Red.Long", "", "category", ReasonAmbiguousID, []string{"pants", "overpants"}},
+		{"unknown ID", "Scavenger_Hiar.Brown", "", "item", ReasonUnknownID, []string{"Scavenger_Hair"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			category, err := svc.ItemCategory(tt.value)
+			if tt.reason == "" {
+				if err != nil || category != tt.category {
+					t.Fatalf("got %q, %v, want %q", category, err, tt.category)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || len(validationErr.Issues) != 1 {
+				t.Fatalf("got %v, want a single issue", err)
+			}
+			issue := validationErr.Issues[0]
+			if issue.Field != tt.field || issue.Reason != tt.reason || !reflect.DeepEqual(issue.Suggestions, tt.suggestions) {
+				t.Errorf("got %s %s %v, want %s %s %v", issue.Field, issue.Reason, issue.Suggestions, tt.field, tt.reason, tt.suggestions)
+			}
+		})
+	}
+}
+
+func TestMergeItemWithoutCategory(t *testing.T) {
+	svc := newTestService(t)
+
+	result, err := svc.MergeItem("", "Scavenger_Hair.Brown", ItemOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.GLBBytes) == 0 {
+		t.Error("got an empty model")
+	}
+	if _, err := svc.MergeItem("", "Pants_A.Red.Long", ItemOptions{}); err == nil {
+		t.Error("merged an item found in several categories")
+	}
+}
@@ -3,43 +3,33 @@ package service
 import (
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
-	"strings"
 
 	"github.com/hytale-tools/blockymodel-merger/pkg/blockymodel"
-	"github.com/hytale-tools/blockymodel-merger/pkg/character"
 	"github.com/hytale-tools/blockymodel-merger/pkg/export"
 	"github.com/hytale-tools/blockymodel-merger/pkg/merger"
-	"github.com/hytale-tools/blockymodel-merger/pkg/registry"
 	"github.com/hytale-tools/blockymodel-merger/pkg/texture"
 )
 
-const (
-	basePath        = "assets/Characters/Player.blockymodel"
-	baseTexturePath = "assets/Characters/Player_Textures/Player_Greyscale.png"
-)
-
-// HeadAccessoryEntry extends registry entry with HeadAccessoryType
-type HeadAccessoryEntry struct {
-	ID                           string `json:"Id"`
-	HeadAccessoryType            string `json:"HeadAccessoryType"`
-	DisableCharacterPartCategory string `json:"DisableCharacterPartCategory"`
-}
-
-// HaircutEntry extends registry entry with HairType
-type HaircutEntry struct {
-	ID       string `json:"Id"`
-	HairType string `json:"HairType"`
-}
-
 // MergeService handles character merging operations
 type MergeService struct {
-	registry         *registry.Registry
-	gradientSets     *texture.GradientSets
+	paths            Paths
+	files            *mappedFS // assets and data in the layout of an extracted assets.zip
 	baseModel        *blockymodel.BlockyModel
-	headAccessories  map[string]HeadAccessoryEntry
-	haircuts         map[string]HaircutEntry
 	haircutFallbacks map[string]string // HairType -> fallback haircut ID
+	catalog          *Catalog
+	rules            []Rule // slot removal and replacement rules, see applyRules
+	assets           *assetCache
+	assetVersion     string
+}
+
+// Options configures a MergeService
+type Options struct {
+	Paths         Paths            // asset and data locations, empty fields use the defaults
+	CacheMaxBytes int64            // memory budget for cached models and textures, 0 disables caching
+	Preload       bool             // load every model and texture into the cache at startup
+	Custom        *CustomCosmetics // uploaded cosmetics shared by every pack, nil disables uploads
 }
 
 // MergeResult contains the results of a merge operation
@@ -47,73 +37,139 @@ type MergeResult struct {
 	Model    *blockymodel.BlockyModel
 	Atlas    *texture.Atlas
 	GLBBytes []byte
+	Textures []TextureInfo // textures packed into the atlas, in packing order
+	Warnings []FieldIssue  // fallbacks and skipped parts applied while rendering
+}
+
+// TextureInfo describes where a texture packed into the atlas came from
+type TextureInfo struct {
+	Name        string // atlas entry name: accessory ID, or "_base" for the player texture
+	Category    string // character field, e.g. "haircut"
+	SourcePath  string // greyscale or pre-coloured texture path
+	GradientSet string // gradient set used for tinting, empty for direct textures
+	Color       string // gradient colour applied, empty for direct textures
 }
 
 // NewMergeService creates a new merge service with all required data loaded
-func NewMergeService() (*MergeService, error) {
-	// Load gradient sets for tinting
-	gradientSets, err := texture.LoadGradientSets()
+func NewMergeService(opts Options) (_ *MergeService, err error) {
+	// Map the asset directories or assets.zip into one file system
+	paths := opts.Paths.withDefaults()
+	files, err := paths.open()
 	if err != nil {
-		return nil, fmt.Errorf("loading gradient sets: %w", err)
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			files.Close()
+		}
+	}()
+	if opts.Custom != nil {
+		files.mount(customMount, os.DirFS(opts.Custom.dir), ".", opts.Custom.dir)
 	}
 
-	// Load accessory registry
-	reg, err := registry.Load()
-	if err != nil {
-		return nil, fmt.Errorf("loading registry: %w", err)
+	// Report every missing file at once rather than failing on the first
+	if err := checkFiles(files); err != nil {
+		return nil, err
 	}
 
 	// Load base player model
-	baseModel, err := blockymodel.Load(basePath)
+	baseModel, err := readModel(files, baseModelFile)
 	if err != nil {
 		return nil, fmt.Errorf("loading base model: %w", err)
 	}
 
-	// Load head accessories for HeadAccessoryType
-	headAccessories, err := loadHeadAccessories("data/HeadAccessory.json")
+	// Load haircut fallbacks
+	haircutFallbacks, err := loadHaircutFallbacks(files, haircutFallbacksFile)
 	if err != nil {
-		return nil, fmt.Errorf("loading head accessories: %w", err)
+		return nil, fmt.Errorf("loading haircut fallbacks: %w", err)
 	}
 
-	// Load haircuts for HairType
-	haircuts, err := loadHaircuts("data/Haircuts.json")
+	// Load cosmetics catalog and gradient sets
+	catalog, err := loadCatalog(files)
 	if err != nil {
-		return nil, fmt.Errorf("loading haircuts: %w", err)
+		return nil, fmt.Errorf("loading catalog: %w", err)
 	}
+	catalog.custom = opts.Custom
 
-	// Load haircut fallbacks
-	haircutFallbacks, err := loadHaircutFallbacks("data/HaircutFallbacks.json")
+	// Build the rules from the registry and the optional rules file
+	rules, err := loadRules(files, catalog)
 	if err != nil {
-		return nil, fmt.Errorf("loading haircut fallbacks: %w", err)
+		return nil, fmt.Errorf("loading rules: %w", err)
 	}
 
-	return &MergeService{
-		registry:         reg,
-		gradientSets:     gradientSets,
+	// Fingerprint the assets so caches can tell versions apart
+	assetVersion, err := fingerprintAssets(paths.roots()...)
+	if err != nil {
+		return nil, fmt.Errorf("fingerprinting assets: %w", err)
+	}
+
+	svc := &MergeService{
+		paths:            paths,
+		files:            files,
 		baseModel:        baseModel,
-		headAccessories:  headAccessories,
-		haircuts:         haircuts,
 		haircutFallbacks: haircutFallbacks,
-	}, nil
+		catalog:          catalog,
+		rules:            rules,
+		assets:           newAssetCache(opts.CacheMaxBytes),
+		assetVersion:     assetVersion,
+	}
+
+	if opts.Preload {
+		svc.preloadAssets()
+	}
+
+	return svc, nil
+}
+
+// Close releases the assets.zip the service reads from. The service must
+// not be used afterwards.
+func (s *MergeService) Close() error {
+	return s.files.Close()
+}
+
+// Catalog returns the cosmetics catalog loaded from the registry files
+func (s *MergeService) Catalog() *Catalog {
+	return s.catalog
+}
+
+// Custom returns the store of uploaded cosmetics, nil if uploads are disabled
+func (s *MergeService) Custom() *CustomCosmetics {
+	return s.catalog.custom
+}
+
+// MergeOptions controls how a character is merged
+type MergeOptions struct {
+	Strict bool   // fail with a ValidationError instead of rendering with warnings
+	Format string // character format, see ImportCharacter; default detected
 }
 
 // MergeFromJSON merges a character from JSON data and returns the result
-func (s *MergeService) MergeFromJSON(charJSON []byte) (*MergeResult, error) {
-	// Parse character data
-	var charData character.CharacterData
-	if err := json.Unmarshal(charJSON, &charData); err != nil {
-		return nil, fmt.Errorf("parsing character JSON: %w", err)
+func (s *MergeService) MergeFromJSON(charJSON []byte, opts MergeOptions) (*MergeResult, error) {
+	// Convert characters saved by the game
+	charJSON, imported, err := s.ImportCharacter(charJSON, opts.Format)
+	if err != nil {
+		return nil, err
 	}
 
-	// Apply haircut fallback if headAccessory requires it
-	s.applyHaircutFallback(&charData)
-
-	// Resolve accessories
-	result, err := charData.ResolveAccessories(s.registry)
+	// Resolve the character into the accessories and textures actually rendered
+	res, err := s.Resolve(charJSON)
 	if err != nil {
-		return nil, fmt.Errorf("resolving accessories: %w", err)
+		return nil, err
 	}
+	if len(imported) > 0 {
+		res.Warnings = append(imported, res.Warnings...)
+	}
+	if opts.Strict && len(res.Warnings) > 0 {
+		return nil, &ValidationError{Issues: res.Warnings}
+	}
+
+	return s.merge(res, false)
+}
 
+// merge merges the accessories of a resolution onto the base model, packs
+// their textures into an atlas and exports the result to GLB. With hideBase
+// the base model keeps its bones but none of its geometry.
+func (s *MergeService) merge(res *Resolution, hideBase bool) (*MergeResult, error) {
 	// Create merger from base model
 	m, err := merger.New(s.baseModel)
 	if err != nil {
@@ -121,8 +177,8 @@ func (s *MergeService) MergeFromJSON(charJSON []byte) (*MergeResult, error) {
 	}
 
 	// Merge each accessory
-	for _, acc := range result.Accessories {
-		accessory, err := blockymodel.Load(acc.Path)
+	for _, acc := range res.paths {
+		accessory, err := s.loadModel(acc.Path)
 		if err != nil {
 			return nil, fmt.Errorf("loading accessory %s: %w", acc.Path, err)
 		}
@@ -134,71 +190,11 @@ func (s *MergeService) MergeFromJSON(charJSON []byte) (*MergeResult, error) {
 
 	// Get merged model
 	mergedModel := m.Result()
-
-	// Process textures
-	var tintedTextures []*texture.TintedTexture
-	skinTone := charData.GetSkinTone()
-
-	// Load and tint base player texture
-	if skinTone != "" {
-		baseTinted, err := texture.ProcessAccessoryTexture(
-			"_base",
-			baseTexturePath,
-			"Skin",
-			skinTone,
-			s.gradientSets,
-		)
-		if err == nil {
-			tintedTextures = append(tintedTextures, baseTinted)
-		}
-	} else {
-		baseImg, err := texture.LoadImage(baseTexturePath)
-		if err == nil {
-			baseTex := &texture.TintedTexture{
-				Name:         "_base",
-				Image:        baseImg,
-				OriginalPath: baseTexturePath,
-			}
-			tintedTextures = append(tintedTextures, baseTex)
-		}
+	if hideBase {
+		hideGeometry(mergedModel.Nodes, m.NodeSources)
 	}
 
-	// Process accessory textures
-	for _, acc := range result.Accessories {
-		if acc.ResolvedTexture == nil {
-			continue
-		}
-
-		var tinted *texture.TintedTexture
-
-		if acc.ResolvedTexture.DirectTexture != "" {
-			img, err := texture.LoadImage(acc.ResolvedTexture.DirectTexture)
-			if err != nil {
-				continue
-			}
-			tinted = &texture.TintedTexture{
-				Name:         acc.Spec.ID,
-				Image:        img,
-				OriginalPath: acc.ResolvedTexture.DirectTexture,
-			}
-		} else if acc.ResolvedTexture.GreyscaleTexture != "" {
-			var err error
-			tinted, err = texture.ProcessAccessoryTexture(
-				acc.Spec.ID,
-				acc.ResolvedTexture.GreyscaleTexture,
-				acc.ResolvedTexture.GradientSet,
-				acc.Spec.Color,
-				s.gradientSets,
-			)
-			if err != nil {
-				continue
-			}
-		} else {
-			continue
-		}
-
-		tintedTextures = append(tintedTextures, tinted)
-	}
+	tintedTextures := res.tinted
 
 	// Pack textures into atlas
 	var atlas *texture.Atlas
@@ -268,121 +264,14 @@ func (s *MergeService) MergeFromJSON(charJSON []byte) (*MergeResult, error) {
 		Model:    mergedModel,
 		Atlas:    atlas,
 		GLBBytes: glbBytes,
+		Textures: res.Textures,
+		Warnings: res.Warnings,
 	}, nil
 }
 
-// applyHaircutFallback modifies haircut based on headAccessory type
-func (s *MergeService) applyHaircutFallback(charData *character.CharacterData) {
-	if charData.HeadAccessory == nil || *charData.HeadAccessory == "" {
-		return
-	}
-
-	// Parse head accessory ID
-	headAccID := strings.Split(*charData.HeadAccessory, ".")[0]
-	headAcc, ok := s.headAccessories[headAccID]
-	if !ok {
-		return
-	}
-
-	// Check if headAccessory disables haircut entirely
-	if headAcc.DisableCharacterPartCategory == "Haircut" {
-		charData.Haircut = nil
-		return
-	}
-
-	// Check headAccessory type
-	switch headAcc.HeadAccessoryType {
-	case "FullyCovering":
-		// No hair visible
-		charData.Haircut = nil
-	case "HalfCovering":
-		// Use fallback hairstyle
-		if charData.Haircut != nil && *charData.Haircut != "" {
-			s.setFallbackHaircut(charData)
-		}
-	}
-	// "Simple" or empty: keep original haircut
-}
-
-// setFallbackHaircut replaces haircut with appropriate fallback based on HairType
-func (s *MergeService) setFallbackHaircut(charData *character.CharacterData) {
-	if charData.Haircut == nil || *charData.Haircut == "" {
-		return
-	}
-
-	// Parse haircut spec (ID.Color.Variant)
-	parts := strings.Split(*charData.Haircut, ".")
-	haircutID := parts[0]
-	color := ""
-	if len(parts) > 1 {
-		color = parts[1]
-	}
-
-	// Get haircut entry to find HairType
-	haircut, ok := s.haircuts[haircutID]
-	if !ok {
-		return
-	}
-
-	// Get fallback haircut ID for this HairType
-	fallbackID, ok := s.haircutFallbacks[haircut.HairType]
-	if !ok {
-		return
-	}
-
-	// Build new haircut string with fallback ID but same color
-	newHaircut := fallbackID
-	if color != "" {
-		newHaircut = fallbackID + "." + color
-	}
-	charData.Haircut = &newHaircut
-}
-
-// loadHeadAccessories loads head accessory data from JSON file
-func loadHeadAccessories(path string) (map[string]HeadAccessoryEntry, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-
-	var entries []HeadAccessoryEntry
-	if err := json.Unmarshal(data, &entries); err != nil {
-		return nil, err
-	}
-
-	result := make(map[string]HeadAccessoryEntry)
-	for _, e := range entries {
-		if e.ID != "" {
-			result[e.ID] = e
-		}
-	}
-	return result, nil
-}
-
-// loadHaircuts loads haircut data from JSON file
-func loadHaircuts(path string) (map[string]HaircutEntry, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-
-	var entries []HaircutEntry
-	if err := json.Unmarshal(data, &entries); err != nil {
-		return nil, err
-	}
-
-	result := make(map[string]HaircutEntry)
-	for _, e := range entries {
-		if e.ID != "" {
-			result[e.ID] = e
-		}
-	}
-	return result, nil
-}
-
-// loadHaircutFallbacks loads haircut fallback mappings from JSON file
-func loadHaircutFallbacks(path string) (map[string]string, error) {
-	data, err := os.ReadFile(path)
+// loadHaircutFallbacks loads haircut fallback mappings from a JSON file
+func loadHaircutFallbacks(files fs.FS, path string) (map[string]string, error) {
+	data, err := fs.ReadFile(files, path)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hytale-tools/blockymodel-merger/pkg/character"
+)
+
+// Character formats accepted by ImportCharacter
+const (
+	FormatAuto   = "auto"   // detect the format from the JSON
+	FormatBlocky = "blocky" // CharacterConfig: camelCase fields with "Id.Color.Variant" strings
+	FormatHytale = "hytale" // the game's saved player skin, experimental, see importHytale
+)
+
+// ReasonNotImported is reported for native fields that have no character field
+const ReasonNotImported = "not_imported"
+
+// ErrUnknownFormat is returned for character formats other than the Format constants
+var ErrUnknownFormat = errors.New("unknown character format")
+
+// Keys of the native format. The wrapper object is what auto-detection
+// looks for; the others are matched case-insensitively.
+const (
+	hytaleWrapper    = "PlayerSkin"
+	hytaleIDKey      = "Id"
+	hytaleColorKey   = "Color"
+	hytaleVariantKey = "Variant"
+	hytaleSkinKey    = "SkinTone"
+)
+
+// ImportCharacter converts character JSON in the given format into
+// CharacterConfig JSON. Native fields that could not be mapped are returned
+// as warnings. An empty format is detected like FormatAuto.
+func (s *MergeService) ImportCharacter(charJSON []byte, format string) ([]byte, []FieldIssue, error) {
+	switch format {
+	case "", FormatAuto:
+		if !isHytaleCharacter(charJSON) {
+			return charJSON, nil, nil
+		}
+	case FormatBlocky:
+		return charJSON, nil, nil
+	case FormatHytale:
+	default:
+		return nil, nil, fmt.Errorf("%w %q, expected %s, %s or %s", ErrUnknownFormat, format, FormatAuto, FormatBlocky, FormatHytale)
+	}
+	return s.importHytale(charJSON)
+}
+
+// isHytaleCharacter reports whether character JSON is in the native format,
+// i.e. wrapped in a PlayerSkin object. Unwrapped native characters must be
+// selected with FormatHytale, as they cannot be told apart from CharacterConfig
+// with unknown fields.
+func isHytaleCharacter(charJSON []byte) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(charJSON, &fields); err != nil {
+		return false
+	}
+	_, ok := fields[hytaleWrapper]
+	return ok
+}
+
+// importHytale converts the game's saved player skin. Field names are
+// matched case-insensitively against the character fields and the registry
+// file names ("Haircut" or "Haircuts" for haircut), optionally nested in a
+// "PlayerSkin" object. A cosmetic is an "Id.Color.Variant" string or an
+// object such as {"Id": "Scavenger_Hair", "Color": "PitchBlack"}. A separate
+// SkinTone becomes the color of bodyCharacteristic. No file saved by the
+// game was available to check this layout against.
+func (s *MergeService) importHytale(charJSON []byte) ([]byte, []FieldIssue, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(charJSON, &fields); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+
+	// Unwrap the skin object, keeping fields next to it
+	if raw, ok := fields[hytaleWrapper]; ok {
+		var inner map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &inner); err != nil {
+			return nil, nil, fmt.Errorf("%w: %s is not an object", ErrInvalidJSON, hytaleWrapper)
+		}
+		delete(fields, hytaleWrapper)
+		for k, v := range inner {
+			fields[k] = v
+		}
+	}
+
+	result := make(map[string]string)
+	var warnings []FieldIssue
+	var skinTone string
+	for _, key := range sortedKeys(fields) {
+		raw := fields[key]
+		if strings.EqualFold(key, hytaleSkinKey) {
+			skinTone, _ = s.hytaleName(raw, "bodyCharacteristic", "")
+			continue
+		}
+
+		field, ok := fieldForPart(key)
+		if !ok {
+			warnings = append(warnings, FieldIssue{
+				Field:   key,
+				Value:   strings.TrimSpace(string(raw)),
+				Reason:  ReasonNotImported,
+				Message: fmt.Sprintf("%s has no matching character field", key),
+			})
+			continue
+		}
+
+		value, issues := s.hytaleCosmetic(key, field, raw)
+		warnings = append(warnings, issues...)
+		if value != "" {
+			result[field] = value
+		}
+	}
+
+	if skinTone != "" {
+		body := character.ParseAccessorySpec(result["bodyCharacteristic"])
+		if body.ID == "" {
+			body.ID = "Default"
+		}
+		if body.Color == "" {
+			body.Color = skinTone
+		}
+		result["bodyCharacteristic"] = joinSpec(body)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, warnings, nil
+}
+
+// hytaleCosmetic converts a native cosmetic value into "Id.Color.Variant"
+func (s *MergeService) hytaleCosmetic(key, field string, raw json.RawMessage) (string, []FieldIssue) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil || obj == nil {
+		value, ok := s.hytaleName(raw, field, "")
+		if !ok {
+			return "", []FieldIssue{{
+				Field:   field,
+				Value:   strings.TrimSpace(string(raw)),
+				Reason:  ReasonNotImported,
+				Message: fmt.Sprintf("%s is neither a string nor a cosmetic object", key),
+			}}
+		}
+		return value, nil
+	}
+
+	// The ID comes first, numeric colors are looked up among its colors
+	var spec character.AccessorySpec
+	for _, sub := range sortedKeys(obj) {
+		if strings.EqualFold(sub, hytaleIDKey) {
+			spec.ID, _ = s.hytaleName(obj[sub], field, "")
+		}
+	}
+
+	var issues []FieldIssue
+	for _, sub := range sortedKeys(obj) {
+		var dst *string
+		switch {
+		case strings.EqualFold(sub, hytaleIDKey):
+			continue
+		case strings.EqualFold(sub, hytaleColorKey):
+			dst = &spec.Color
+		case strings.EqualFold(sub, hytaleVariantKey):
+			dst = &spec.Variant
+		default:
+			issues = append(issues, FieldIssue{
+				Field:   field,
+				Value:   strings.TrimSpace(string(obj[sub])),
+				Reason:  ReasonNotImported,
+				Message: fmt.Sprintf("%s.%s has no equivalent", key, sub),
+			})
+			continue
+		}
+		*dst, _ = s.hytaleName(obj[sub], field, spec.ID)
+	}
+
+	if spec.ID == "" {
+		if spec.Color != "" || spec.Variant != "" {
+			issues = append(issues, FieldIssue{
+				Field:   field,
+				Reason:  ReasonNotImported,
+				Message: fmt.Sprintf("%s has no Id", key),
+			})
+		}
+		return "", issues
+	}
+	return joinSpec(spec), issues
+}
+
+// hytaleName reads a native string or number. Numbers are colors or skin
+// tones; they are matched against the item's colors with and without zero
+// padding, so 2 becomes "02" where that is the name.
+func (s *MergeService) hytaleName(raw json.RawMessage, field, id string) (string, bool) {
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		return str, true
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return "", string(raw) == "null"
+	}
+	i, err := n.Int64()
+	if err != nil {
+		return n.String(), true
+	}
+
+	item, _ := s.catalog.Item(field, id)
+	colors := s.itemColors(field, item)
+	for _, name := range []string{strconv.FormatInt(i, 10), fmt.Sprintf("%02d", i)} {
+		if containsString(colors, name) {
+			return name, true
+		}
+	}
+	return strconv.FormatInt(i, 10), true
+}
+
+// itemColors returns the named colors of an item. The color of a body
+// characteristic is the skin tone.
+func (s *MergeService) itemColors(field string, item *CatalogItem) []string {
+	if field == "bodyCharacteristic" {
+		return s.catalog.GradientColors("Skin")
+	}
+	if item == nil {
+		return nil
+	}
+	return item.Colors
+}
+
+// joinSpec formats an "Id.Color.Variant" value, leaving the color empty if
+// only a variant is set
+func joinSpec(spec character.AccessorySpec) string {
+	value := spec.ID
+	if spec.Color != "" || spec.Variant != "" {
+		value += "." + spec.Color
+	}
+	if spec.Variant != "" {
+		value += "." + spec.Variant
+	}
+	return value
+}
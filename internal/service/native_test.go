@@ -0,0 +1,134 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestImportCharacter(t *testing.T) {
+	svc := newTestService(t)
+
+	tests := []struct {
+		name        string
+		format      string
+		character   string
+		want        map[string]string // nil if the character is passed through unchanged
+		notImported []string          // fields reported as not_imported, in order
+	}{
+		{
+			name:      "auto keeps CharacterConfig",
+			format:    FormatAuto,
+			character: `{"haircut":"Scavenger_Hair.Brown"}`,
+		},
+		{
+			name:      "auto keeps unwrapped PascalCase fields",
+			format:    FormatAuto,
+			character: `{"Haircut":{"Id":"Scavenger_Hair","Color":"Brown"},"SkinTone":2}`,
+		},
+		{
+			name:      "blocky keeps a wrapped skin",
+			format:    FormatBlocky,
+			character: `{"PlayerSkin":{"Haircut":"Scavenger_Hair.Brown"}}`,
+		},
+		{
+			name:      "auto detects the wrapper",
+			format:    "",
+			character: `{"PlayerSkin":{"Haircut":{"Id":"Scavenger_Hair","Color":"PitchBlack"},"HeadAccessory":"Hat_A.Red"}}`,
+			want:      map[string]string{"haircut": "Scavenger_Hair.PitchBlack", "headAccessory": "Hat_A.Red"},
+		},
+		{
+			name:      "hytale without wrapper",
+			format:    FormatHytale,
+			character: `{"Haircuts":"Scavenger_Hair.Brown","pants":{"id":"Pants_A","color":"Red","variant":"Short"}}`,
+			want:      map[string]string{"haircut": "Scavenger_Hair.Brown", "pants": "Pants_A.Red.Short"},
+		},
+		{
+			name:      "numeric skin tone is zero-padded",
+			format:    FormatHytale,
+			character: `{"PlayerSkin":{"BodyCharacteristic":{"Id":"Default"},"SkinTone":2}}`,
+			want:      map[string]string{"bodyCharacteristic": "Default.02"},
+		},
+		{
+			name:      "skin tone without body",
+			format:    FormatHytale,
+			character: `{"PlayerSkin":{"SkinTone":"01"}}`,
+			want:      map[string]string{"bodyCharacteristic": "Default.01"},
+		},
+		{
+			name:      "variant without color",
+			format:    FormatHytale,
+			character: `{"Pants":{"Id":"Pants_A","Variant":"Long"}}`,
+			want:      map[string]string{"pants": "Pants_A..Long"},
+		},
+		{
+			name:        "unmapped fields are reported",
+			format:      FormatHytale,
+			character:   `{"PlayerSkin":{"Haircut":{"Id":"Scavenger_Hair","Shine":1},"Voice":"Deep","Cape":true}}`,
+			want:        map[string]string{"haircut": "Scavenger_Hair"},
+			notImported: []string{"cape", "haircut", "Voice"},
+		},
+		{
+			name:        "cosmetic without an ID",
+			format:      FormatHytale,
+			character:   `{"Haircut":{"Color":"Brown"}}`,
+			want:        map[string]string{},
+			notImported: []string{"haircut"},
+		},
+		{
+			name:        "only PlayerSkin is a wrapper",
+			format:      FormatHytale,
+			character:   `{"Appearance":{"Haircut":"Scavenger_Hair.Brown"}}`,
+			want:        map[string]string{},
+			notImported: []string{"Appearance"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, warnings, err := svc.ImportCharacter([]byte(tt.character), tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if string(data) != tt.character || len(warnings) > 0 {
+					t.Errorf("got %s with warnings %+v, want the character unchanged", data, warnings)
+				}
+				return
+			}
+
+			var got map[string]string
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			var fields []string
+			for _, w := range warnings {
+				if w.Reason != ReasonNotImported {
+					t.Errorf("got reason %s, want %s", w.Reason, ReasonNotImported)
+				}
+				fields = append(fields, w.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.notImported) {
+				t.Errorf("got not imported %v, want %v", fields, tt.notImported)
+			}
+		})
+	}
+}
+
+func TestImportCharacterErrors(t *testing.T) {
+	svc := newTestService(t)
+
+	if _, _, err := svc.ImportCharacter([]byte(`{}`), "json"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("got %v, want ErrUnknownFormat", err)
+	}
+	if _, _, err := svc.ImportCharacter([]byte(`{"PlayerSkin":"Default"}`), FormatHytale); !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("got %v, want ErrInvalidJSON", err)
+	}
+	if _, _, err := svc.ImportCharacter([]byte(`[1]`), FormatHytale); !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("got %v, want ErrInvalidJSON", err)
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// DefaultPackName names the only pack when no packs are configured
+const DefaultPackName = "default"
+
+// PackSpec names an asset pack and where its files are
+type PackSpec struct {
+	Name  string
+	Paths Paths
+}
+
+// Packs holds one Reloader per asset pack, so characters saved under
+// older releases can be rendered with the cosmetics of that release
+type Packs struct {
+	names       []string // in configuration order
+	packs       map[string]*Reloader
+	defaultPack string
+}
+
+// NewPacks loads every pack. opts configures caching for each pack; its
+// Paths are replaced by the pack's. An empty defaultPack selects the first pack.
+func NewPacks(specs []PackSpec, defaultPack string, opts Options) (*Packs, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no asset packs configured")
+	}
+	if defaultPack == "" {
+		defaultPack = specs[0].Name
+	}
+
+	p := &Packs{packs: make(map[string]*Reloader), defaultPack: defaultPack}
+	for _, spec := range specs {
+		if _, ok := p.packs[spec.Name]; ok {
+			return nil, fmt.Errorf("asset pack %q is configured twice", spec.Name)
+		}
+
+		packOpts := opts
+		packOpts.Paths = spec.Paths
+		r, err := NewReloader(spec.Name, packOpts)
+		if err != nil {
+			return nil, fmt.Errorf("loading asset pack %s: %w", spec.Name, err)
+		}
+		p.names = append(p.names, spec.Name)
+		p.packs[spec.Name] = r
+	}
+
+	if _, ok := p.packs[defaultPack]; !ok {
+		return nil, fmt.Errorf("default asset pack %q is not configured", defaultPack)
+	}
+	return p, nil
+}
+
+// Names returns the pack names in configuration order
+func (p *Packs) Names() []string {
+	return p.names
+}
+
+// Default returns the name of the pack used when a request does not pick one
+func (p *Packs) Default() string {
+	return p.defaultPack
+}
+
+// Get returns the Reloader of a pack, or of the default pack if name is empty
+func (p *Packs) Get(name string) (*Reloader, bool) {
+	if name == "" {
+		name = p.defaultPack
+	}
+	r, ok := p.packs[name]
+	return r, ok
+}
+
+// Reload reloads every pack and returns the first error. Packs that fail
+// keep serving their previous assets.
+func (p *Packs) Reload() error {
+	var first error
+	for _, name := range p.names {
+		if err := p.packs[name].Reload(); err != nil && first == nil {
+			first = fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return first
+}
+
+// Watch polls every pack for changes, see Reloader.Watch
+func (p *Packs) Watch(interval time.Duration, stop <-chan struct{}) {
+	for _, name := range p.names {
+		go p.packs[name].Watch(interval, stop)
+	}
+	log.Printf("Watching %d asset pack(s) for changes every %s", len(p.names), interval)
+}
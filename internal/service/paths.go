@@ -0,0 +1,186 @@
+package service
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Files read by the service, in the layout of an extracted assets.zip.
+// Every asset path reported by the service starts with "assets/".
+const (
+	assetPrefix          = "assets/"
+	baseModelFile        = "assets/Characters/Player.blockymodel"
+	baseTextureFile      = "assets/Characters/Player_Textures/Player_Greyscale.png"
+	headAccessoriesFile  = "data/HeadAccessory.json"
+	haircutsFile         = "data/Haircuts.json"
+	haircutFallbacksFile = "data/HaircutFallbacks.json"
+	gradientSetsFile     = "data/GradientSets.json"
+	rulesFile            = "data/CharacterRules.json" // optional
+)
+
+// zipLayout maps the folders of the official assets.zip to the layout the
+// service reads, like blockymodel-merger's extract-assets tool does
+var zipLayout = []struct {
+	Name string // folder in the service layout
+	Dir  string // folder in assets.zip
+}{
+	{"assets/Characters", "Common/Characters"},
+	{"assets/Cosmetics", "Common/Cosmetics"},
+	{"assets/TintGradients", "Common/TintGradients"},
+	{"data", "Cosmetics/CharacterCreator"},
+}
+
+// Paths locates the asset and data files, either in extracted directories
+// or in the official assets.zip. The individual files replace the ones
+// found there.
+type Paths struct {
+	AssetsZip        string // Hytale server assets.zip, takes precedence over AssetsDir and DataDir
+	AssetsDir        string // root of Characters/, Cosmetics/ and TintGradients/, default "assets"
+	DataDir          string // directory of the registry JSON files, default "data"
+	BaseModel        string // replaces assets/Characters/Player.blockymodel
+	BaseTexture      string // replaces assets/Characters/Player_Textures/Player_Greyscale.png
+	HeadAccessories  string // replaces data/HeadAccessory.json
+	Haircuts         string // replaces data/Haircuts.json
+	HaircutFallbacks string // replaces data/HaircutFallbacks.json
+	GradientSets     string // replaces data/GradientSets.json
+	Rules            string // replaces data/CharacterRules.json
+}
+
+// withDefaults fills in the default directories
+func (p Paths) withDefaults() Paths {
+	if p.AssetsDir == "" {
+		p.AssetsDir = "assets"
+	}
+	if p.DataDir == "" {
+		p.DataDir = "data"
+	}
+	return p
+}
+
+// overrides pairs each individual file with the file it replaces
+func (p Paths) overrides() []struct{ Name, Path string } {
+	return []struct{ Name, Path string }{
+		{baseModelFile, p.BaseModel},
+		{baseTextureFile, p.BaseTexture},
+		{headAccessoriesFile, p.HeadAccessories},
+		{haircutsFile, p.Haircuts},
+		{haircutFallbacksFile, p.HaircutFallbacks},
+		{gradientSetsFile, p.GradientSets},
+		{rulesFile, p.Rules},
+	}
+}
+
+// open builds the file system the service reads from. An assets.zip stays
+// open until the file system is closed.
+func (p Paths) open() (*mappedFS, error) {
+	files := &mappedFS{}
+
+	if p.AssetsZip != "" {
+		archive, err := zip.OpenReader(p.AssetsZip)
+		if err != nil {
+			return nil, fmt.Errorf("opening %s: %w", p.AssetsZip, err)
+		}
+		files.closers = append(files.closers, archive)
+		for _, l := range zipLayout {
+			files.mount(l.Name, archive, l.Dir, p.AssetsZip+":"+l.Dir)
+		}
+	} else {
+		files.mount("assets", os.DirFS(p.AssetsDir), ".", p.AssetsDir)
+		files.mount("data", os.DirFS(p.DataDir), ".", p.DataDir)
+	}
+
+	for _, o := range p.overrides() {
+		if o.Path != "" {
+			files.mount(o.Name, os.DirFS(filepath.Dir(o.Path)), filepath.Base(o.Path), o.Path)
+		}
+	}
+	return files, nil
+}
+
+// roots returns the directories and files on disk whose contents determine
+// the asset version
+func (p Paths) roots() []string {
+	roots := []string{p.AssetsDir, p.DataDir}
+	if p.AssetsZip != "" {
+		roots = []string{p.AssetsZip}
+	}
+	for _, o := range p.overrides() {
+		if o.Path != "" {
+			roots = append(roots, o.Path)
+		}
+	}
+	return roots
+}
+
+// MissingFile is a required file or directory that does not exist
+type MissingFile struct {
+	Name string // what the file is for, e.g. "base model"
+	Path string // where it was looked for
+}
+
+// MissingFilesError lists every required file that is missing at startup
+type MissingFilesError struct {
+	Files []MissingFile
+}
+
+func (e *MissingFilesError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d required files are missing:", len(e.Files))
+	for _, f := range e.Files {
+		fmt.Fprintf(&b, "\n  %-20s %s", f.Name, f.Path)
+	}
+	return b.String()
+}
+
+// checkFiles reports every required file that does not exist. Registry
+// files for individual categories are optional and not checked, and the
+// rules file only when one is given.
+func checkFiles(files *mappedFS) error {
+	required := []MissingFile{
+		{"base model", baseModelFile},
+		{"base texture", baseTextureFile},
+		{"head accessories", headAccessoriesFile},
+		{"haircuts", haircutsFile},
+		{"haircut fallbacks", haircutFallbacksFile},
+		{"gradient sets", gradientSetsFile},
+	}
+	isRequired := func(name string) bool {
+		for _, r := range required {
+			if r.Path == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Mounted folders first; replaced files are reported below
+	var missing []MissingFile
+	for _, m := range files.mounts {
+		if isRequired(m.name) {
+			continue
+		}
+		if _, err := fs.Stat(m.fsys, m.dir); err != nil {
+			name := m.name + "/"
+			if m.name == rulesFile {
+				name = "rules"
+			}
+			missing = append(missing, MissingFile{Name: name, Path: m.source})
+		}
+	}
+
+	for _, r := range required {
+		info, err := fs.Stat(files, r.Path)
+		if err != nil || info.IsDir() {
+			missing = append(missing, MissingFile{Name: r.Name, Path: files.where(r.Path)})
+		}
+	}
+
+	if len(missing) > 0 {
+		return &MissingFilesError{Files: missing}
+	}
+	return nil
+}
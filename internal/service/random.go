@@ -0,0 +1,216 @@
+package service
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// defaultFillProbability is the chance of each character field being filled
+// when no categories are requested. Fields not listed are never filled.
+var defaultFillProbability = map[string]float64{
+	"bodyCharacteristic": 1,
+	"underwear":          1,
+	"face":               1,
+	"ears":               1,
+	"mouth":              1,
+	"haircut":            1,
+	"facialHair":         0.3,
+	"eyebrows":           1,
+	"eyes":               1,
+	"pants":              1,
+	"overpants":          0.2,
+	"undertop":           1,
+	"overtop":            0.5,
+	"shoes":              1,
+	"headAccessory":      0.3,
+	"faceAccessory":      0.2,
+	"earAccessory":       0.2,
+	"gloves":             0.2,
+	"cape":               0.15,
+}
+
+// RandomOptions controls random character generation
+type RandomOptions struct {
+	Seed          int64
+	Categories    []string            // fields to fill, default every field in defaultFillProbability
+	Probabilities map[string]float64  // field -> chance of being filled, default 1 for requested categories
+	Palettes      map[string][]string // gradient set -> allowed colors, e.g. "Hair" -> ["PitchBlack"]
+	Locked        map[string]string   // field -> fixed "Id.Color.Variant" value
+}
+
+// Random builds a valid character from the catalog. The same options always
+// give the same character. The rules are applied to the result, so it
+// describes what is actually rendered.
+func (s *MergeService) Random(opts RandomOptions) (map[string]string, error) {
+	// Locked fields must be valid on their own
+	var issues []FieldIssue
+	for _, field := range sortedKeys(opts.Locked) {
+		value := opts.Locked[field]
+		if !containsCategory(field) {
+			issues = append(issues, FieldIssue{
+				Field:   field,
+				Value:   value,
+				Reason:  ReasonUnknownField,
+				Message: fmt.Sprintf("unknown field %q", field),
+			})
+			continue
+		}
+		if issue := s.validateField(field, value); issue != nil {
+			issues = append(issues, *issue)
+		}
+	}
+	for _, field := range opts.Categories {
+		if !containsCategory(field) {
+			issues = append(issues, FieldIssue{
+				Field:   field,
+				Reason:  ReasonUnknownField,
+				Message: fmt.Sprintf("unknown category %q", field),
+			})
+		}
+	}
+	if len(issues) > 0 {
+		return nil, &ValidationError{Issues: issues}
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	result := make(map[string]string)
+
+	// Walk the fields in a fixed order so the seed fully determines the result
+	for _, cf := range categoryFiles {
+		field := cf.Category
+		if value, ok := opts.Locked[field]; ok {
+			result[field] = value
+			continue
+		}
+
+		probability, ok := opts.Probabilities[field]
+		if !ok {
+			if len(opts.Categories) > 0 {
+				probability = 0
+				if containsString(opts.Categories, field) {
+					probability = 1
+				}
+			} else {
+				probability = defaultFillProbability[field]
+			}
+		}
+		if probability <= 0 || rng.Float64() >= probability {
+			continue
+		}
+
+		if value, ok := s.randomValue(rng, field, opts); ok {
+			result[field] = value
+		}
+	}
+
+	// Apply the rules so the result describes what is rendered
+	s.applyRules(result)
+	return result, nil
+}
+
+// randomValue picks a random cosmetic, color and variant for a field
+func (s *MergeService) randomValue(rng *rand.Rand, field string, opts RandomOptions) (string, bool) {
+	if field == "bodyCharacteristic" {
+		return s.randomBody(rng, opts)
+	}
+
+	items, _ := s.catalog.Items(field)
+
+	var candidates []*CatalogItem
+	for _, item := range items {
+		// Locked fields must not be hidden by a random cosmetic
+		if s.hidesLocked(field, item.ID, opts.Locked) {
+			continue
+		}
+		if item.Model == "" && len(item.Variants) == 0 {
+			continue
+		}
+		if len(item.Colors) > 0 && len(allowedColors(item, opts.Palettes)) == 0 {
+			continue
+		}
+		candidates = append(candidates, item)
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	item := candidates[rng.Intn(len(candidates))]
+	parts := []string{item.ID}
+
+	if colors := allowedColors(item, opts.Palettes); len(colors) > 0 {
+		parts = append(parts, colors[rng.Intn(len(colors))])
+	}
+
+	// Items without a base model only exist as variants
+	if item.Model == "" {
+		if len(parts) == 1 {
+			parts = append(parts, "")
+		}
+		parts = append(parts, item.Variants[rng.Intn(len(item.Variants))].Name)
+	}
+
+	return strings.Join(parts, "."), true
+}
+
+// hidesLocked reports whether a cosmetic triggers a rule that would remove
+// one of the locked fields
+func (s *MergeService) hidesLocked(field, id string, locked map[string]string) bool {
+	for _, rule := range s.rulesFor(field, id) {
+		for _, target := range rule.Disable {
+			if _, ok := locked[target]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// randomBody picks a body characteristic and skin tone
+func (s *MergeService) randomBody(rng *rand.Rand, opts RandomOptions) (string, bool) {
+	id := "Default"
+	if items, ok := s.catalog.Items("bodyCharacteristic"); ok && len(items) > 0 {
+		id = items[rng.Intn(len(items))].ID
+	}
+
+	tones := s.catalog.GradientColors("Skin")
+	if palette, ok := opts.Palettes["Skin"]; ok {
+		tones = intersect(tones, palette)
+	}
+	if len(tones) == 0 {
+		return id, true
+	}
+	return id + "." + tones[rng.Intn(len(tones))], true
+}
+
+// allowedColors returns the item's colors permitted by the palettes of its
+// gradient set. Items whose gradient set has no palette accept all colors.
+func allowedColors(item *CatalogItem, palettes map[string][]string) []string {
+	palette, ok := palettes[item.GradientSet]
+	if !ok || item.GradientSet == "" {
+		return item.Colors
+	}
+	return intersect(item.Colors, palette)
+}
+
+// intersect returns the elements of sorted that are also in allowed, keeping
+// the order of sorted
+func intersect(sorted, allowed []string) []string {
+	var result []string
+	for _, v := range sorted {
+		if containsString(allowed, v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// containsCategory reports whether field is a known character field
+func containsCategory(field string) bool {
+	for _, cf := range categoryFiles {
+		if cf.Category == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReloadStatus reports the loaded asset version and the outcome of the last reload
+type ReloadStatus struct {
+	AssetVersion string    `json:"assetVersion"`
+	LoadedAt     time.Time `json:"loadedAt"`
+	LoadMs       int64     `json:"loadMs"`  // time taken to load the current version
+	Reloads      int       `json:"reloads"` // successful reloads since startup
+	LastAttempt  time.Time `json:"lastAttempt,omitempty"`
+	LastError    string    `json:"lastError,omitempty"` // error of the last attempt, empty if it succeeded
+}
+
+// Reloader holds the current MergeService and swaps in a new one when the
+// assets change. Requests keep the service they started with, so in-flight
+// requests finish on the old version, which is closed once they release it.
+type Reloader struct {
+	name    string // asset pack name, used in logs
+	opts    Options
+	current atomic.Pointer[MergeService]
+	reload  sync.Mutex // serializes reloads
+
+	mu     sync.Mutex
+	status ReloadStatus
+	users  map[*MergeService]int // callers holding a service, see Acquire
+}
+
+// NewReloader loads the initial MergeService of an asset pack
+func NewReloader(name string, opts Options) (*Reloader, error) {
+	start := time.Now()
+	svc, err := NewMergeService(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reloader{name: name, opts: opts, users: make(map[*MergeService]int)}
+	r.current.Store(svc)
+	r.status = ReloadStatus{
+		AssetVersion: svc.AssetVersion(),
+		LoadedAt:     time.Now(),
+		LoadMs:       time.Since(start).Milliseconds(),
+	}
+	return r, nil
+}
+
+// Current returns the MergeService serving new requests. Callers that keep
+// using it while a reload may happen must Acquire it instead.
+func (r *Reloader) Current() *MergeService {
+	return r.current.Load()
+}
+
+// Acquire returns the MergeService serving new requests and a function to
+// call once the caller is done with it. A service replaced by a reload stays
+// open until every caller holding it has released it.
+func (r *Reloader) Acquire() (*MergeService, func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	svc := r.current.Load()
+	r.users[svc]++
+
+	var once sync.Once
+	return svc, func() { once.Do(func() { r.release(svc) }) }
+}
+
+func (r *Reloader) release(svc *MergeService) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.users[svc]--
+	if r.users[svc] > 0 {
+		return
+	}
+	delete(r.users, svc)
+	if svc != r.current.Load() {
+		r.closeService(svc)
+	}
+}
+
+// retire closes a service replaced by a reload, unless it is still in use,
+// in which case its last user closes it
+func (r *Reloader) retire(svc *MergeService) {
+	if r.users[svc] == 0 {
+		r.closeService(svc)
+	}
+}
+
+func (r *Reloader) closeService(svc *MergeService) {
+	if err := svc.Close(); err != nil {
+		log.Printf("Closing previous version of pack %s: %v", r.name, err)
+	}
+}
+
+// Status returns the loaded asset version and the outcome of the last reload
+func (r *Reloader) Status() ReloadStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+// Reload builds a new MergeService from the files on disk, checks that it
+// can merge a character, and swaps it in. On failure the current service
+// keeps serving and the error is recorded in the status.
+func (r *Reloader) Reload() error {
+	r.reload.Lock()
+	defer r.reload.Unlock()
+
+	start := time.Now()
+	svc, err := NewMergeService(r.opts)
+	if err == nil {
+		err = svc.selfCheck()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.status.LastAttempt = start
+	if err != nil {
+		if svc != nil {
+			svc.Close()
+		}
+		r.status.LastError = err.Error()
+		log.Printf("Reload of pack %s failed, keeping asset version %s: %v", r.name, r.status.AssetVersion, err)
+		return err
+	}
+
+	r.retire(r.current.Swap(svc))
+	r.status.AssetVersion = svc.AssetVersion()
+	r.status.LoadedAt = time.Now()
+	r.status.LoadMs = time.Since(start).Milliseconds()
+	r.status.Reloads++
+	r.status.LastError = ""
+	log.Printf("Reloaded pack %s (version %s) in %s", r.name, svc.AssetVersion(), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// Watch polls the asset and data files every interval and reloads once
+// a change has settled, i.e. the fingerprint is the same on two polls in a row.
+// A version that failed to load is not retried until the files change again.
+func (r *Reloader) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	previous := r.Current().AssetVersion()
+	failed := ""
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		version, err := fingerprintAssets(r.Current().paths.roots()...)
+		if err != nil {
+			log.Printf("Watching pack %s: %v", r.name, err)
+			continue
+		}
+
+		settled := version == previous
+		previous = version
+		if !settled || version == r.Current().AssetVersion() || version == failed {
+			continue
+		}
+
+		log.Printf("Assets of pack %s changed, reloading", r.name)
+		if err := r.Reload(); err != nil {
+			failed = version
+		}
+	}
+}
+
+// selfCheck merges an empty character to make sure the base model and
+// textures load before the service is swapped in
+func (s *MergeService) selfCheck() error {
+	if _, err := s.MergeFromJSON([]byte("{}"), MergeOptions{}); err != nil {
+		return fmt.Errorf("self-check: %w", err)
+	}
+	return nil
+}
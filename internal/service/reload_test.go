@@ -0,0 +1,91 @@
+package service
+
+import (
+	"archive/zip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestZip packs testdata into the layout of the official assets.zip
+func writeTestZip(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "assets.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for dir, prefix := range map[string]string{"testdata/assets": "Common", "testdata/data": "Cosmetics/CharacterCreator"} {
+		err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, _ := filepath.Rel(dir, file)
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			w, err := zw.Create(prefix + "/" + filepath.ToSlash(rel))
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(data)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReloaderClosesReplacedService(t *testing.T) {
+	r, err := NewReloader("test", Options{Paths: Paths{AssetsZip: writeTestZip(t)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	character := []byte(`{"haircut":"Scavenger_Hair.Brown"}`)
+
+	// A request in flight keeps the old version open across the reload
+	old, release := r.Acquire()
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Current() == old {
+		t.Fatal("reload did not replace the service")
+	}
+	if _, err := old.MergeFromJSON(character, MergeOptions{}); err != nil {
+		t.Fatalf("old version failed before release: %v", err)
+	}
+	if len(old.files.closers) == 0 {
+		t.Fatal("old version closed before release")
+	}
+
+	release()
+	release() // releasing twice is harmless
+	if len(old.files.closers) != 0 {
+		t.Error("old version still open after release")
+	}
+
+	// Without users, a reload closes the replaced version right away
+	current := r.Current()
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(current.files.closers) != 0 {
+		t.Error("unused version still open after reload")
+	}
+
+	svc, release := r.Acquire()
+	defer release()
+	if _, err := svc.MergeFromJSON(character, MergeOptions{}); err != nil {
+		t.Errorf("current version failed: %v", err)
+	}
+}
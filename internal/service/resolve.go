@@ -0,0 +1,272 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+
+	"github.com/hytale-tools/blockymodel-merger/pkg/character"
+	"github.com/hytale-tools/blockymodel-merger/pkg/texture"
+)
+
+// Resolution is the normalized character that is actually rendered, after
+// fallbacks have been applied and every model and texture has been looked up
+type Resolution struct {
+	Character   map[string]string   // character field -> final "Id.Color.Variant" value
+	Accessories []ResolvedAccessory // accessories merged onto the base model, in merge order
+	Textures    []TextureInfo       // textures packed into the atlas, in packing order
+	Warnings    []FieldIssue        // fallbacks and skipped parts
+
+	paths  []character.AccessoryPath
+	tinted []*texture.TintedTexture
+}
+
+// ResolvedAccessory describes the files used for one accessory
+type ResolvedAccessory struct {
+	Category    string `json:"category"`
+	ID          string `json:"id"`
+	Color       string `json:"color,omitempty"`
+	Variant     string `json:"variant,omitempty"`
+	Model       string `json:"model"`
+	Texture     string `json:"texture,omitempty"`     // greyscale or pre-colored texture path, empty if missing
+	GradientSet string `json:"gradientSet,omitempty"` // gradient set used for tinting
+}
+
+// Resolve validates a character and resolves it into the accessories and
+// textures that would be rendered, without merging any geometry
+func (s *MergeService) Resolve(charJSON []byte) (*Resolution, error) {
+	// Reject characters with invalid fields before doing any work
+	validation, err := s.Validate(charJSON)
+	if err != nil {
+		return nil, err
+	}
+	if !validation.Valid {
+		return nil, &ValidationError{Issues: validation.Errors}
+	}
+
+	// Parse character data
+	var charData character.CharacterData
+	if err := json.Unmarshal(charJSON, &charData); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+
+	res := &Resolution{
+		Character: make(map[string]string),
+		Warnings:  []FieldIssue{},
+	}
+
+	// Unknown fields are not rendered
+	for _, issue := range validation.Warnings {
+		if issue.Reason == ReasonUnknownField {
+			res.Warnings = append(res.Warnings, issue)
+		}
+	}
+	res.Warnings = append(res.Warnings, s.normalizeCharacter(&charData)...)
+
+	for _, f := range characterFields(&charData) {
+		if f.Value != nil && *f.Value != "" {
+			res.Character[f.Name] = *f.Value
+		}
+	}
+
+	s.resolveAccessories(res, charData.GetSkinTone())
+	s.resolveTextures(res, charData.GetSkinTone())
+
+	for _, acc := range res.paths {
+		ra := ResolvedAccessory{
+			Category: acc.Type,
+			ID:       acc.Spec.ID,
+			Color:    acc.Spec.Color,
+			Variant:  acc.Spec.Variant,
+			Model:    acc.Path,
+		}
+		for _, info := range res.Textures {
+			if info.Name == acc.Spec.ID {
+				ra.Texture = info.SourcePath
+				ra.GradientSet = info.GradientSet
+			}
+		}
+		res.Accessories = append(res.Accessories, ra)
+	}
+
+	return res, nil
+}
+
+// normalizeCharacter applies the rules to a character and returns a warning
+// for every field it changed
+func (s *MergeService) normalizeCharacter(charData *character.CharacterData) []FieldIssue {
+	values := make(map[string]string)
+	for _, f := range characterFields(charData) {
+		if f.Value != nil && *f.Value != "" {
+			values[f.Name] = *f.Value
+		}
+	}
+
+	// Remove and replace the slots covered by other cosmetics
+	warnings := s.applyRules(values)
+	data, _ := json.Marshal(values)
+	*charData = character.CharacterData{}
+	json.Unmarshal(data, charData)
+
+	// Skin features are not part of the merged model
+	if charData.SkinFeature != nil && *charData.SkinFeature != "" {
+		warnings = append(warnings, FieldIssue{
+			Field:   "skinFeature",
+			Value:   *charData.SkinFeature,
+			Reason:  ReasonCategoryIgnored,
+			Message: "skin features are not rendered",
+		})
+		charData.SkinFeature = nil
+	}
+
+	return warnings
+}
+
+// mergeOrder is the order accessories are merged in: body parts first, then
+// clothing from the bottom layer up, then accessories. Skin features and body
+// characteristics are not merged.
+var mergeOrder = []string{
+	"face", "ears", "eyes", "eyebrows", "mouth", "facialHair", "haircut",
+	"underwear", "pants", "overpants", "undertop", "overtop", "shoes", "gloves", "cape",
+	"headAccessory", "faceAccessory", "earAccessory",
+}
+
+// resolveAccessories looks up the model and texture of every accessory in
+// the character. Accessories whose model is missing are skipped with a warning.
+func (s *MergeService) resolveAccessories(res *Resolution, skinTone string) {
+	for _, field := range mergeOrder {
+		value, ok := res.Character[field]
+		if !ok {
+			continue
+		}
+		warn := func(reason, message string) {
+			res.Warnings = append(res.Warnings, FieldIssue{Field: field, Value: value, Reason: reason, Message: message})
+		}
+
+		spec := character.ParseAccessorySpec(value)
+		item, ok := s.catalog.Item(field, spec.ID)
+		if !ok {
+			warn(ReasonUnknownID, fmt.Sprintf("unknown %s %q", field, spec.ID))
+			continue
+		}
+
+		path := item.modelPath(spec.Variant)
+		if path == "" {
+			warn(ReasonModelMissing, fmt.Sprintf("%s has no model", spec.ID))
+			continue
+		}
+		if _, err := fs.Stat(s.files, path); err != nil {
+			warn(ReasonModelMissing, fmt.Sprintf("model file not found: %s", path))
+			continue
+		}
+
+		// Skin-colored accessories follow the skin tone unless a color is given
+		if spec.Color == "" && item.GradientSet == "Skin" {
+			spec.Color = skinTone
+		}
+
+		resolved := item.resolveTexture(spec.Color, spec.Variant)
+		if resolved == nil {
+			warn(ReasonTextureMissing, fmt.Sprintf("%s has no texture (color: %s, variant: %s)", spec.ID, spec.Color, spec.Variant))
+		}
+
+		entry := item.entry
+		res.paths = append(res.paths, character.AccessoryPath{
+			Type:            field,
+			Spec:            spec,
+			Path:            path,
+			Entry:           &entry,
+			ResolvedTexture: resolved,
+		})
+	}
+}
+
+// resolveTextures loads and tints the base and accessory textures. Textures
+// that cannot be loaded are left out of the atlas and reported as warnings.
+func (s *MergeService) resolveTextures(res *Resolution, skinTone string) {
+	missing := func(field, value, path string, err error) {
+		res.Warnings = append(res.Warnings, FieldIssue{
+			Field:   field,
+			Value:   value,
+			Reason:  ReasonTextureMissing,
+			Message: fmt.Sprintf("texture %s could not be loaded: %v", path, err),
+		})
+	}
+
+	// Load and tint base player texture
+	if skinTone != "" {
+		baseTinted, err := s.tintTexture("_base", baseTextureFile, "Skin", skinTone)
+		if err != nil {
+			missing("bodyCharacteristic", res.Character["bodyCharacteristic"], baseTextureFile, err)
+		} else {
+			res.tinted = append(res.tinted, baseTinted)
+			res.Textures = append(res.Textures, TextureInfo{
+				Name:        "_base",
+				Category:    "bodyCharacteristic",
+				SourcePath:  baseTextureFile,
+				GradientSet: "Skin",
+				Color:       skinTone,
+			})
+		}
+	} else {
+		baseImg, err := s.loadImage(baseTextureFile)
+		if err != nil {
+			missing("bodyCharacteristic", res.Character["bodyCharacteristic"], baseTextureFile, err)
+		} else {
+			res.tinted = append(res.tinted, &texture.TintedTexture{
+				Name:         "_base",
+				Image:        baseImg,
+				OriginalPath: baseTextureFile,
+			})
+			res.Textures = append(res.Textures, TextureInfo{
+				Name:       "_base",
+				Category:   "bodyCharacteristic",
+				SourcePath: baseTextureFile,
+			})
+		}
+	}
+
+	// Process accessory textures
+	for _, acc := range res.paths {
+		value := res.Character[acc.Type]
+		if acc.ResolvedTexture == nil {
+			// resolveAccessories already reported it
+			continue
+		}
+
+		var tinted *texture.TintedTexture
+		info := TextureInfo{Name: acc.Spec.ID, Category: acc.Type}
+
+		if acc.ResolvedTexture.DirectTexture != "" {
+			img, err := s.loadImage(acc.ResolvedTexture.DirectTexture)
+			if err != nil {
+				missing(acc.Type, value, acc.ResolvedTexture.DirectTexture, err)
+				continue
+			}
+			tinted = &texture.TintedTexture{
+				Name:         acc.Spec.ID,
+				Image:        img,
+				OriginalPath: acc.ResolvedTexture.DirectTexture,
+			}
+			info.SourcePath = acc.ResolvedTexture.DirectTexture
+		} else {
+			var err error
+			tinted, err = s.tintTexture(
+				acc.Spec.ID,
+				acc.ResolvedTexture.GreyscaleTexture,
+				acc.ResolvedTexture.GradientSet,
+				acc.Spec.Color,
+			)
+			if err != nil {
+				missing(acc.Type, value, acc.ResolvedTexture.GreyscaleTexture, err)
+				continue
+			}
+			info.SourcePath = acc.ResolvedTexture.GreyscaleTexture
+			info.GradientSet = acc.ResolvedTexture.GradientSet
+			info.Color = acc.Spec.Color
+		}
+
+		res.tinted = append(res.tinted, tinted)
+		res.Textures = append(res.Textures, info)
+	}
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/hytale-tools/blockymodel-merger/pkg/character"
+)
+
+// Rule sources reported in Rule.Source
+const (
+	RuleSourceBuiltin  = "builtin"  // game behavior not described by the data files
+	RuleSourceRegistry = "registry" // a Disable* field of a cosmetic
+	RuleSourceFile     = "file"     // the local rules file
+)
+
+// Rule removes or replaces character slots while another slot holds a
+// matching cosmetic
+type Rule struct {
+	Name     string            `json:"name"`
+	Source   string            `json:"source"`
+	Field    string            `json:"field,omitempty"`    // slot that triggers the rule, e.g. "headAccessory"
+	IDs      []string          `json:"ids,omitempty"`      // cosmetic IDs or glob patterns, empty matches any
+	Type     string            `json:"type,omitempty"`     // HeadAccessoryType or HairType the cosmetic must have
+	Disable  []string          `json:"disable,omitempty"`  // slots removed
+	Fallback []string          `json:"fallback,omitempty"` // slots replaced by their haircut fallback for their HairType
+	Replace  map[string]string `json:"replace,omitempty"`  // slot -> replacement "Id" or "Id.Color"; the color is kept if omitted
+}
+
+// ruleOrder is the order rules are triggered in: outer layers first, so a
+// slot removed by an outer layer no longer triggers its own rules
+var ruleOrder = func() []string {
+	order := make([]string, len(mergeOrder))
+	for i, field := range mergeOrder {
+		order[len(mergeOrder)-1-i] = field
+	}
+	return order
+}()
+
+// builtinRules describe how head accessory types cover the haircut
+var builtinRules = []Rule{
+	{Name: "headAccessoryType/FullyCovering", Source: RuleSourceBuiltin, Field: "headAccessory", Type: "FullyCovering", Disable: []string{"haircut"}},
+	{Name: "headAccessoryType/HalfCovering", Source: RuleSourceBuiltin, Field: "headAccessory", Type: "HalfCovering", Fallback: []string{"haircut"}},
+}
+
+// loadRules builds the rules from the registry's Disable* fields and the
+// built-in rules, then applies the optional rules file. A rule in the file
+// replaces the rule of the same name; one without actions switches it off.
+func loadRules(files fs.FS, catalog *Catalog) ([]Rule, error) {
+	var rules []Rule
+	for _, field := range catalog.Categories() {
+		items, _ := catalog.Items(field)
+		for _, item := range items {
+			for _, key := range sortedKeys(item.disables) {
+				rules = append(rules, Rule{
+					Name:    field + "/" + item.ID + "/" + key,
+					Source:  RuleSourceRegistry,
+					Field:   field,
+					IDs:     []string{item.ID},
+					Disable: item.disables[key],
+				})
+			}
+		}
+	}
+	rules = append(rules, builtinRules...)
+
+	data, err := fs.ReadFile(files, rulesFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return rules, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var local []Rule
+	if err := json.Unmarshal(data, &local); err != nil {
+		return nil, fmt.Errorf("parsing rules: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, rule := range local {
+		if err := rule.check(); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+		if seen[rule.Name] {
+			return nil, fmt.Errorf("rule %q is defined twice", rule.Name)
+		}
+		seen[rule.Name] = true
+		rule.Source = RuleSourceFile
+
+		replaced := false
+		for i := range rules {
+			if rules[i].Name == rule.Name {
+				rules[i] = rule
+				replaced = true
+			}
+		}
+		if !replaced {
+			if rule.switchesOff() {
+				return nil, fmt.Errorf("rule %q switches off a rule that does not exist", rule.Name)
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// switchesOff reports whether a rule from the rules file has no actions,
+// which switches off the rule of the same name
+func (r Rule) switchesOff() bool {
+	return len(r.Disable) == 0 && len(r.Fallback) == 0 && len(r.Replace) == 0
+}
+
+// check validates a rule from the rules file
+func (r Rule) check() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	if r.switchesOff() {
+		return nil
+	}
+	if !containsString(mergeOrder, r.Field) {
+		return fmt.Errorf("unknown field %q", r.Field)
+	}
+	for _, id := range r.IDs {
+		if _, err := path.Match(id, ""); err != nil {
+			return fmt.Errorf("invalid ID pattern %q", id)
+		}
+	}
+	targets := append(append([]string{}, r.Disable...), r.Fallback...)
+	for target, with := range r.Replace {
+		if with == "" {
+			return fmt.Errorf("replacement for %s is empty", target)
+		}
+		targets = append(targets, target)
+	}
+	for _, target := range targets {
+		if !containsString(mergeOrder, target) {
+			return fmt.Errorf("unknown target field %q", target)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the rule applies to a cosmetic in its field.
+// item is nil for IDs missing from the catalog.
+func (r Rule) matches(id string, item *CatalogItem) bool {
+	if len(r.IDs) > 0 {
+		matched := false
+		for _, pattern := range r.IDs {
+			if ok, _ := path.Match(pattern, id); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if r.Type != "" && (item == nil || (item.HeadAccessoryType != r.Type && item.HairType != r.Type)) {
+		return false
+	}
+	return true
+}
+
+// Rules returns the rules applied to every character, in the order they are
+// checked within a field
+func (s *MergeService) Rules() []Rule {
+	return s.rules
+}
+
+// rulesFor returns the rules triggered by a cosmetic in a field
+func (s *MergeService) rulesFor(field, value string) []Rule {
+	spec := character.ParseAccessorySpec(value)
+	item, _ := s.catalog.Item(field, spec.ID)
+
+	var result []Rule
+	for _, rule := range s.rules {
+		if rule.Field == field && rule.matches(spec.ID, item) {
+			result = append(result, rule)
+		}
+	}
+	return result
+}
+
+// applyRules removes and replaces slots of a character following the rules
+// and reports every change with the rule that made it
+func (s *MergeService) applyRules(values map[string]string) []FieldIssue {
+	var warnings []FieldIssue
+
+	for _, field := range ruleOrder {
+		value, ok := values[field]
+		if !ok || value == "" {
+			continue
+		}
+		trigger := fmt.Sprintf("%s %s", field, character.ParseAccessorySpec(value).ID)
+
+		for _, rule := range s.rulesFor(field, value) {
+			for _, target := range rule.Disable {
+				original, ok := values[target]
+				if !ok || original == "" {
+					continue
+				}
+				delete(values, target)
+				warnings = append(warnings, FieldIssue{
+					Field:   target,
+					Value:   original,
+					Reason:  ReasonCategoryDisabled,
+					Message: fmt.Sprintf("%s is hidden by %s", target, trigger),
+					Rule:    rule.Name,
+				})
+			}
+
+			for _, target := range rule.Fallback {
+				original, ok := values[target]
+				if !ok || original == "" {
+					continue
+				}
+				replacement := s.fallbackFor(target, original)
+				if replacement == original {
+					continue
+				}
+				values[target] = replacement
+				warnings = append(warnings, FieldIssue{
+					Field:   target,
+					Value:   original,
+					Reason:  ReasonFallbackApplied,
+					Message: fmt.Sprintf("%s replaced by %s under %s", target, replacement, trigger),
+					Rule:    rule.Name,
+				})
+			}
+
+			for _, target := range sortedKeys(rule.Replace) {
+				original, ok := values[target]
+				if !ok || original == "" {
+					continue
+				}
+				replacement := withColor(rule.Replace[target], original)
+				if replacement == original {
+					continue
+				}
+				values[target] = replacement
+				warnings = append(warnings, FieldIssue{
+					Field:   target,
+					Value:   original,
+					Reason:  ReasonFallbackApplied,
+					Message: fmt.Sprintf("%s replaced by %s under %s", target, replacement, trigger),
+					Rule:    rule.Name,
+				})
+			}
+		}
+	}
+	return warnings
+}
+
+// fallbackFor returns the haircut fallback for the HairType of a cosmetic,
+// keeping its color. Cosmetics without a fallback are returned unchanged.
+func (s *MergeService) fallbackFor(field, value string) string {
+	spec := character.ParseAccessorySpec(value)
+	item, ok := s.catalog.Item(field, spec.ID)
+	if !ok {
+		return value
+	}
+	fallbackID, ok := s.haircutFallbacks[item.HairType]
+	if !ok {
+		return value
+	}
+	return withColor(fallbackID, value)
+}
+
+// withColor gives a replacement "Id" the color of the value it replaces.
+// Replacements that name a color keep it.
+func withColor(replacement, original string) string {
+	if strings.Contains(replacement, ".") {
+		return replacement
+	}
+	if color := character.ParseAccessorySpec(original).Color; color != "" {
+		return replacement + "." + color
+	}
+	return replacement
+}
+
+// registryDisables reads the Disable* fields of a registry entry, mapping
+// category names such as "Haircut" or "FacialHair" to character fields.
+// Values may be a name, a comma-separated list or an array; unknown names
+// are ignored.
+func registryDisables(raw json.RawMessage) map[string][]string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil
+	}
+
+	result := make(map[string][]string)
+	for key, value := range fields {
+		if !strings.HasPrefix(key, "Disable") {
+			continue
+		}
+
+		var names []string
+		var single string
+		if err := json.Unmarshal(value, &single); err == nil {
+			names = strings.Split(single, ",")
+		} else if err := json.Unmarshal(value, &names); err != nil {
+			continue
+		}
+
+		var targets []string
+		for _, name := range names {
+			if field, ok := fieldForPart(strings.TrimSpace(name)); ok && !containsString(targets, field) {
+				targets = append(targets, field)
+			}
+		}
+		if len(targets) > 0 {
+			result[key] = targets
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// fieldForPart maps a registry category name to its character field,
+// matching the field or the registry file name case-insensitively
+func fieldForPart(name string) (string, bool) {
+	for _, cf := range categoryFiles {
+		if strings.EqualFold(name, cf.Category) || strings.EqualFold(name, cf.File) {
+			return cf.Category, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestApplyRules(t *testing.T) {
+	tests := []struct {
+		name      string
+		rules     string // rules file, empty for the registry and built-in rules only
+		character map[string]string
+		want      map[string]string
+		applied   []string // rule of each warning, in order
+	}{
+		{
+			name:      "registry disable",
+			character: map[string]string{"headAccessory": "Hood_A.Red", "haircut": "Scavenger_Hair.Brown"},
+			want:      map[string]string{"headAccessory": "Hood_A.Red"},
+			applied:   []string{"headAccessory/Hood_A/DisableCharacterPartCategory"},
+		},
+		{
+			name:      "fully covering removes the haircut",
+			character: map[string]string{"headAccessory": "Helmet_A.Steel", "haircut": "Scavenger_Hair.Brown"},
+			want:      map[string]string{"headAccessory": "Helmet_A.Steel"},
+			applied:   []string{"headAccessoryType/FullyCovering"},
+		},
+		{
+			name:      "half covering falls back keeping the color",
+			character: map[string]string{"headAccessory": "Hat_A.Red", "haircut": "Scavenger_Hair.Brown"},
+			want:      map[string]string{"headAccessory": "Hat_A.Red", "haircut": "Short_Fallback.Brown"},
+			applied:   []string{"headAccessoryType/HalfCovering"},
+		},
+		{
+			name:      "fallback haircut is kept",
+			character: map[string]string{"headAccessory": "Hat_A.Red", "haircut": "Short_Fallback.Brown"},
+			want:      map[string]string{"headAccessory": "Hat_A.Red", "haircut": "Short_Fallback.Brown"},
+		},
+		{
+			name:      "unknown haircut has no fallback",
+			character: map[string]string{"headAccessory": "Hat_A.Red", "haircut": "Missing_Hair.Brown"},
+			want:      map[string]string{"headAccessory": "Hat_A.Red", "haircut": "Missing_Hair.Brown"},
+		},
+		{
+			name:      "no trigger",
+			character: map[string]string{"haircut": "Scavenger_Hair.Brown", "pants": "Pants_A.Red.Long"},
+			want:      map[string]string{"haircut": "Scavenger_Hair.Brown", "pants": "Pants_A.Red.Long"},
+		},
+		{
+			name:      "file switches off a built-in rule",
+			rules:     `[{"name":"headAccessoryType/HalfCovering"}]`,
+			character: map[string]string{"headAccessory": "Hat_A.Red", "haircut": "Scavenger_Hair.Brown"},
+			want:      map[string]string{"headAccessory": "Hat_A.Red", "haircut": "Scavenger_Hair.Brown"},
+		},
+		{
+			name:      "file replaces a built-in rule",
+			rules:     `[{"name":"headAccessoryType/HalfCovering","field":"headAccessory","type":"HalfCovering","disable":["haircut"]}]`,
+			character: map[string]string{"headAccessory": "Hat_A.Red", "haircut": "Scavenger_Hair.Brown"},
+			want:      map[string]string{"headAccessory": "Hat_A.Red"},
+			applied:   []string{"headAccessoryType/HalfCovering"},
+		},
+		{
+			name:      "replace keeps the color unless given",
+			rules:     `[{"name":"cape-outfit","field":"cape","ids":["Cape_*"],"replace":{"haircut":"Short_Fallback","pants":"Pants_A.Blue.Short"}}]`,
+			character: map[string]string{"cape": "Cape_A.Red", "haircut": "Scavenger_Hair.Brown", "pants": "Pants_A.Red.Long"},
+			want:      map[string]string{"cape": "Cape_A.Red", "haircut": "Short_Fallback.Brown", "pants": "Pants_A.Blue.Short"},
+			applied:   []string{"cape-outfit", "cape-outfit"},
+		},
+		{
+			name:      "pattern does not match",
+			rules:     `[{"name":"cape-outfit","field":"cape","ids":["Cloak_*"],"disable":["pants"]}]`,
+			character: map[string]string{"cape": "Cape_A.Red", "pants": "Pants_A.Red.Long"},
+			want:      map[string]string{"cape": "Cape_A.Red", "pants": "Pants_A.Red.Long"},
+		},
+		{
+			name: "outer slots run first",
+			rules: `[{"name":"hat-hides-cape","field":"headAccessory","ids":["Hat_A"],"disable":["cape"]},
+				{"name":"cape-hides-pants","field":"cape","disable":["pants"]}]`,
+			character: map[string]string{"headAccessory": "Hat_A.Red", "cape": "Cape_A.Red", "pants": "Pants_A.Red.Long"},
+			want:      map[string]string{"headAccessory": "Hat_A.Red", "pants": "Pants_A.Red.Long"},
+			applied:   []string{"hat-hides-cape"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := Paths{AssetsDir: "testdata/assets", DataDir: "testdata/data"}
+			if tt.rules != "" {
+				paths.Rules = filepath.Join(t.TempDir(), "CharacterRules.json")
+				if err := os.WriteFile(paths.Rules, []byte(tt.rules), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			svc, err := NewMergeService(Options{Paths: paths})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values := make(map[string]string)
+			for k, v := range tt.character {
+				values[k] = v
+			}
+			warnings := svc.applyRules(values)
+
+			if !reflect.DeepEqual(values, tt.want) {
+				t.Errorf("got %v, want %v", values, tt.want)
+			}
+			var applied []string
+			for _, w := range warnings {
+				applied = append(applied, w.Rule)
+			}
+			if !reflect.DeepEqual(applied, tt.applied) {
+				t.Errorf("got rules %v, want %v", applied, tt.applied)
+			}
+		})
+	}
+}
+
+func TestLoadRulesErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+	}{
+		{"invalid JSON", `[{"name":`},
+		{"missing name", `[{"field":"cape","disable":["pants"]}]`},
+		{"unknown field", `[{"name":"r","field":"hat","disable":["pants"]}]`},
+		{"unknown target", `[{"name":"r","field":"cape","disable":["trousers"]}]`},
+		{"empty replacement", `[{"name":"r","field":"cape","replace":{"pants":""}}]`},
+		{"invalid pattern", `[{"name":"r","field":"cape","ids":["[Cape"],"disable":["pants"]}]`},
+		{"switches off an unknown rule", `[{"name":"headAccessoryType/HalfCoverin"}]`},
+		{"defined twice", `[{"name":"r","field":"cape","disable":["pants"]},{"name":"r","field":"cape","disable":["haircut"]}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := filepath.Join(t.TempDir(), "CharacterRules.json")
+			if err := os.WriteFile(rules, []byte(tt.rules), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := NewMergeService(Options{Paths: Paths{AssetsDir: "testdata/assets", DataDir: "testdata/data", Rules: rules}})
+			if err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
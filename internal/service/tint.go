@@ -0,0 +1,123 @@
+package service
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/hytale-tools/blockymodel-merger/pkg/texture"
+)
+
+const (
+	rampWidth    = 256 // gradient textures map each greyscale level to a pixel
+	maxRampStops = 16
+)
+
+// isHexColor reports whether a color is given as hex rather than by name
+func isHexColor(color string) bool {
+	return strings.HasPrefix(color, "#")
+}
+
+// parseColorRamp parses a hex color such as "#3A7BD5", or a list of
+// gradient stops from dark to light such as "#102030-#3A7BD5-#E0F0FF".
+// A single color becomes a ramp from a dark shade of it to a light tint.
+func parseColorRamp(s string) ([]color.RGBA, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) > maxRampStops {
+		return nil, fmt.Errorf("at most %d gradient stops are allowed", maxRampStops)
+	}
+
+	stops := make([]color.RGBA, len(parts))
+	for i, part := range parts {
+		if len(part) != 7 || part[0] != '#' {
+			return nil, fmt.Errorf("invalid hex color %q, expected \"#RRGGBB\"", part)
+		}
+		r, g, b, err := texture.ParseHexColor(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex color %q, expected \"#RRGGBB\"", part)
+		}
+		stops[i] = color.RGBA{R: r, G: g, B: b, A: 255}
+	}
+
+	if len(stops) == 1 {
+		c := stops[0]
+		stops = []color.RGBA{mixColor(c, color.RGBA{A: 255}, 0.75), c, mixColor(c, color.RGBA{R: 255, G: 255, B: 255, A: 255}, 0.3)}
+	}
+	return stops, nil
+}
+
+// gradientRamp renders evenly spaced stops into a gradient texture like the
+// ones in TintGradients/
+func gradientRamp(stops []color.RGBA) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, rampWidth, 1))
+	for x := 0; x < rampWidth; x++ {
+		pos := float64(x) / float64(rampWidth-1) * float64(len(stops)-1)
+		i := int(pos)
+		if i >= len(stops)-1 {
+			img.SetRGBA(x, 0, stops[len(stops)-1])
+			continue
+		}
+		img.SetRGBA(x, 0, mixColor(stops[i], stops[i+1], pos-float64(i)))
+	}
+	return img
+}
+
+// mixColor interpolates linearly from a to b
+func mixColor(a, b color.RGBA, t float64) color.RGBA {
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*t + 0.5)
+	}
+	return color.RGBA{R: mix(a.R, b.R), G: mix(a.G, b.G), B: mix(a.B, b.B), A: 255}
+}
+
+// tint colors a greyscale texture the way Hytale does: greyscale pixels
+// look up their color in the gradient by brightness, colored pixels are kept.
+// Without a gradient, greyscale pixels are multiplied by baseColor (grey if
+// empty). Alpha is thresholded at 128 to avoid semi-transparent edges.
+func tint(greyscale, gradient image.Image, baseColor string) image.Image {
+	var baseR, baseG, baseB uint8 = 128, 128, 128
+	if baseColor != "" {
+		if r, g, b, err := texture.ParseHexColor(baseColor); err == nil {
+			baseR, baseG, baseB = r, g, b
+		}
+	}
+
+	bounds := greyscale.Bounds()
+	result := image.NewRGBA(bounds)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.RGBAModel.Convert(greyscale.At(x, y)).(color.RGBA)
+
+			r, g, b := c.R, c.G, c.B
+			if c.R == c.G && c.G == c.B {
+				if gradient != nil {
+					// The brightness maps to the X position in the gradient
+					gb := gradient.Bounds()
+					gx := int(float64(c.R) / 255.0 * float64(gb.Max.X-1))
+					if gx >= gb.Max.X {
+						gx = gb.Max.X - 1
+					}
+					if gx < 0 {
+						gx = 0
+					}
+					rr, gg, bb, _ := gradient.At(gx, gb.Min.Y).RGBA()
+					r, g, b = uint8(rr>>8), uint8(gg>>8), uint8(bb>>8)
+				} else {
+					r = uint8(float64(c.R) * float64(baseR) / 255.0)
+					g = uint8(float64(c.R) * float64(baseG) / 255.0)
+					b = uint8(float64(c.R) * float64(baseB) / 255.0)
+				}
+			}
+
+			a := uint8(0)
+			if c.A >= 128 {
+				a = 255
+			}
+			result.Set(x, y, color.RGBA{R: r, G: g, B: b, A: a})
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hytale-tools/blockymodel-merger/pkg/character"
+)
+
+// ErrInvalidJSON is returned when character data is not valid JSON
+var ErrInvalidJSON = errors.New("invalid character JSON")
+
+// Reasons reported in FieldIssue.Reason
+const (
+	ReasonUnknownField     = "unknown_field"
+	ReasonInvalidFormat    = "invalid_format"
+	ReasonUnknownID        = "unknown_id"
+	ReasonAmbiguousID      = "ambiguous_id" // item ID found in several categories
+	ReasonUnknownColor     = "unknown_color"
+	ReasonUnknownVariant   = "unknown_variant"
+	ReasonCategoryDisabled = "category_disabled" // removed by a head accessory
+	ReasonCategoryIgnored  = "category_ignored"  // not part of the rendered model
+	ReasonFallbackApplied  = "fallback_applied"  // replaced by a fallback
+	ReasonModelMissing     = "model_missing"
+	ReasonTextureMissing   = "texture_missing"
+	ReasonNotPrintable     = "not_printable" // shape left out of an STL print
+)
+
+const maxSuggestions = 3
+
+// FieldIssue describes a problem with a single character field
+type FieldIssue struct {
+	Field       string   `json:"field"`
+	Value       string   `json:"value,omitempty"`
+	Reason      string   `json:"reason"`
+	Message     string   `json:"message"`
+	Suggestions []string `json:"suggestions,omitempty"`
+	Rule        string   `json:"rule,omitempty"` // rule that removed or replaced the field
+}
+
+// ValidationResult lists the problems found in a character.
+// Errors prevent rendering; warnings describe changes applied while rendering.
+type ValidationResult struct {
+	Valid    bool         `json:"valid"`
+	Errors   []FieldIssue `json:"errors"`
+	Warnings []FieldIssue `json:"warnings"`
+}
+
+// ValidationError is returned when a character contains invalid fields
+type ValidationError struct {
+	Issues []FieldIssue
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		msgs[i] = issue.Field + ": " + issue.Message
+	}
+	return "invalid character: " + strings.Join(msgs, "; ")
+}
+
+// Validate checks every field of a character against the catalog
+func (s *MergeService) Validate(charJSON []byte) (*ValidationResult, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(charJSON, &fields); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+
+	var charData character.CharacterData
+	if err := json.Unmarshal(charJSON, &charData); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+
+	result := &ValidationResult{
+		Errors:   []FieldIssue{},
+		Warnings: []FieldIssue{},
+	}
+
+	// Unknown field names (e.g. typos such as "haricut") are ignored when
+	// rendering, so clients may send extra keys; strict renders reject them
+	known := make([]string, len(categoryFiles))
+	for i, cf := range categoryFiles {
+		known[i] = cf.Category
+	}
+	for _, name := range sortedKeys(fields) {
+		if !containsString(known, name) {
+			result.Warnings = append(result.Warnings, FieldIssue{
+				Field:       name,
+				Reason:      ReasonUnknownField,
+				Message:     fmt.Sprintf("unknown field %q", name),
+				Suggestions: suggest(name, known),
+			})
+		}
+	}
+
+	for _, f := range characterFields(&charData) {
+		if f.Value == nil || *f.Value == "" {
+			continue
+		}
+		if issue := s.validateField(f.Name, *f.Value); issue != nil {
+			result.Errors = append(result.Errors, *issue)
+		}
+	}
+
+	// Report what the fallback rules would change
+	result.Warnings = append(result.Warnings, s.normalizeCharacter(&charData)...)
+	result.Valid = len(result.Errors) == 0
+	return result, nil
+}
+
+// validateField checks a single "Id.Color.Variant" value
+func (s *MergeService) validateField(field, value string) *FieldIssue {
+	parts := strings.Split(value, ".")
+	if len(parts) > 3 || parts[0] == "" {
+		return &FieldIssue{
+			Field:   field,
+			Value:   value,
+			Reason:  ReasonInvalidFormat,
+			Message: "expected \"Id\", \"Id.Color\" or \"Id.Color.Variant\"",
+		}
+	}
+	spec := character.ParseAccessorySpec(value)
+
+	items, ok := s.catalog.Items(field)
+	if !ok {
+		// Body characteristics only carry the skin tone when there is no registry file
+		if field == "bodyCharacteristic" {
+			return s.validateSkinTone(field, value, spec.Color)
+		}
+		return &FieldIssue{
+			Field:   field,
+			Value:   value,
+			Reason:  ReasonUnknownID,
+			Message: fmt.Sprintf("no cosmetics are loaded for %s", field),
+		}
+	}
+
+	item, ok := s.catalog.Item(field, spec.ID)
+	if !ok {
+		ids := make([]string, len(items))
+		for i, it := range items {
+			ids[i] = it.ID
+		}
+		return &FieldIssue{
+			Field:       field,
+			Value:       value,
+			Reason:      ReasonUnknownID,
+			Message:     fmt.Sprintf("unknown %s %q", field, spec.ID),
+			Suggestions: suggest(spec.ID, ids),
+		}
+	}
+
+	if field == "bodyCharacteristic" {
+		return s.validateSkinTone(field, value, spec.Color)
+	}
+
+	if isHexColor(spec.Color) {
+		if issue := validateHexColor(field, value, item, spec.Color, spec.Variant); issue != nil {
+			return issue
+		}
+	} else if spec.Color != "" && !item.HasColor(spec.Color) {
+		return &FieldIssue{
+			Field:       field,
+			Value:       value,
+			Reason:      ReasonUnknownColor,
+			Message:     fmt.Sprintf("%s does not come in color %q", item.ID, spec.Color),
+			Suggestions: suggest(spec.Color, item.Colors),
+		}
+	}
+
+	if spec.Variant != "" {
+		if _, ok := item.Variant(spec.Variant); !ok {
+			names := make([]string, len(item.Variants))
+			for i, v := range item.Variants {
+				names[i] = v.Name
+			}
+			return &FieldIssue{
+				Field:       field,
+				Value:       value,
+				Reason:      ReasonUnknownVariant,
+				Message:     fmt.Sprintf("%s has no variant %q", item.ID, spec.Variant),
+				Suggestions: suggest(spec.Variant, names),
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateHexColor checks a hex color or gradient stop list. Only greyscale
+// textures can be tinted with it.
+func validateHexColor(field, value string, item *CatalogItem, color, variant string) *FieldIssue {
+	if _, err := parseColorRamp(color); err != nil {
+		return &FieldIssue{
+			Field:   field,
+			Value:   value,
+			Reason:  ReasonInvalidFormat,
+			Message: err.Error(),
+		}
+	}
+	if resolved := item.resolveTexture(color, variant); resolved == nil || resolved.GreyscaleTexture == "" {
+		return &FieldIssue{
+			Field:       field,
+			Value:       value,
+			Reason:      ReasonUnknownColor,
+			Message:     fmt.Sprintf("%s has no greyscale texture to tint with %s", item.ID, color),
+			Suggestions: suggest(color, item.Colors),
+		}
+	}
+	return nil
+}
+
+// validateSkinTone checks the skin tone part of bodyCharacteristic against
+// the Skin gradient set, or as a hex color
+func (s *MergeService) validateSkinTone(field, value, tone string) *FieldIssue {
+	if isHexColor(tone) {
+		if _, err := parseColorRamp(tone); err != nil {
+			return &FieldIssue{
+				Field:   field,
+				Value:   value,
+				Reason:  ReasonInvalidFormat,
+				Message: "invalid skin tone: " + err.Error(),
+			}
+		}
+		return nil
+	}
+
+	tones := s.catalog.GradientColors("Skin")
+	if tone == "" || len(tones) == 0 || containsString(tones, tone) {
+		return nil
+	}
+	return &FieldIssue{
+		Field:       field,
+		Value:       value,
+		Reason:      ReasonUnknownColor,
+		Message:     fmt.Sprintf("unknown skin tone %q", tone),
+		Suggestions: suggest(tone, tones),
+	}
+}
+
+// characterField is a named, optional character slot
+type characterField struct {
+	Name  string
+	Value *string
+}
+
+// characterFields returns pointers to every slot of a character in catalog order
+func characterFields(c *character.CharacterData) []characterField {
+	return []characterField{
+		{"bodyCharacteristic", c.BodyCharacteristic},
+		{"underwear", c.Underwear},
+		{"face", c.Face},
+		{"ears", c.Ears},
+		{"mouth", c.Mouth},
+		{"haircut", c.Haircut},
+		{"facialHair", c.FacialHair},
+		{"eyebrows", c.Eyebrows},
+		{"eyes", c.Eyes},
+		{"pants", c.Pants},
+		{"overpants", c.Overpants},
+		{"undertop", c.Undertop},
+		{"overtop", c.Overtop},
+		{"shoes", c.Shoes},
+		{"headAccessory", c.HeadAccessory},
+		{"faceAccessory", c.FaceAccessory},
+		{"earAccessory", c.EarAccessory},
+		{"skinFeature", c.SkinFeature},
+		{"gloves", c.Gloves},
+		{"cape", c.Cape},
+	}
+}
+
+// suggest returns up to maxSuggestions candidates closest to value by edit distance
+func suggest(value string, candidates []string) []string {
+	type scored struct {
+		candidate string
+		distance  int
+	}
+
+	lower := strings.ToLower(value)
+	threshold := len(value)/3 + 1
+	var matches []scored
+	for _, c := range candidates {
+		lc := strings.ToLower(c)
+		d := levenshtein(lower, lc)
+		if strings.Contains(lc, lower) || strings.Contains(lower, lc) {
+			d = min(d, 1)
+		}
+		if d <= threshold {
+			matches = append(matches, scored{c, d})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+
+	var result []string
+	for i := 0; i < len(matches) && i < maxSuggestions; i++ {
+		result = append(result, matches[i].candidate)
+	}
+	return result
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
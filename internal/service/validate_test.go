@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// newTestService loads the small asset pack in testdata, shared by the
+// tests of this package
+func newTestService(t *testing.T) *MergeService {
+	t.Helper()
+	svc, err := NewMergeService(Options{Paths: Paths{AssetsDir: "testdata/assets", DataDir: "testdata/data"}})
+	if err != nil {
+		t.Fatalf("loading testdata: %v", err)
+	}
+	return svc
+}
+
+func TestValidate(t *testing.T) {
+	svc := newTestService(t)
+
+	tests := []struct {
+		name        string
+		character   string
+		field       string // field of the single expected error, empty if valid
+		reason      string
+		suggestions []string
+	}{
+		{"valid", `{"bodyCharacteristic":"Default.01","haircut":"Scavenger_Hair.Brown","pants":"Pants_A.Red.Long"}`, "", "", nil},
+		{"too many parts", `{"haircut":"Scavenger_Hair.Brown.Long.Extra"}`, "haircut", ReasonInvalidFormat, nil},
+		{"empty ID", `{"haircut":".Brown"}`, "haircut", ReasonInvalidFormat, nil},
+		{"unknown ID", `{"haircut":"Scavenger_Hiar.Brown"}`, "haircut", ReasonUnknownID, []string{"Scavenger_Hair"}},
+		{"unknown color", `{"haircut":"Scavenger_Hair.Brwn"}`, "haircut", ReasonUnknownColor, []string{"Brown"}},
+		{"unknown variant", `{"pants":"Pants_A.Red.Lnog"}`, "pants", ReasonUnknownVariant, []string{"Long"}},
+		{"invalid hex color", `{"haircut":"Scavenger_Hair.#12345"}`, "haircut", ReasonInvalidFormat, nil},
+		{"hex color without greyscale texture", `{"eyes":"Large_Eyes.#123456"}`, "eyes", ReasonUnknownColor, nil},
+		{"hex color", `{"haircut":"Scavenger_Hair.#3A7BD5-#E0F0FF"}`, "", "", nil},
+		{"unknown skin tone", `{"bodyCharacteristic":"Default.03"}`, "bodyCharacteristic", ReasonUnknownColor, []string{"01", "02"}},
+		{"hex skin tone", `{"bodyCharacteristic":"Default.#C8966E"}`, "", "", nil},
+		{"no cosmetics loaded", `{"overtop":"Jacket_A.Red"}`, "overtop", ReasonUnknownID, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := svc.Validate([]byte(tt.character))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.field == "" {
+				if !result.Valid || len(result.Errors) > 0 {
+					t.Fatalf("got errors %+v, want valid", result.Errors)
+				}
+				return
+			}
+			if result.Valid || len(result.Errors) != 1 {
+				t.Fatalf("got valid=%v errors %+v, want one error", result.Valid, result.Errors)
+			}
+			issue := result.Errors[0]
+			if issue.Field != tt.field || issue.Reason != tt.reason {
+				t.Errorf("got %s %s, want %s %s", issue.Field, issue.Reason, tt.field, tt.reason)
+			}
+			if len(tt.suggestions) > 0 && !reflect.DeepEqual(issue.Suggestions, tt.suggestions) {
+				t.Errorf("got suggestions %v, want %v", issue.Suggestions, tt.suggestions)
+			}
+		})
+	}
+}
+
+func TestValidateUnknownField(t *testing.T) {
+	svc := newTestService(t)
+	character := []byte(`{"haricut":"Scavenger_Hair.Brown","pants":"Pants_A.Red.Long"}`)
+
+	// Unknown keys are warnings, so extra metadata does not fail renders
+	result, err := svc.Validate(character)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid {
+		t.Fatalf("got errors %+v, want valid", result.Errors)
+	}
+	want := FieldIssue{
+		Field:       "haricut",
+		Reason:      ReasonUnknownField,
+		Message:     `unknown field "haricut"`,
+		Suggestions: []string{"haircut"},
+	}
+	if len(result.Warnings) == 0 || !reflect.DeepEqual(result.Warnings[0], want) {
+		t.Fatalf("got warnings %+v, want %+v first", result.Warnings, want)
+	}
+
+	merged, err := svc.MergeFromJSON(character, MergeOptions{})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if len(merged.Warnings) == 0 || merged.Warnings[0].Reason != ReasonUnknownField {
+		t.Errorf("got render warnings %+v, want unknown_field", merged.Warnings)
+	}
+
+	// Strict renders reject them
+	_, err = svc.MergeFromJSON(character, MergeOptions{Strict: true})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("got %v, want a ValidationError", err)
+	}
+}
+
+func TestValidateInvalidJSON(t *testing.T) {
+	svc := newTestService(t)
+	if _, err := svc.Validate([]byte(`{"haircut":`)); !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("got %v, want ErrInvalidJSON", err)
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+)
+
+// AssetVersion returns a fingerprint of the loaded assets. It changes
+// whenever an asset or data file is added, removed or modified.
+func (s *MergeService) AssetVersion() string {
+	return s.assetVersion
+}
+
+// fingerprintAssets hashes the path, size and modification time of every file
+// under the given directories and files. Missing roots are skipped.
+func fingerprintAssets(roots ...string) (string, error) {
+	h := sha256.New()
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == root && errors.Is(err, fs.ErrNotExist) {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s\x00%d\x00%d\n", filepath.ToSlash(path), info.Size(), info.ModTime().UnixNano())
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// CanonicalCharacter returns a normalized encoding of character JSON, with
+// keys sorted and empty fields removed, so equivalent characters compare equal
+func CanonicalCharacter(charJSON []byte) ([]byte, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(charJSON, &fields); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+	for k, v := range fields {
+		if v == nil || v == "" {
+			delete(fields, k)
+		}
+	}
+	return json.Marshal(fields)
+}
@@ -1,38 +1,221 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"blockyserver/internal/api"
+	"blockyserver/internal/config"
 	"blockyserver/internal/service"
 )
 
 func main() {
-	port := flag.Int("port", 8080, "Port to listen on")
-	flag.Parse()
+	// Without a command, or with flags only, the server starts as before
+	args := os.Args[1:]
+	command := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		serve(args)
+	case "render":
+		renderCommand(args)
+	case "icons":
+		iconsCommand(args)
+	case "help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", command)
+		usage()
+		os.Exit(2)
+	}
+}
+
+// usage lists the commands
+func usage() {
+	fmt.Fprint(os.Stderr, `Usage: blockyserver [command] [flags]
+
+Commands:
+  serve   Starts the HTTP server (default)
+  render  Renders a character file to GLB, PNG, GIF, MP4, OBJ, STL or VOX
+  icons   Renders a PNG icon of every cosmetic and color of a category
+
+Run blockyserver <command> -help for the flags of a command.
+`)
+}
+
+// pathFlags registers the asset location flags shared by every command.
+// Flags override the corresponding environment variables.
+func pathFlags(fs *flag.FlagSet) *config.PathsConfig {
+	pathsCfg := config.LoadPathsConfig()
+	fs.StringVar(&pathsCfg.Packs, "packs", pathsCfg.Packs, "Asset packs as name=path,... where path is a directory with assets/ and data/ or an assets.zip (BLOCKY_PACKS)")
+	fs.StringVar(&pathsCfg.DefaultPack, "default-pack", pathsCfg.DefaultPack, "Pack used when a request does not pick one, default the first (BLOCKY_DEFAULT_PACK)")
+	fs.StringVar(&pathsCfg.AssetsZip, "assets-zip", pathsCfg.AssetsZip, "Hytale server assets.zip to read instead of -assets and -data (BLOCKY_ASSETS_ZIP)")
+	fs.StringVar(&pathsCfg.AssetsDir, "assets", pathsCfg.AssetsDir, "Assets directory (BLOCKY_ASSETS_DIR)")
+	fs.StringVar(&pathsCfg.DataDir, "data", pathsCfg.DataDir, "Data directory (BLOCKY_DATA_DIR)")
+	fs.StringVar(&pathsCfg.BaseModel, "base-model", pathsCfg.BaseModel, "Player model, default <assets>/Characters/Player.blockymodel (BLOCKY_BASE_MODEL)")
+	fs.StringVar(&pathsCfg.BaseTexture, "base-texture", pathsCfg.BaseTexture, "Player texture, default <assets>/Characters/Player_Textures/Player_Greyscale.png (BLOCKY_BASE_TEXTURE)")
+	fs.StringVar(&pathsCfg.HeadAccessories, "head-accessories", pathsCfg.HeadAccessories, "Head accessory file, default <data>/HeadAccessory.json (BLOCKY_HEAD_ACCESSORIES_FILE)")
+	fs.StringVar(&pathsCfg.Haircuts, "haircuts", pathsCfg.Haircuts, "Haircut file, default <data>/Haircuts.json (BLOCKY_HAIRCUTS_FILE)")
+	fs.StringVar(&pathsCfg.HaircutFallbacks, "haircut-fallbacks", pathsCfg.HaircutFallbacks, "Haircut fallback file, default <data>/HaircutFallbacks.json (BLOCKY_HAIRCUT_FALLBACKS_FILE)")
+	fs.StringVar(&pathsCfg.GradientSets, "gradient-sets", pathsCfg.GradientSets, "Gradient set file, default <data>/GradientSets.json (BLOCKY_GRADIENT_SETS_FILE)")
+	fs.StringVar(&pathsCfg.Rules, "rules", pathsCfg.Rules, "Character rules file, default <data>/CharacterRules.json if present (BLOCKY_RULES_FILE)")
+	return pathsCfg
+}
+
+// loadPacks loads the asset packs, exiting on failure. The server creates
+// the custom cosmetics directory; the offline commands only read it if it
+// exists.
+func loadPacks(pathsCfg *config.PathsConfig, server bool) *service.Packs {
+	cacheCfg := config.LoadCacheConfig()
+
+	specs, err := packSpecs(pathsCfg)
+	if err != nil {
+		log.Fatalf("Invalid asset packs: %v", err)
+	}
+	for _, spec := range specs {
+		if spec.Paths.AssetsZip != "" {
+			log.Printf("Loading asset pack %s (assets from %s)...", spec.Name, spec.Paths.AssetsZip)
+		} else {
+			log.Printf("Loading asset pack %s (assets %s, data %s)...", spec.Name, spec.Paths.AssetsDir, spec.Paths.DataDir)
+		}
+	}
+
+	// Uploaded cosmetics are shared by every pack
+	var custom *service.CustomCosmetics
+	customCfg := config.LoadCustomConfig()
+	_, statErr := os.Stat(customCfg.Dir)
+	if config.LoadEndpointConfig().CosmeticsEnabled && (server || statErr == nil) {
+		custom, err = service.OpenCustomCosmetics(customCfg.Dir, service.CustomLimits{
+			MaxUploadBytes: customCfg.MaxUploadBytes,
+			MaxNodes:       customCfg.MaxNodes,
+			MaxTextureSize: customCfg.MaxTextureSize,
+			MaxCount:       customCfg.MaxCount,
+		})
+		if err != nil {
+			log.Fatalf("Failed to open custom cosmetics: %v", err)
+		}
+		log.Printf("Loaded %d custom cosmetic(s) from %s", custom.Count(), customCfg.Dir)
+	}
 
-	log.Println("Loading merge service...")
-	svc, err := service.NewMergeService()
+	packs, err := service.NewPacks(specs, pathsCfg.DefaultPack, service.Options{
+		CacheMaxBytes: cacheCfg.MaxBytes,
+		Preload:       cacheCfg.Preload,
+		Custom:        custom,
+	})
+	var missing *service.MissingFilesError
+	if errors.As(err, &missing) {
+		log.Fatalf("%v\nSet -assets-zip to the Hytale server assets.zip, or -assets and -data to the extracted directories; see -help for per-file overrides", err)
+	}
 	if err != nil {
 		log.Fatalf("Failed to create merge service: %v", err)
 	}
+	return packs
+}
+
+// serve starts the HTTP server
+func serve(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	port := fs.Int("port", 8080, "Port to listen on")
+	pathsCfg := pathFlags(fs)
+	fs.Parse(args)
 
-	srv := api.NewServer(svc)
+	packs := loadPacks(pathsCfg, true)
+
+	// Reload assets on change, on SIGHUP and on POST /admin/reload
+	reloadCfg := config.LoadReloadConfig()
+	if reloadCfg.Interval > 0 {
+		packs.Watch(reloadCfg.Interval, nil)
+	}
+	reloadOnSignal(packs)
+
+	srv, err := api.NewServer(packs)
+	if err != nil {
+		log.Fatalf("Failed to create server: %v", err)
+	}
 
 	addr := fmt.Sprintf(":%d", *port)
 	log.Printf("Starting server on %s", addr)
 	log.Printf("Endpoints:")
 	log.Printf("  GET  /docs         - Swagger UI")
 	log.Printf("  GET  /openapi.json - OpenAPI spec")
+	log.Printf("  GET  /packs        - Lists loaded asset packs")
+	log.Printf("  GET  /catalog      - Lists available cosmetics")
+	log.Printf("  POST /validate     - Validates a character")
+	log.Printf("  POST /resolve      - Resolves the character actually rendered")
+	log.Printf("  GET  /rules        - Lists the slot removal and replacement rules")
+	log.Printf("  POST /codes/encode - Packs a character into a share code")
+	log.Printf("  POST /codes/decode - Unpacks a share code into a character")
+	log.Printf("  POST /random       - Generates a random character from a seed")
+	log.Printf("  POST /cosmetics    - Uploads a custom cosmetic (requires BLOCKY_ADMIN_TOKEN)")
 	log.Printf("  POST /render/glb   - Returns GLB binary")
 	log.Printf("  POST /render/png   - Returns PNG image")
 	log.Printf("  POST /render/gif   - Returns animated GIF")
+	log.Printf("  POST /render/mp4   - Returns MP4 video")
+	log.Printf("  POST /render/obj   - Returns ZIP with OBJ, MTL and texture")
+	log.Printf("  POST /render/stl   - Returns binary STL for 3D printing")
+	log.Printf("  POST /render/vox   - Returns MagicaVoxel .vox model")
+	log.Printf("  POST /render/atlas - Returns packed texture atlas and manifest")
+	log.Printf("  POST /render/blockymodel - Returns ZIP with merged .blockymodel and texture")
+	log.Printf("  POST /render/item  - Returns PNG icon of a single cosmetic")
+	log.Printf("  POST /render/batch - Returns ZIP or multipart with many renders and a manifest")
+	log.Printf("  POST /jobs         - Queues a render to run in the background")
+	log.Printf("  GET  /jobs/{id}    - Returns the status and progress of a job")
+	log.Printf("  GET  /jobs/{id}/result - Returns the output of a finished job")
+	log.Printf("  GET  /jobs/{id}/events - Streams the progress of a job")
+	log.Printf("  GET  /renders/{id}/events - Streams the progress of a render sent with X-Blocky-Progress-ID")
 	log.Printf("  GET  /health       - Health check")
+	log.Printf("  GET  /ready        - Readiness check")
+	log.Printf("  POST /admin/reload - Reloads assets (requires BLOCKY_ADMIN_TOKEN)")
 
 	if err := http.ListenAndServe(addr, srv); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
+
+// packSpecs builds the asset packs to load. Without -packs there is a single
+// pack from -assets-zip or -assets and -data. The individual file overrides
+// apply to every pack.
+func packSpecs(cfg *config.PathsConfig) ([]service.PackSpec, error) {
+	paths := service.Paths{
+		AssetsZip:        cfg.AssetsZip,
+		AssetsDir:        cfg.AssetsDir,
+		DataDir:          cfg.DataDir,
+		BaseModel:        cfg.BaseModel,
+		BaseTexture:      cfg.BaseTexture,
+		HeadAccessories:  cfg.HeadAccessories,
+		Haircuts:         cfg.Haircuts,
+		HaircutFallbacks: cfg.HaircutFallbacks,
+		GradientSets:     cfg.GradientSets,
+		Rules:            cfg.Rules,
+	}
+
+	packs, err := config.ParsePacks(cfg.Packs)
+	if err != nil {
+		return nil, err
+	}
+	if len(packs) == 0 {
+		return []service.PackSpec{{Name: service.DefaultPackName, Paths: paths}}, nil
+	}
+
+	specs := make([]service.PackSpec, len(packs))
+	for i, pack := range packs {
+		packPaths := paths
+		packPaths.AssetsZip = ""
+		packPaths.AssetsDir = filepath.Join(pack.Path, "assets")
+		packPaths.DataDir = filepath.Join(pack.Path, "data")
+		if strings.EqualFold(filepath.Ext(pack.Path), ".zip") {
+			packPaths.AssetsZip = pack.Path
+		}
+		specs[i] = service.PackSpec{Name: pack.Name, Paths: packPaths}
+	}
+	return specs, nil
+}
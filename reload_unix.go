@@ -0,0 +1,25 @@
+//go:build !windows
+
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"blockyserver/internal/service"
+)
+
+// reloadOnSignal reloads every asset pack whenever the process receives SIGHUP
+func reloadOnSignal(packs *service.Packs) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	go func() {
+		for range sighup {
+			log.Printf("Received SIGHUP, reloading assets")
+			packs.Reload()
+		}
+	}()
+}
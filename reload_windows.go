@@ -0,0 +1,9 @@
+//go:build windows
+
+package main
+
+import "blockyserver/internal/service"
+
+// reloadOnSignal is a no-op on Windows, which has no SIGHUP. Use
+// BLOCKY_RELOAD_INTERVAL or POST /admin/reload instead.
+func reloadOnSignal(packs *service.Packs) {}